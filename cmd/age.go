@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"crypto/ecdh"
+	"fmt"
+	"time"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func init() {
+	keyOutputs[keygen.KeyTypeAge] = keyOutput{
+		name:     "age_key",
+		suffixes: func() []string { return []string{"", ".pub"} },
+		files:    ageFiles,
+	}
+}
+
+// ageFiles returns the identity file for r at base, as age-keygen -o
+// writes it, and the recipient at base.pub.
+func ageFiles(r keygen.Result, base string) ([]keyFile, error) {
+	priv, ok := r.PrivateKey.(*ecdh.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("age key is %T, not x25519", r.PrivateKey)
+	}
+	identity := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), r.AuthorizedKey, keygen.AgeIdentity(priv))
+	return []keyFile{
+		{Format: "age", Path: base, Data: []byte(identity), Secret: true},
+		{Format: "age", Path: base + ".pub", Data: []byte(r.AuthorizedKey + "\n")},
+	}, nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_Age(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "age", "--jobs", "1", "^age1q"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	identity, err := os.ReadFile(filepath.Join(dir, "age_key"))
+	if err != nil {
+		t.Fatalf("identity file: %v", err)
+	}
+	if stdout != string(identity) {
+		t.Errorf("stdout = %q, want the identity file %q", stdout, identity)
+	}
+	recipient, err := os.ReadFile(filepath.Join(dir, "age_key.pub"))
+	if err != nil {
+		t.Fatalf("recipient file: %v", err)
+	}
+	if !strings.HasPrefix(string(recipient), "age1q") || len(recipient) != 63 {
+		t.Errorf("recipient = %q, want 62 characters starting age1q", recipient)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(identity), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "# created: ") ||
+		lines[1] != "# public key: "+strings.TrimSpace(string(recipient)) ||
+		!strings.HasPrefix(lines[2], "AGE-SECRET-KEY-1") || len(lines[2]) != 74 {
+		t.Errorf("identity file = %q", identity)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "age_key")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("identity file mode: %v, %v", info, err)
+	}
+}
+
+func TestRun_Age_JSON(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "age", "--output", "json", "--jobs", "1", "9$"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	var rec matchRecord
+	if err := json.Unmarshal([]byte(stdout), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	if rec.KeyType != "age" || len(rec.PublicKey) != 62 || !strings.HasSuffix(rec.PublicKey, "9") {
+		t.Errorf("key type %q, public key %q", rec.KeyType, rec.PublicKey)
+	}
+	if rec.AuthorizedKey != "" || !strings.Contains(rec.PrivateKey, "AGE-SECRET-KEY-1") {
+		t.Errorf("authorized key %q, private key %q", rec.AuthorizedKey, rec.PrivateKey)
+	}
+	if rec.PrivateFile != "age_key" || rec.PublicFile != "age_key.pub" {
+		t.Errorf("private %q, public %q", rec.PrivateFile, rec.PublicFile)
+	}
+}
+
+func TestRun_AgeInfeasible(t *testing.T) {
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "age", "^age1bob"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "never match") {
+		t.Errorf("Execute error = %v, want an infeasible pattern", err)
+	}
+}
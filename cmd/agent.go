@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/danielewood/vanityssh-go/display"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagAddToAgent bool
+	flagLifetime   time.Duration
+	flagConfirm    bool
+	flagAgentOnly  bool
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&flagAddToAgent, "add-to-agent", false, "add each key found to the ssh-agent at $SSH_AUTH_SOCK")
+	rootCmd.Flags().DurationVar(&flagLifetime, "lifetime", 0, "with --add-to-agent, have the agent drop keys after this long (whole seconds, e.g. 8h)")
+	rootCmd.Flags().BoolVar(&flagConfirm, "confirm", false, "with --add-to-agent, have the agent confirm every use of a key")
+	rootCmd.Flags().BoolVar(&flagAgentOnly, "agent-only", false, "with --add-to-agent, keep private keys out of files and stdout unless the agent refuses them")
+}
+
+// errNoAgent is returned by --add-to-agent when no agent socket is set.
+var errNoAgent = errors.New("SSH_AUTH_SOCK is not set; start ssh-agent or drop --add-to-agent")
+
+// checkAgent validates the agent flags and, with --add-to-agent, that the
+// agent is reachable before any key is generated.
+func checkAgent() error {
+	if !flagAddToAgent {
+		if flagLifetime != 0 || flagConfirm || flagAgentOnly {
+			return fmt.Errorf("--lifetime, --confirm and --agent-only require --add-to-agent")
+		}
+		return nil
+	}
+	if flagLifetime < 0 || flagLifetime%time.Second != 0 || flagLifetime.Seconds() > math.MaxUint32 {
+		return fmt.Errorf("--lifetime must be a positive whole number of seconds, got %v", flagLifetime)
+	}
+	conn, err := dialAgent()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dialAgent connects to the agent at $SSH_AUTH_SOCK.
+func dialAgent() (net.Conn, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errNoAgent
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("connect to ssh-agent: %w", err)
+	}
+	return conn, nil
+}
+
+// addToAgent adds the private key of r to the agent under comment, with
+// the --lifetime and --confirm constraints. Like ssh-add, it adds the key
+// a second time with its certificate when there is one among files.
+func addToAgent(r keygen.Result, comment string, files []keyFile) error {
+	conn, err := dialAgent()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := agent.NewClient(conn)
+	key := agent.AddedKey{
+		PrivateKey:       r.PrivateKey,
+		Comment:          comment,
+		LifetimeSecs:     uint32(flagLifetime / time.Second),
+		ConfirmBeforeUse: flagConfirm,
+	}
+	if err := client.Add(key); err != nil {
+		return fmt.Errorf("add key to ssh-agent: %w", err)
+	}
+	if key.Certificate = fileCert(files); key.Certificate != nil {
+		if err := client.Add(key); err != nil {
+			return fmt.Errorf("add certificate to ssh-agent: %w", err)
+		}
+	}
+	return nil
+}
+
+// fileCert returns the certificate among files, or nil.
+func fileCert(files []keyFile) *ssh.Certificate {
+	for _, f := range files {
+		if f.Format != "openssh-cert" {
+			continue
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(f.Data)
+		if err != nil {
+			return nil
+		}
+		cert, _ := pub.(*ssh.Certificate)
+		return cert
+	}
+	return nil
+}
+
+// saveKey writes the key files of r and, with --add-to-agent, adds the key
+// to the agent. It returns the files to report: all of them, or with
+// --agent-only only the public ones. With --agent-only the private key
+// files are still written if the agent refuses the key, so a key found is
+// never lost; the error then says where it went.
+func saveKey(r keygen.Result, files []keyFile) ([]keyFile, error) {
+	if !flagAddToAgent {
+		return files, writeKeyFiles(files)
+	}
+	// Like ssh-add, label keys without a comment with their file.
+	comment := r.Comment
+	if comment == "" {
+		comment = files[0].Path
+	}
+
+	if !flagAgentOnly {
+		if err := writeKeyFiles(files); err != nil {
+			return files, err
+		}
+		if err := addToAgent(r, comment, files); err != nil {
+			return files, fmt.Errorf("%w (key saved to %s)", err, files[0].Path)
+		}
+		reportAgent(r)
+		return files, nil
+	}
+
+	if err := addToAgent(r, comment, files); err != nil {
+		if werr := writeKeyFiles(files); werr != nil {
+			return files, errors.Join(err, werr)
+		}
+		return files, fmt.Errorf("%w; private key saved to %s instead", err, files[0].Path)
+	}
+	reportAgent(r)
+	public := slices.DeleteFunc(slices.Clone(files), func(f keyFile) bool { return f.Secret })
+	return public, writeKeyFiles(public)
+}
+
+// reportAgent notes on stderr that r is in the agent.
+func reportAgent(r keygen.Result) {
+	// With --progress json, stderr carries only JSON records.
+	if flagProgress != "json" {
+		display.PrintAboveStatus("Added SHA256:%s to ssh-agent", r.Fingerprint)
+	}
+}
+
+// stdoutKey returns what stdout carries for a match: the first private key
+// file or, when the private key went only to the agent or is binary, the
+// public key line, which for key types that are not SSH keys is their last
+// file.
+func stdoutKey(r keygen.Result, files []keyFile) []byte {
+	switch {
+	case files[0].Secret && !files[0].Binary:
+		return files[0].Data
+	case !keygen.KeyType(flagType).SSH():
+		return files[len(files)-1].Data
+	}
+	return []byte(r.AuthorizedKeyLine() + "\n")
+}
+
+// publicLines returns the lines that identify the key of a match on the
+// terminal: its authorized_keys line and fingerprint, or for key types that
+// are not SSH keys what their keyOutput gives, by default their public file.
+func publicLines(r keygen.Result, files []keyFile) []string {
+	if out := keyOutputs[keygen.KeyType(flagType)]; out.public != nil {
+		return out.public(r)
+	}
+	if !keygen.KeyType(flagType).SSH() {
+		return strings.Split(strings.TrimSuffix(string(files[len(files)-1].Data), "\n"), "\n")
+	}
+	return []string{r.AuthorizedKeyLine(), "SHA256:" + r.Fingerprint}
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// recordingAgent is an in-memory agent that also keeps every AddedKey, so
+// tests can check the constraints sent.
+type recordingAgent struct {
+	agent.Agent
+	mu    sync.Mutex
+	added []agent.AddedKey
+}
+
+func (a *recordingAgent) Add(key agent.AddedKey) error {
+	a.mu.Lock()
+	a.added = append(a.added, key)
+	a.mu.Unlock()
+	return a.Agent.Add(key)
+}
+
+// startAgent serves an in-process agent keyring on a temporary unix socket
+// and points SSH_AUTH_SOCK at it.
+func startAgent(t *testing.T) *recordingAgent {
+	t.Helper()
+	// Unix socket paths are short; t.TempDir can be too long on macOS.
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	a := &recordingAgent{Agent: agent.NewKeyring()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(a, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+	return a
+}
+
+func TestCheckAgent(t *testing.T) {
+	startAgent(t)
+	tests := []struct {
+		name     string
+		add      bool
+		lifetime time.Duration
+		confirm  bool
+		only     bool
+		wantSub  string
+	}{
+		{name: "off"},
+		{name: "on", add: true, lifetime: time.Hour, confirm: true, only: true},
+		{name: "lifetime without agent", lifetime: time.Hour, wantSub: "require --add-to-agent"},
+		{name: "agent-only without agent", only: true, wantSub: "require --add-to-agent"},
+		{name: "fractional lifetime", add: true, lifetime: 1500 * time.Millisecond, wantSub: "whole number of seconds"},
+		{name: "negative lifetime", add: true, lifetime: -time.Second, wantSub: "whole number of seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			flagAddToAgent, flagLifetime, flagConfirm, flagAgentOnly = tt.add, tt.lifetime, tt.confirm, tt.only
+			err := checkAgent()
+			switch {
+			case tt.wantSub == "" && err != nil:
+				t.Errorf("checkAgent() = %v, want nil", err)
+			case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+				t.Errorf("checkAgent() = %v, want error containing %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestCheckAgent_NoAgent(t *testing.T) {
+	saveFlags(t)
+	flagAddToAgent = true
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if err := checkAgent(); !errors.Is(err, errNoAgent) {
+		t.Errorf("checkAgent() = %v, want errNoAgent", err)
+	}
+	t.Setenv("SSH_AUTH_SOCK", filepath.Join(t.TempDir(), "missing"))
+	if err := checkAgent(); err == nil || !strings.Contains(err.Error(), "connect to ssh-agent") {
+		t.Errorf("checkAgent() = %v, want connect error", err)
+	}
+}
+
+func TestRun_AddToAgent(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	a := startAgent(t)
+	rootCmd.SetArgs([]string{"--add-to-agent", "--lifetime", "1h", "--confirm", "-C", "ci", "--jobs", "1", "A"})
+	captureStderr(t, func() {
+		captureStdout(t, func() {
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+		})
+	})
+
+	if len(a.added) != 1 {
+		t.Fatalf("agent got %d keys, want 1", len(a.added))
+	}
+	if k := a.added[0]; k.Comment != "ci" || k.LifetimeSecs != 3600 || !k.ConfirmBeforeUse {
+		t.Errorf("added key comment %q, lifetime %d, confirm %v", k.Comment, k.LifetimeSecs, k.ConfirmBeforeUse)
+	}
+	keys, err := a.List()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("agent lists %d keys (%v), want 1", len(keys), err)
+	}
+	pub, err := os.ReadFile(filepath.Join(dir, "id_ed25519.pub"))
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	if got := keys[0].String(); got != strings.TrimSpace(string(pub)) {
+		t.Errorf("agent holds %q, public key file is %q", got, pub)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "id_ed25519")); err != nil {
+		t.Errorf("private key not written without --agent-only: %v", err)
+	}
+}
+
+func TestRun_AgentOnly(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	a := startAgent(t)
+	rootCmd.SetArgs([]string{"--add-to-agent", "--agent-only", "--format", "openssh,pkcs8", "--jobs", "1", "A"})
+	var stdout string
+	captureStderr(t, func() {
+		stdout = captureStdout(t, func() {
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+		})
+	})
+
+	if keys, _ := a.List(); len(keys) != 1 {
+		t.Fatalf("agent holds %d keys, want 1", len(keys))
+	}
+	if strings.Contains(stdout, "PRIVATE KEY") || !strings.HasPrefix(stdout, "ssh-ed25519 ") {
+		t.Errorf("stdout = %q, want only the public key line", stdout)
+	}
+	// With no comment, the agent labels the key with its would-be file.
+	if a.added[0].Comment != "id_ed25519" {
+		t.Errorf("comment = %q, want id_ed25519", a.added[0].Comment)
+	}
+	for _, name := range []string{"id_ed25519", "id_ed25519.pem"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s written with --agent-only (%v)", name, err)
+		}
+	}
+	for _, name := range []string{"id_ed25519.pub", "id_ed25519.pub.pem"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("public key %s: %v", name, err)
+		}
+	}
+}
+
+func TestRun_AgentOnly_Refused(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	a := startAgent(t)
+	if err := a.Lock([]byte("locked")); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"--add-to-agent", "--agent-only", "--jobs", "1", "A"})
+	var err error
+	var stdout string
+	captureStderr(t, func() {
+		stdout = captureStdout(t, func() {
+			err = rootCmd.Execute()
+		})
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "add key to ssh-agent") || !strings.Contains(err.Error(), "saved to id_ed25519 instead") {
+		t.Fatalf("Execute error = %v, want agent failure naming the saved key", err)
+	}
+	priv, rerr := os.ReadFile(filepath.Join(dir, "id_ed25519"))
+	if rerr != nil {
+		t.Fatalf("refused key not saved: %v", rerr)
+	}
+	if _, perr := ssh.ParseRawPrivateKey(priv); perr != nil {
+		t.Errorf("saved key does not parse: %v", perr)
+	}
+	if stdout != string(priv) {
+		t.Error("stdout does not carry the refused private key")
+	}
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+// defaultExtensions are the extensions ssh-keygen grants user certificates.
+// Host certificates get none.
+var defaultExtensions = []string{
+	"permit-X11-forwarding",
+	"permit-agent-forwarding",
+	"permit-port-forwarding",
+	"permit-pty",
+	"permit-user-rc",
+}
+
+var (
+	flagCAKey           string
+	flagPrincipals      string
+	flagValidFor        time.Duration
+	flagKeyID           string
+	flagKeyIDPattern    string
+	flagCriticalOptions []string
+	flagExtensions      = slices.Clone(defaultExtensions)
+
+	criticalOptionFlag = &listValue{list: &flagCriticalOptions}
+	extensionFlag      = &listValue{list: &flagExtensions}
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&flagCAKey, "ca-key", "", "sign each key found with this CA private key into <name>-cert.pub (a host certificate with --host)")
+	rootCmd.Flags().StringVar(&flagPrincipals, "principals", "", "comma-separated certificate principals (user or host names); none means any, or with --host the --hostname names")
+	rootCmd.Flags().DurationVar(&flagValidFor, "valid-for", 0, "certificate lifetime from now, e.g. 24h (default forever)")
+	rootCmd.Flags().StringVar(&flagKeyID, "key-id", "", "certificate key ID; may use {user}, {host}, {date}, {pattern}, {match} and {fp} (default {user}@{host})")
+	rootCmd.Flags().StringVar(&flagKeyIDPattern, "key-id-pattern", "", "with --ca-key, only keep keys whose certificate key ID also matches this regex; --key-id must use {fp} or {match}, and {fp} is the key's fingerprint, not the certificate's")
+	rootCmd.Flags().Var(criticalOptionFlag, "critical-option", "certificate critical `option[=value]`, e.g. force-command=/bin/true; repeatable")
+	rootCmd.Flags().Var(extensionFlag, "extension", "certificate `extension[=value]`, repeatable; replaces ssh-keygen's defaults, \"clear\" grants none")
+}
+
+// keyIDFields are the placeholders a --key-id template may use.
+var keyIDFields = append(slices.Clone(commentFields), "{fp}")
+
+// caSigner signs a certificate for every key found when non-nil. run loads
+// it from --ca-key before starting the search.
+var caSigner ssh.Signer
+
+// keyIDRegexp filters keys by certificate key ID when non-nil. run wraps
+// the search's Matcher in a keyIDMatcher with it.
+var keyIDRegexp *regexp.Regexp
+
+// errCertFlag is returned for certificate options given without --ca-key.
+var errCertFlag = errors.New("requires --ca-key")
+
+// checkCert validates the certificate flags and compiles --key-id-pattern.
+func checkCert() error {
+	keyIDRegexp = nil
+	if flagCAKey == "" {
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"--principals", flagPrincipals != ""},
+			{"--valid-for", flagValidFor != 0},
+			{"--key-id", flagKeyID != ""},
+			{"--key-id-pattern", flagKeyIDPattern != ""},
+			{"--critical-option", criticalOptionFlag.set},
+			{"--extension", extensionFlag.set},
+		} {
+			if f.set {
+				return fmt.Errorf("%s %w", f.name, errCertFlag)
+			}
+		}
+		return nil
+	}
+	if flagValidFor < 0 {
+		return fmt.Errorf("--valid-for must be positive, got %v", flagValidFor)
+	}
+	if strings.ContainsAny(flagKeyID, "\r\n") {
+		return fmt.Errorf("--key-id must be a single line")
+	}
+	if err := checkPlaceholders("--key-id", flagKeyID, keyIDFields); err != nil {
+		return err
+	}
+	for _, opt := range append(slices.Clone(flagCriticalOptions), flagExtensions...) {
+		if name, _, _ := strings.Cut(opt, "="); name == "" {
+			return fmt.Errorf("certificate option %q has no name", opt)
+		}
+	}
+	if flagKeyIDPattern != "" {
+		if flagPatterns != "" {
+			return fmt.Errorf("--key-id-pattern cannot be used with --patterns")
+		}
+		re, err := regexp.Compile(flagKeyIDPattern)
+		if err != nil {
+			return fmt.Errorf("invalid --key-id-pattern: %w", err)
+		}
+		if !strings.Contains(flagKeyID, "{fp}") && !strings.Contains(flagKeyID, "{match}") {
+			return fmt.Errorf("--key-id-pattern needs {fp} or {match} in --key-id, or every key has the same key ID")
+		}
+		keyIDRegexp = re
+	}
+	return nil
+}
+
+// loadCA reads the --ca-key private key, prompting for its passphrase on a
+// terminal if it is encrypted.
+func loadCA() (ssh.Signer, error) {
+	b, err := os.ReadFile(flagCAKey)
+	if err != nil {
+		return nil, fmt.Errorf("read CA key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return nil, fmt.Errorf("CA key %s is encrypted and stdin is not a terminal", flagCAKey)
+		}
+		fmt.Fprintf(os.Stderr, "Enter passphrase for CA key %s: ", flagCAKey)
+		pass, rerr := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if rerr != nil {
+			return nil, fmt.Errorf("read passphrase: %w", rerr)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(b, pass)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse CA key: %w", err)
+	}
+	return signer, nil
+}
+
+// keyID returns the certificate key ID for r, found by re against its input
+// text.
+func keyID(r keygen.Result, re *regexp.Regexp, input keygen.Input) string {
+	return expandKeyID(r.Fingerprint, re, re.FindString(r.Text(input)))
+}
+
+// expandKeyID expands the --key-id template for a key with the base64
+// SHA256 fingerprint fp whose text matched re as match.
+func expandKeyID(fp string, re *regexp.Regexp, match string) string {
+	tmpl := flagKeyID
+	if tmpl == "" {
+		tmpl = "{user}@{host}"
+	}
+	tmpl = strings.ReplaceAll(tmpl, "{fp}", "SHA256:"+strings.TrimRight(fp, "="))
+	return expandComment(tmpl, re.String(), match)
+}
+
+// keyIDMatcher only accepts the hits of Matcher whose certificate key ID
+// also matches id, so the keys --key-id-pattern rejects are never counted
+// as matches.
+type keyIDMatcher struct {
+	keygen.Matcher
+	re, id *regexp.Regexp
+}
+
+// Match reports whether candidate is a hit of the wrapped Matcher and its
+// key ID matches.
+func (m *keyIDMatcher) Match(candidate []byte) bool {
+	if !m.Matcher.Match(candidate) {
+		return false
+	}
+	fp := candidateFingerprint(candidate, m.Input())
+	return m.id.MatchString(expandKeyID(fp, m.re, string(m.re.Find(candidate))))
+}
+
+// String returns the name of the wrapped matching engine.
+func (m *keyIDMatcher) String() string { return fmt.Sprint(m.Matcher) }
+
+// candidateFingerprint returns the base64 SHA256 fingerprint of the SSH key
+// whose representation in is candidate.
+func candidateFingerprint(candidate []byte, in keygen.Input) string {
+	switch in {
+	case keygen.InputFingerprint:
+		return string(candidate)
+	case keygen.InputSSHFP:
+		sum, _ := hex.DecodeString(string(candidate))
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	_, key, _ := bytes.Cut(candidate, []byte(" "))
+	wire, _ := base64.StdEncoding.DecodeString(string(key))
+	sum := sha256.Sum256(wire)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// certOptions turns name[=value] flag values into certificate options.
+func certOptions(opts []string) map[string]string {
+	m := map[string]string{}
+	for _, opt := range opts {
+		if opt == "clear" {
+			clear(m)
+			continue
+		}
+		name, value, _ := strings.Cut(opt, "=")
+		m[name] = value
+	}
+	return m
+}
+
+// signCert returns the public key of r certified by the CA under keyID.
+func signCert(r keygen.Result, keyID string) (*ssh.Certificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.AuthorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	cert := &ssh.Certificate{
+		Key:         pub,
+		CertType:    ssh.UserCert,
+		KeyId:       keyID,
+		ValidBefore: ssh.CertTimeInfinity,
+		Permissions: ssh.Permissions{
+			CriticalOptions: certOptions(flagCriticalOptions),
+			Extensions:      certOptions(flagExtensions),
+		},
+	}
+	if flagPrincipals != "" {
+		cert.ValidPrincipals = strings.Split(flagPrincipals, ",")
+	}
+	if flagHost {
+		// Like ssh-keygen -h, grant host certificates no extensions by
+		// default; they are for the host names given.
+		cert.CertType = ssh.HostCert
+		if !extensionFlag.set {
+			cert.Extensions = map[string]string{}
+		}
+		if flagPrincipals == "" {
+			cert.ValidPrincipals = hostPrincipals()
+		}
+	}
+	if flagValidFor > 0 {
+		now := time.Now()
+		cert.ValidAfter = uint64(now.Unix())
+		cert.ValidBefore = uint64(now.Add(flagValidFor).Unix())
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// certLine returns cert as an authorized_keys line ending in comment, as
+// ssh-keygen writes <name>-cert.pub.
+func certLine(cert *ssh.Certificate, comment string) string {
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(cert)), "\n")
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
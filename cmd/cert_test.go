@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+// writeCAKey writes a new ed25519 CA private key to dir, encrypted when
+// pass is non-empty, and returns its path and public key.
+func writeCAKey(t *testing.T, dir string, pass string) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := keyfile.MarshalOpenSSH(priv, "ca", keyfile.Options{Passphrase: []byte(pass), Rounds: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "ca")
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, sshPub
+}
+
+// readCert parses the certificate file at path.
+func readCert(t *testing.T, path string) (*ssh.Certificate, string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("certificate: %v", err)
+	}
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		t.Fatalf("%s holds a %s, not a certificate", path, pub.Type())
+	}
+	return cert, comment
+}
+
+func TestCheckCert(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func()
+		wantSub string
+	}{
+		{name: "none", set: func() {}},
+		{name: "principals without CA", set: func() { flagPrincipals = "alice" }, wantSub: "--principals requires --ca-key"},
+		{name: "extension without CA", set: func() { _ = extensionFlag.Set("permit-pty") }, wantSub: "--extension requires --ca-key"},
+		{name: "full", set: func() {
+			flagCAKey, flagPrincipals, flagValidFor, flagKeyID, flagKeyIDPattern = "ca", "alice", time.Hour, "{user}-{fp}", "^a"
+			_ = criticalOptionFlag.Set("force-command=/bin/true")
+		}},
+		{name: "negative validity", set: func() { flagCAKey, flagValidFor = "ca", -time.Hour }, wantSub: "--valid-for must be positive"},
+		{name: "unknown placeholder", set: func() { flagCAKey, flagKeyID = "ca", "{fp8}" }, wantSub: "unknown placeholder {fp8}"},
+		{name: "multi-line key ID", set: func() { flagCAKey, flagKeyID = "ca", "a\nb" }, wantSub: "single line"},
+		{name: "nameless option", set: func() { flagCAKey = "ca"; _ = criticalOptionFlag.Set("=x") }, wantSub: "has no name"},
+		{name: "bad key ID pattern", set: func() { flagCAKey, flagKeyIDPattern = "ca", "(" }, wantSub: "invalid --key-id-pattern"},
+		{name: "key ID pattern on a constant key ID", set: func() { flagCAKey, flagKeyID, flagKeyIDPattern = "ca", "{user}", "a" }, wantSub: "needs {fp} or {match} in --key-id"},
+		{name: "key ID pattern with patterns", set: func() { flagCAKey, flagKeyIDPattern, flagPatterns = "ca", "a", "targets.txt" }, wantSub: "cannot be used with --patterns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			tt.set()
+			err := checkCert()
+			switch {
+			case tt.wantSub == "" && err != nil:
+				t.Errorf("checkCert() = %v, want nil", err)
+			case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+				t.Errorf("checkCert() = %v, want error containing %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestCertOptions(t *testing.T) {
+	got := certOptions([]string{"permit-pty", "clear", "force-command=/bin/echo a=b", "permit-agent-forwarding"})
+	want := map[string]string{"force-command": "/bin/echo a=b", "permit-agent-forwarding": ""}
+	if !maps.Equal(got, want) {
+		t.Errorf("certOptions = %v, want %v", got, want)
+	}
+}
+
+func TestRun_CACert(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	caPath, caPub := writeCAKey(t, dir, "")
+	rootCmd.SetArgs([]string{
+		"--ca-key", caPath, "--principals", "alice,bob", "--valid-for", "1h",
+		"--key-id", "{user}:{fp}", "--critical-option", "force-command=/bin/true",
+		"--extension", "permit-pty", "-C", "ci", "--jobs", "1", "A",
+	})
+	captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	cert, comment := readCert(t, filepath.Join(dir, "id_ed25519-cert.pub"))
+	pubLine, err := os.ReadFile(filepath.Join(dir, "id_ed25519.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(pubLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment != "ci" || string(cert.Key.Marshal()) != string(pub.Marshal()) {
+		t.Errorf("certificate comment %q, certifies %s; want ci and the found key", comment, ssh.FingerprintSHA256(cert.Key))
+	}
+	if want := currentUser() + ":" + ssh.FingerprintSHA256(pub); cert.KeyId != want {
+		t.Errorf("key ID = %q, want %q", cert.KeyId, want)
+	}
+	if cert.CertType != ssh.UserCert || !slices.Equal(cert.ValidPrincipals, []string{"alice", "bob"}) {
+		t.Errorf("type %d, principals %q", cert.CertType, cert.ValidPrincipals)
+	}
+	if life := time.Duration(cert.ValidBefore-cert.ValidAfter) * time.Second; life != time.Hour {
+		t.Errorf("validity = %v, want 1h", life)
+	}
+	if !maps.Equal(cert.CriticalOptions, map[string]string{"force-command": "/bin/true"}) || !maps.Equal(cert.Extensions, map[string]string{"permit-pty": ""}) {
+		t.Errorf("critical options %v, extensions %v", cert.CriticalOptions, cert.Extensions)
+	}
+
+	checker := ssh.CertChecker{
+		SupportedCriticalOptions: []string{"force-command"},
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caPub.Marshal())
+		},
+	}
+	if err := checker.CheckCert("bob", cert); err != nil {
+		t.Errorf("CheckCert: %v", err)
+	}
+	if err := checker.CheckCert("mallory", cert); err == nil {
+		t.Error("CheckCert accepted a principal not in the certificate")
+	}
+}
+
+func TestRun_CACert_Defaults(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	caPath, _ := writeCAKey(t, dir, "")
+	rootCmd.SetArgs([]string{"--ca-key", caPath, "--jobs", "1", "A"})
+	captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	cert, _ := readCert(t, filepath.Join(dir, "id_ed25519-cert.pub"))
+	if cert.ValidAfter != 0 || cert.ValidBefore != ssh.CertTimeInfinity || len(cert.ValidPrincipals) != 0 {
+		t.Errorf("validity %d-%d, principals %q; want forever and any", cert.ValidAfter, cert.ValidBefore, cert.ValidPrincipals)
+	}
+	if got := slices.Sorted(maps.Keys(cert.Extensions)); !slices.Equal(got, slices.Sorted(slices.Values(defaultExtensions))) {
+		t.Errorf("extensions = %q, want ssh-keygen's defaults", got)
+	}
+	if host, _ := os.Hostname(); cert.KeyId != currentUser()+"@"+host {
+		t.Errorf("key ID = %q, want user@host", cert.KeyId)
+	}
+}
+
+func TestKeyIDMatcher(t *testing.T) {
+	saveFlags(t)
+	flagKeyID = "{fp}"
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(sshPub.Marshal())
+	fp := ssh.FingerprintSHA256(sshPub)
+	candidates := map[keygen.Input]string{
+		keygen.InputPublicKey:   strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(sshPub)), "\n"),
+		keygen.InputFingerprint: base64.StdEncoding.EncodeToString(sum[:]),
+		keygen.InputSSHFP:       hex.EncodeToString(sum[:]),
+	}
+	re := regexp.MustCompile(".")
+	for in, candidate := range candidates {
+		inner, err := keygen.NewRegexMatcher(re, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &keyIDMatcher{Matcher: inner, re: re, id: regexp.MustCompile("^" + regexp.QuoteMeta(fp) + "$")}
+		if !m.Match([]byte(candidate)) {
+			t.Errorf("%v: Match = false, want true for key ID %s", in, fp)
+		}
+		m.id = regexp.MustCompile("^SHA256:$")
+		if m.Match([]byte(candidate)) {
+			t.Errorf("%v: Match = true for another key ID", in)
+		}
+	}
+}
+
+func TestRun_KeyIDPattern(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	caPath, _ := writeCAKey(t, dir, "")
+	rootCmd.SetArgs([]string{"--ca-key", caPath, "--key-id", "{fp}", "--key-id-pattern", "^SHA256:A", "--jobs", "1", "."})
+	captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	if cert, _ := readCert(t, filepath.Join(dir, "id_ed25519-cert.pub")); !strings.HasPrefix(cert.KeyId, "SHA256:A") {
+		t.Errorf("key ID = %q, want one matching ^SHA256:A", cert.KeyId)
+	}
+}
+
+func TestRun_CACert_Encrypted(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	caPath, _ := writeCAKey(t, dir, "secret")
+	rootCmd.SetArgs([]string{"--ca-key", caPath, "--jobs", "1", "A"})
+	// Tests do not run on a terminal, so there is no one to ask.
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "is encrypted") {
+		t.Errorf("Execute error = %v, want encrypted CA key error", err)
+	}
+}
+
+func TestRun_CACert_Agent(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	a := startAgent(t)
+	caPath, _ := writeCAKey(t, dir, "")
+	rootCmd.SetArgs([]string{"--ca-key", caPath, "--add-to-agent", "--jobs", "1", "A"})
+	captureStderr(t, func() {
+		captureStdout(t, func() {
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+		})
+	})
+
+	keys, err := a.List()
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("agent holds %d keys (%v), want the key and its certificate", len(keys), err)
+	}
+	if !slices.ContainsFunc(keys, func(k *agent.Key) bool { return strings.HasSuffix(k.Format, "-cert-v01@openssh.com") }) {
+		t.Error("agent does not hold the certificate")
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var flagComment string
+
+func init() {
+	rootCmd.Flags().StringVarP(&flagComment, "comment", "C", "", "key comment; may use {user}, {host}, {date}, {pattern} and {match}")
+}
+
+// placeholder matches a {name} placeholder in a template.
+var placeholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// commentFields are the placeholders a comment template may use.
+var commentFields = []string{"{user}", "{host}", "{date}", "{pattern}", "{match}"}
+
+// checkComment rejects comment templates that use unknown placeholders or
+// would break the authorized_keys line.
+func checkComment(tmpl string) error {
+	if strings.ContainsAny(tmpl, "\r\n") {
+		return fmt.Errorf("--comment must be a single line")
+	}
+	return checkPlaceholders("--comment", tmpl, commentFields)
+}
+
+// checkPlaceholders rejects placeholders in the template given to flag that
+// are not in fields.
+func checkPlaceholders(flag, tmpl string, fields []string) error {
+	for _, ph := range placeholder.FindAllString(tmpl, -1) {
+		if !slices.Contains(fields, ph) {
+			return fmt.Errorf("%s: unknown placeholder %s; use %s", flag, ph, strings.Join(fields, ", "))
+		}
+	}
+	return nil
+}
+
+// expandComment fills in a comment template for a key found by pattern,
+// where match is the part of the key text or fingerprint it matched.
+func expandComment(tmpl, pattern, match string) string {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return strings.NewReplacer(
+		"{user}", currentUser(),
+		"{host}", host,
+		"{date}", time.Now().Format(time.DateOnly),
+		"{pattern}", pattern,
+		"{match}", match,
+	).Replace(tmpl)
+}
+
+// currentUser returns the login name, as ssh-keygen's default comment does.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// commentResult stores the --comment for r, found by re against its input
+// text, in the key. Without --comment r is returned unchanged.
+func commentResult(r keygen.Result, re *regexp.Regexp, input keygen.Input) (keygen.Result, error) {
+	if flagComment == "" {
+		return r, nil
+	}
+	return r.WithComment(expandComment(flagComment, re.String(), re.FindString(r.Text(input))))
+}
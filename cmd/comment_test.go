@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestCheckComment(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantSub string
+	}{
+		{tmpl: ""},
+		{tmpl: "deploy key"},
+		{tmpl: "{user}@{host} {date} {pattern} {match}"},
+		{tmpl: "{nope}", wantSub: "unknown placeholder {nope}"},
+		{tmpl: "{user}@{hostname}", wantSub: "unknown placeholder {hostname}"},
+		{tmpl: "a\nb", wantSub: "single line"},
+	}
+	for _, tt := range tests {
+		err := checkComment(tt.tmpl)
+		switch {
+		case tt.wantSub == "" && err != nil:
+			t.Errorf("checkComment(%q) = %v, want nil", tt.tmpl, err)
+		case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+			t.Errorf("checkComment(%q) = %v, want error containing %q", tt.tmpl, err, tt.wantSub)
+		}
+	}
+}
+
+func TestExpandComment(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Skipf("Hostname: %v", err)
+	}
+	got := expandComment("{user}@{host} {date} {pattern}={match}", "(?i)dwd$", "DwD")
+	want := currentUser() + "@" + host + " " + time.Now().Format(time.DateOnly) + " (?i)dwd$=DwD"
+	if got != want {
+		t.Errorf("expandComment = %q, want %q", got, want)
+	}
+	if got := expandComment("plain", "x", "y"); got != "plain" {
+		t.Errorf("expandComment(plain) = %q", got)
+	}
+}
+
+func TestRun_Comment_EndToEnd(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"-C", "team {pattern} {match}", "--jobs", "1", "[AB]$"})
+	captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	pub, err := os.ReadFile(filepath.Join(dir, "id_ed25519.pub"))
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	fields := strings.Fields(string(pub))
+	if len(fields) != 5 || fields[2] != "team" || fields[3] != "[AB]$" || !strings.HasSuffix(fields[1], fields[4]) {
+		t.Fatalf("public key line = %q, want key followed by %q", pub, "team [AB]$ <match>")
+	}
+	comment := strings.Join(fields[2:], " ")
+
+	priv, err := os.ReadFile(filepath.Join(dir, "id_ed25519"))
+	if err != nil {
+		t.Fatalf("private key: %v", err)
+	}
+	block, _ := pem.Decode(priv)
+	if block == nil || !bytes.Contains(block.Bytes, []byte(comment)) {
+		t.Errorf("private key does not contain comment %q", comment)
+	}
+}
+
+func TestRun_Comment_Patterns(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	if err := os.WriteFile("targets.txt", []byte("alice=A\nbob=B\n"), 0644); err != nil {
+		t.Fatalf("write patterns: %v", err)
+	}
+	rootCmd.SetArgs([]string{"--patterns", "targets.txt", "-C", "{pattern}:{match}", "--jobs", "1"})
+	captureStderr(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	for name, want := range map[string]string{"alice": "A:A", "bob": "B:B"} {
+		pub, err := os.ReadFile(filepath.Join(dir, name+"_ed25519.pub"))
+		if err != nil {
+			t.Fatalf("%s public key: %v", name, err)
+		}
+		if !strings.HasSuffix(string(pub), " "+want) {
+			t.Errorf("%s public key = %q, want comment %q", name, pub, want)
+		}
+	}
+}
+
+func TestRun_CommentValidation(t *testing.T) {
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--comment", "{who}", "."})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "unknown placeholder {who}") {
+		t.Errorf("error = %v, want unknown placeholder", err)
+	}
+}
+
+func TestCommentResult_NoComment(t *testing.T) {
+	saveFlags(t)
+	flagComment = ""
+	r := fakeResult(t)
+	got, err := commentResult(r, regexp.MustCompile("."), keygen.InputPublicKey)
+	if err != nil {
+		t.Fatalf("commentResult: %v", err)
+	}
+	if got.Comment != "" || string(got.PrivateKeyPEM) != string(r.PrivateKeyPEM) {
+		t.Error("commentResult changed the key without --comment")
+	}
+}
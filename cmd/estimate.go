@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielewood/vanityssh-go/display"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagRate    float64
+	flagMeasure time.Duration
+)
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate <regex>",
+	Short: "Estimate how long finding a pattern will take",
+	Long: `estimate computes the probability that one random key matches the
+pattern, using the same key type, --bits and --fingerprint flags as a real
+run, and reports how many keys a search is expected to generate.
+
+Times are based on the key rate of --jobs workers, measured for --measure
+(matches found meanwhile are discarded), or on --rate when given. The
+per-worker table assumes the rate scales linearly with workers, which holds
+up to the number of physical cores.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEstimate,
+}
+
+func init() {
+	estimateCmd.Flags().Float64Var(&flagRate, "rate", 0, "keys/s of all workers together (default: measure it)")
+	estimateCmd.Flags().DurationVar(&flagMeasure, "measure", 2*time.Second, "how long to measure the key rate for")
+	rootCmd.AddCommand(estimateCmd)
+}
+
+func runEstimate(cmd *cobra.Command, args []string) error {
+	opts, input, err := searchMode()
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+	if err := keygen.CheckFeasible(re, opts, input); err != nil {
+		return err
+	}
+	p, err := keygen.MatchProbability(re, opts, input)
+	if err != nil {
+		return err
+	}
+	workers, err := workerCount()
+	if err != nil {
+		return err
+	}
+	if flagRate < 0 {
+		return fmt.Errorf("--rate must be non-negative, got %g", flagRate)
+	}
+
+	rate, source := flagRate, "given"
+	if rate == 0 {
+		if flagMeasure <= 0 {
+			return fmt.Errorf("--measure must be positive, got %v", flagMeasure)
+		}
+		opts.Matcher, err = keygen.NewMatcher(re, input)
+		if err != nil {
+			return err
+		}
+		rate, err = measureRate(opts, workers, flagMeasure)
+		if err != nil {
+			return err
+		}
+		source = fmt.Sprintf("measured over %v", flagMeasure)
+	}
+
+	printEstimate(cmd.OutOrStdout(), re, opts.Type, input, p, rate, workers, source)
+	return nil
+}
+
+// measureRate runs a real search for d and returns its key rate.
+func measureRate(opts keygen.Options, workers int, d time.Duration) (float64, error) {
+	s, err := keygen.NewSearcher(opts, workers)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		return 0, err
+	}
+	for range s.Results() {
+	}
+	if err := s.Wait(); err != nil {
+		return 0, err
+	}
+	st := s.Stats()
+	if st.Keys == 0 {
+		return 0, fmt.Errorf("no keys generated in %v; raise --measure", d)
+	}
+	return float64(st.Keys) / st.Elapsed.Seconds(), nil
+}
+
+func printEstimate(w io.Writer, re *regexp.Regexp, kt keygen.KeyType, in keygen.Input, p, rate float64, workers int, source string) {
+	mean := 1 / p
+	median := keygen.KeysForProbability(p, 0.5)
+	p90 := keygen.KeysForProbability(p, 0.9)
+
+	fmt.Fprintf(w, "Pattern:      %s (%s)\n", re, in)
+	fmt.Fprintf(w, "Key type:     %s\n", keyLabel(kt))
+	fmt.Fprintf(w, "Probability:  1 in %s keys (%.3g)\n", formatKeys(mean), p)
+	fmt.Fprintf(w, "Keys needed:  median %s | 90%% %s | mean %s\n", formatKeys(median), formatKeys(p90), formatKeys(mean))
+	fmt.Fprintf(w, "Rate:         %s keys/s with %d %s (%s)\n\n", formatKeys(rate), workers, plural(workers, "worker"), source)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Workers\tKeys/s\tMedian\t90%\tMean\t")
+	perWorker := rate / float64(workers)
+	for _, n := range workerSteps(workers) {
+		r := perWorker * float64(n)
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t\n", n, formatKeys(r),
+			display.FormatSeconds(median/r), display.FormatSeconds(p90/r), display.FormatSeconds(mean/r))
+	}
+	tw.Flush()
+}
+
+// workerSteps returns the powers of two below n, followed by n.
+func workerSteps(n int) []int {
+	var steps []int
+	for i := 1; i < n; i *= 2 {
+		steps = append(steps, i)
+	}
+	return append(steps, n)
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// formatKeys formats a key count that may exceed int64.
+func formatKeys(f float64) string {
+	switch {
+	case math.IsInf(f, 1) || math.IsNaN(f):
+		return "more than 1e308"
+	case f < 1e15:
+		return display.FormatCount(int64(math.Round(f)))
+	default:
+		return fmt.Sprintf("%.3g", f)
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// executeEstimate runs the estimate subcommand with args and returns its
+// output.
+func executeEstimate(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	saveFlags(t)
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+	rootCmd.SetArgs(append([]string{"estimate"}, args...))
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestEstimate_GivenRate(t *testing.T) {
+	out, err := executeEstimate(t, "--rate", "4000", "-j", "4", "(?i)vanity$")
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	for _, want := range []string{
+		"Pattern:      (?i)vanity$ (public key)",
+		"Key type:     ed25519",
+		"1 in 1,073,741,824 keys",
+		"median 744,261,118",
+		"4,000 keys/s with 4 workers (given)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	// 1, 2 and 4 workers at 1,000 keys/s each.
+	for _, row := range []string{`(?m)^ +1 +1,000 `, `(?m)^ +2 +2,000 `, `(?m)^ +4 +4,000 `} {
+		if !regexp.MustCompile(row).MatchString(out) {
+			t.Errorf("output missing row %s:\n%s", row, out)
+		}
+	}
+}
+
+func TestEstimate_UsesModeFlags(t *testing.T) {
+	out, err := executeEstimate(t, "-f", "-t", "rsa", "-b", "4096", "--rate", "10", "Q=$")
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	for _, want := range []string{"(fingerprint)", "rsa-4096", "1 in 16 keys"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEstimate_SSHFP(t *testing.T) {
+	out, err := executeEstimate(t, "--sshfp", "--rate", "10", "^c0ffee")
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if !strings.Contains(out, "(SSHFP digest)") || !strings.Contains(out, "1 in 16,777,216 keys") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestEstimate_MeasuredRate(t *testing.T) {
+	out, err := executeEstimate(t, "-j", "1", "--measure", "100ms", "abc$")
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if !strings.Contains(out, "with 1 worker (measured over 100ms)") {
+		t.Errorf("output missing measured rate:\n%s", out)
+	}
+}
+
+func TestEstimate_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantSub string
+	}{
+		{name: "infeasible", args: []string{"^ssh-ed25519 AAAB"}, wantSub: "pattern can never match"},
+		{name: "invalid regex", args: []string{"[invalid"}, wantSub: "invalid regex"},
+		{name: "negative rate", args: []string{"--rate", "-1", "abc"}, wantSub: "--rate must be non-negative"},
+		{name: "zero measure", args: []string{"--measure", "0s", "abc"}, wantSub: "--measure must be positive"},
+		{name: "bits without rsa", args: []string{"-b", "4096", "abc"}, wantSub: "--bits only applies to --type rsa"},
+		{name: "no regex", args: nil, wantSub: "accepts 1 arg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeEstimate(t, tt.args...)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantSub) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantSub)
+			}
+		})
+	}
+}
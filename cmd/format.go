@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagFormats    = []string{"openssh"}
+	flagPPKVersion int
+
+	formatFlag = &listValue{list: &flagFormats, sep: ","}
+)
+
+func init() {
+	rootCmd.Flags().Var(formatFlag, "format", "private key formats, repeatable or comma-separated: "+strings.Join(keyfile.FormatNames(), ", ")+"; stdout gets the first")
+	rootCmd.Flags().IntVar(&flagPPKVersion, "ppk-version", 0, fmt.Sprintf("PuTTY key file version: 3 (Argon2id, PuTTY 0.75+) or 2 (default %d)", keyfile.PPKVersion3))
+}
+
+// listValue is a repeatable flag. The default list holds until the flag
+// is first given, which replaces it; later ones add to the list. With a
+// separator, each value may also hold several items. Unlike pflag's slice
+// flags, tests can reset it between runs.
+type listValue struct {
+	list *[]string
+	sep  string
+	set  bool
+}
+
+func (v *listValue) Set(s string) error {
+	if !v.set {
+		*v.list, v.set = nil, true
+	}
+	if v.sep == "" {
+		*v.list = append(*v.list, s)
+	} else {
+		*v.list = append(*v.list, strings.Split(s, v.sep)...)
+	}
+	return nil
+}
+
+func (v *listValue) String() string {
+	// An empty list reads as unset, so help shows no default for it.
+	if len(*v.list) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(*v.list, ",") + "]"
+}
+
+func (v *listValue) Type() string { return "strings" }
+
+// keyFile is one file written for a match.
+type keyFile struct {
+	Format string
+	Path   string
+	Data   []byte
+	Secret bool
+	// Binary files are never printed.
+	Binary bool
+}
+
+// keyOutput describes the files written for a key type that is not an SSH
+// key, in place of those of --format.
+type keyOutput struct {
+	// name is the default --name stem, in place of id_{type}.
+	name string
+	// suffixes returns those of the files files returns, in order.
+	suffixes func() []string
+	files    func(r keygen.Result, base string) ([]keyFile, error)
+	// public returns the lines identifying a match on the terminal; nil
+	// means those of its last file.
+	public func(r keygen.Result) []string
+	// encrypts reports whether files encrypts the private key with the
+	// passphrase, and rounds whether it takes --kdf-rounds for it.
+	encrypts, rounds bool
+}
+
+// keyOutputs holds the output of each key type that is not an SSH key,
+// registered by the file implementing it.
+var keyOutputs = map[keygen.KeyType]keyOutput{}
+
+// dirSuffixes returns the suffixes of files named names inside the
+// directory at a key path.
+func dirSuffixes(names ...string) []string {
+	suffixes := make([]string, len(names))
+	for i, name := range names {
+		suffixes[i] = string(filepath.Separator) + name
+	}
+	return suffixes
+}
+
+// checkKeyOutput rejects the flags that only apply to SSH keys when kt is
+// another kind of key.
+func checkKeyOutput(kt keygen.KeyType) error {
+	if kt.SSH() {
+		return nil
+	}
+	out := keyOutputs[kt]
+	var flag string
+	switch {
+	case formatFlag.set:
+		flag = "--format"
+	case flagPPKVersion != 0:
+		flag = "--ppk-version"
+	case (flagPassphraseFile != "" || flagPassphraseEnv != "" || flagAskPassphrase) && !out.encrypts:
+		return fmt.Errorf("a passphrase only applies to %s keys, not --type %s", keyTypesWith(func(o keyOutput) bool { return o.encrypts }), kt)
+	case flagKDFRounds != 0 && !out.rounds:
+		return fmt.Errorf("--kdf-rounds only applies to %s keys, not --type %s", keyTypesWith(func(o keyOutput) bool { return o.rounds }), kt)
+	case flagComment != "":
+		flag = "--comment"
+	case flagAddToAgent:
+		flag = "--add-to-agent"
+	case flagCAKey != "":
+		flag = "--ca-key"
+	case flagHost:
+		flag = "--host"
+	case flagGitSigning:
+		flag = "--git-signing"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%s only applies to SSH keys, not --type %s", flag, kt)
+}
+
+// keyTypesWith lists SSH and the key types whose keyOutput has a feature,
+// for error messages.
+func keyTypesWith(has func(keyOutput) bool) string {
+	names := []string{"SSH"}
+	for _, kt := range keygen.KeyTypes {
+		if out, ok := keyOutputs[kt]; ok && has(out) {
+			names = append(names, string(kt))
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// checkFormat validates --format and the options that only apply to some
+// formats.
+func checkFormat() error {
+	if len(flagFormats) == 0 {
+		return fmt.Errorf("--format needs at least one format")
+	}
+	encrypt := flagPassphraseFile != "" || flagPassphraseEnv != "" || flagAskPassphrase
+	for i, name := range flagFormats {
+		f, ok := keyfile.LookupFormat(name)
+		if !ok {
+			return fmt.Errorf("--format must be one of %s, got %q", strings.Join(keyfile.FormatNames(), ", "), name)
+		}
+		if slices.Contains(flagFormats[:i], name) {
+			return fmt.Errorf("--format %s given twice", name)
+		}
+		if encrypt && !f.Encrypts {
+			return fmt.Errorf("--format %s cannot encrypt private keys; drop it or the passphrase", name)
+		}
+	}
+	if flagPPKVersion != 0 {
+		if !slices.Contains(flagFormats, "ppk") {
+			return fmt.Errorf("--ppk-version only applies to --format ppk")
+		}
+		if flagPPKVersion != keyfile.PPKVersion2 && flagPPKVersion != keyfile.PPKVersion3 {
+			return fmt.Errorf("--ppk-version must be 2 or 3, got %d", flagPPKVersion)
+		}
+	}
+	if flagKDFRounds != 0 && !slices.Contains(flagFormats, "openssh") {
+		return fmt.Errorf("--kdf-rounds only applies to --format openssh")
+	}
+	return nil
+}
+
+// keyFileSuffixes returns the suffixes of every file written per match:
+// those of each --format, the certificate's "-cert.pub" with --ca-key,
+// ".allowed_signers" with --git-signing, then the OpenSSH public key's
+// ".pub". Key types that are not SSH keys have their own.
+func keyFileSuffixes() []string {
+	if out, ok := keyOutputs[keygen.KeyType(flagType)]; ok {
+		return out.suffixes()
+	}
+	var suffixes []string
+	for _, name := range flagFormats {
+		f, _ := keyfile.LookupFormat(name)
+		suffixes = append(suffixes, f.Suffixes...)
+	}
+	if flagCAKey != "" {
+		suffixes = append(suffixes, "-cert.pub")
+	}
+	if flagGitSigning {
+		suffixes = append(suffixes, ".allowed_signers")
+	}
+	return append(suffixes, ".pub")
+}
+
+// keyFiles encodes r in every --format, in order, for the key files at
+// base, followed by its certificate under keyID when a CA is loaded, its
+// allowed_signers entry with --git-signing and its OpenSSH public key line.
+// The first file is the private key of the first format. Private keys are
+// encrypted when a passphrase is set. Key types that are not SSH keys write
+// their own files, the private key first and the public one last.
+func keyFiles(r keygen.Result, base, keyID string) ([]keyFile, error) {
+	if out, ok := keyOutputs[keygen.KeyType(flagType)]; ok {
+		return out.files(r, base)
+	}
+	var files []keyFile
+	for _, name := range flagFormats {
+		f, _ := keyfile.LookupFormat(name)
+		var encoded []keyfile.File
+		if name == "openssh" && passphrase == nil {
+			// keygen has already encoded the plaintext OpenSSH key.
+			encoded = []keyfile.File{{Data: r.PrivateKeyPEM, Secret: true}}
+		} else {
+			var err error
+			encoded, err = f.Encode(r.PrivateKey, r.Comment, keyfile.Options{
+				Passphrase: passphrase,
+				Rounds:     flagKDFRounds,
+				PPKVersion: flagPPKVersion,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("encode %s key: %w", name, err)
+			}
+		}
+		for _, e := range encoded {
+			files = append(files, keyFile{Format: name, Path: base + e.Suffix, Data: e.Data, Secret: e.Secret})
+		}
+	}
+	if caSigner != nil {
+		cert, err := signCert(r, keyID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, keyFile{Format: "openssh-cert", Path: base + "-cert.pub", Data: []byte(certLine(cert, r.Comment))})
+	}
+	if flagGitSigning {
+		f, err := allowedSignersFile(r, base)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return append(files, keyFile{Format: "openssh", Path: base + ".pub", Data: []byte(r.AuthorizedKeyLine())}), nil
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		formats    []string
+		ppkVersion int
+		kdfRounds  int
+		passEnv    string
+		wantSub    string
+	}{
+		{formats: []string{"openssh"}},
+		{formats: []string{"openssh"}, kdfRounds: 32},
+		{formats: []string{"ppk"}},
+		{formats: []string{"ppk"}, ppkVersion: 2},
+		{formats: []string{"openssh", "ppk"}, ppkVersion: 3, kdfRounds: 32},
+		{formats: []string{"pkcs8", "jwk", "openssh"}},
+		{formats: []string{"pkcs8"}, passEnv: "PASS"},
+		{formats: []string{"ppk"}, ppkVersion: 4, wantSub: "--ppk-version must be 2 or 3"},
+		{formats: []string{"ppk"}, kdfRounds: 32, wantSub: "--kdf-rounds only applies to --format openssh"},
+		{formats: []string{"openssh"}, ppkVersion: 2, wantSub: "--ppk-version only applies to --format ppk"},
+		{formats: []string{"pem"}, wantSub: "--format must be one of jwk, openssh, pkcs8, ppk"},
+		{formats: []string{"jwk", "pkcs8", "jwk"}, wantSub: "--format jwk given twice"},
+		{formats: []string{"openssh", "jwk"}, passEnv: "PASS", wantSub: "--format jwk cannot encrypt"},
+		{formats: nil, wantSub: "at least one format"},
+	}
+	for _, tt := range tests {
+		saveFlags(t)
+		flagFormats, flagPPKVersion, flagKDFRounds, flagPassphraseEnv = tt.formats, tt.ppkVersion, tt.kdfRounds, tt.passEnv
+		err := checkFormat()
+		switch {
+		case tt.wantSub == "" && err != nil:
+			t.Errorf("checkFormat(%+v) = %v, want nil", tt, err)
+		case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+			t.Errorf("checkFormat(%+v) = %v, want error containing %q", tt, err, tt.wantSub)
+		}
+	}
+}
+
+func TestCheckKeyOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyType keygen.KeyType
+		set     func()
+		wantSub string
+	}{
+		{name: "ssh key with every flag", keyType: keygen.KeyTypeED25519, set: func() { formatFlag.set, flagComment, flagHost = true, "c", true }},
+		{name: "onion", keyType: keygen.KeyTypeOnion, set: func() {}},
+		{name: "onion format", keyType: keygen.KeyTypeOnion, set: func() { formatFlag.set = true }, wantSub: "--format only applies to SSH keys, not --type onion"},
+		{name: "onion passphrase", keyType: keygen.KeyTypeOnion, set: func() { flagPassphraseEnv = "PASS" }, wantSub: "a passphrase only applies"},
+		{name: "onion comment", keyType: keygen.KeyTypeOnion, set: func() { flagComment = "c" }, wantSub: "--comment only applies"},
+		{name: "onion agent", keyType: keygen.KeyTypeOnion, set: func() { flagAddToAgent = true }, wantSub: "--add-to-agent only applies"},
+		{name: "onion host", keyType: keygen.KeyTypeOnion, set: func() { flagHost = true }, wantSub: "--host only applies"},
+		{name: "onion rounds", keyType: keygen.KeyTypeOnion, set: func() { flagKDFRounds = 32 }, wantSub: "--kdf-rounds only applies to SSH and signify keys"},
+		{name: "minisign passphrase", keyType: keygen.KeyTypeMinisign, set: func() { flagPassphraseEnv = "PASS" }},
+		{name: "minisign rounds", keyType: keygen.KeyTypeMinisign, set: func() { flagPassphraseEnv, flagKDFRounds = "PASS", 32 }, wantSub: "--kdf-rounds only applies to SSH and signify keys, not --type minisign"},
+		{name: "signify rounds", keyType: keygen.KeyTypeSignify, set: func() { flagPassphraseEnv, flagKDFRounds = "PASS", 32 }},
+		{name: "signify format", keyType: keygen.KeyTypeSignify, set: func() { formatFlag.set = true }, wantSub: "--format only applies"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			tt.set()
+			err := checkKeyOutput(tt.keyType)
+			switch {
+			case tt.wantSub == "" && err != nil:
+				t.Errorf("checkKeyOutput = %v, want nil", err)
+			case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+				t.Errorf("checkKeyOutput = %v, want error containing %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestRun_PPK(t *testing.T) {
+	for _, tt := range []struct {
+		args   []string
+		header string
+	}{
+		{args: []string{"--format", "ppk"}, header: "PuTTY-User-Key-File-3: ssh-ed25519\nEncryption: none\n"},
+		{args: []string{"--format", "ppk", "--ppk-version", "2"}, header: "PuTTY-User-Key-File-2: ssh-ed25519\nEncryption: none\n"},
+		{args: []string{"--format", "ppk", "--passphrase-env", "VANITYSSH_TEST_PASS"}, header: "PuTTY-User-Key-File-3: ssh-ed25519\nEncryption: aes256-cbc\n"},
+	} {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			dir := chdirTemp(t)
+			saveFlags(t)
+			t.Setenv("VANITYSSH_TEST_PASS", "hunter2")
+			rootCmd.SetArgs(append(tt.args, "-C", "putty", "--jobs", "1", "A"))
+			stdout := captureStdout(t, func() {
+				if err := rootCmd.Execute(); err != nil {
+					t.Fatalf("Execute error: %v", err)
+				}
+			})
+
+			ppk, err := os.ReadFile(filepath.Join(dir, "id_ed25519.ppk"))
+			if err != nil {
+				t.Fatalf("PuTTY key: %v", err)
+			}
+			if !strings.HasPrefix(string(ppk), tt.header) || !strings.Contains(string(ppk), "\nComment: putty\n") {
+				t.Errorf("PuTTY key =\n%s\nwant header %q and comment putty", ppk, tt.header)
+			}
+			if !strings.Contains(stdout, string(ppk)) {
+				t.Error("stdout does not contain the PuTTY key")
+			}
+			if info, err := os.Stat(filepath.Join(dir, "id_ed25519.ppk")); err == nil && info.Mode().Perm() != 0600 {
+				t.Errorf("PuTTY key mode = %v, want 0600", info.Mode().Perm())
+			}
+			pub, err := os.ReadFile(filepath.Join(dir, "id_ed25519.pub"))
+			if err != nil || !strings.HasPrefix(string(pub), "ssh-ed25519 ") {
+				t.Errorf("public key = %q (%v)", pub, err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "id_ed25519")); !os.IsNotExist(err) {
+				t.Errorf("OpenSSH private key written with --format ppk (%v)", err)
+			}
+		})
+	}
+}
+
+func TestRun_PPK_Exists(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	if err := os.WriteFile("id_ed25519.ppk", []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"--format", "ppk", "--jobs", "1", "."})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "id_ed25519.ppk already exists") {
+		t.Errorf("error = %v, want id_ed25519.ppk already exists", err)
+	}
+}
+
+func TestRun_MultipleFormats(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--format", "pkcs8,jwk", "--format", "openssh", "--jobs", "1", "A"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	read := func(name string) []byte {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return b
+	}
+	pkcs8 := read("id_ed25519.pem")
+	if !strings.Contains(stdout, string(pkcs8)) || strings.Contains(stdout, "OPENSSH PRIVATE KEY") {
+		t.Errorf("stdout = %q, want only the PKCS #8 key of the first --format", stdout)
+	}
+	block, _ := pem.Decode(pkcs8)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("id_ed25519.pem block = %v", block)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		t.Errorf("parse PKCS #8 key: %v", err)
+	}
+	if block, _ := pem.Decode(read("id_ed25519.pub.pem")); block == nil || block.Type != "PUBLIC KEY" {
+		t.Errorf("id_ed25519.pub.pem block = %v", block)
+	}
+	var jwk map[string]string
+	if err := json.Unmarshal(read("id_ed25519.jwk"), &jwk); err != nil || jwk["crv"] != "Ed25519" || jwk["d"] == "" {
+		t.Errorf("id_ed25519.jwk = %v (%v)", jwk, err)
+	}
+	if !strings.Contains(string(read("id_ed25519.jwks")), `"keys"`) {
+		t.Error("id_ed25519.jwks is not a key set")
+	}
+	if !strings.Contains(string(read("id_ed25519")), "OPENSSH PRIVATE KEY") {
+		t.Error("id_ed25519 is not an OpenSSH key")
+	}
+	read("id_ed25519.pub")
+	for name, want := range map[string]os.FileMode{"id_ed25519.pem": 0600, "id_ed25519.jwk": 0600, "id_ed25519.pub.pem": 0644, "id_ed25519.jwks": 0644} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if perm := info.Mode().Perm(); perm != want {
+			t.Errorf("%s permissions = %o, want %o", name, perm, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagGitSigning bool
+	flagEmail      string
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&flagGitSigning, "git-signing", false, "write <name>.allowed_signers for --email and print the git config commands to sign commits with the key")
+	rootCmd.Flags().StringVar(&flagEmail, "email", "", "with --git-signing, the committer email the key signs for")
+}
+
+// gitNamespace is the SSHSIG namespace git signs commits and tags in.
+const gitNamespace = "git"
+
+// gitSelfTestMessage is signed and verified before a git signing key is
+// reported.
+const gitSelfTestMessage = "vanityssh git signing self-test\n"
+
+// checkGit validates the git signing flags.
+func checkGit() error {
+	if !flagGitSigning {
+		if flagEmail != "" {
+			return fmt.Errorf("--email requires --git-signing")
+		}
+		return nil
+	}
+	if flagHost {
+		return fmt.Errorf("--git-signing cannot be used with --host")
+	}
+	if flagEmail == "" {
+		return fmt.Errorf("--git-signing requires --email")
+	}
+	if strings.ContainsAny(flagEmail, " \t\r\n,\"") {
+		return fmt.Errorf("--email %q cannot contain whitespace, commas or quotes", flagEmail)
+	}
+	if !slices.Contains(flagFormats, "openssh") && !flagAddToAgent {
+		return fmt.Errorf("--git-signing needs --format openssh or --add-to-agent, as git signs with an OpenSSH private key or through ssh-agent")
+	}
+	return nil
+}
+
+// allowedSignersFile returns the allowed_signers file for r at base, after
+// checking that the key produces SSH signatures that verify.
+func allowedSignersFile(r keygen.Result, base string) (keyFile, error) {
+	signer, err := ssh.NewSignerFromKey(r.PrivateKey)
+	if err != nil {
+		return keyFile{}, fmt.Errorf("git signing self-test: %w", err)
+	}
+	sig, err := keyfile.SignSSHSIG(signer, gitNamespace, []byte(gitSelfTestMessage))
+	if err != nil {
+		return keyFile{}, fmt.Errorf("git signing self-test: %w", err)
+	}
+	if err := keyfile.VerifySSHSIG(signer.PublicKey(), gitNamespace, []byte(gitSelfTestMessage), sig); err != nil {
+		return keyFile{}, fmt.Errorf("git signing self-test: %w", err)
+	}
+	line := fmt.Sprintf("%s namespaces=%q %s\n", flagEmail, gitNamespace, r.AuthorizedKeyLine())
+	return keyFile{Format: "allowed-signers", Path: base + ".allowed_signers", Data: []byte(line)}, nil
+}
+
+// gitConfig returns the git config commands that sign commits with the key
+// written as files.
+func gitConfig(files []keyFile) []string {
+	if !flagGitSigning {
+		return nil
+	}
+	cmds := []string{
+		"git config --global gpg.format ssh",
+		"git config --global user.signingkey " + shellQuote(absPath(gitSigningKey(files))),
+	}
+	for _, f := range files {
+		if f.Format == "allowed-signers" {
+			cmds = append(cmds, "git config --global gpg.ssh.allowedSignersFile "+shellQuote(absPath(f.Path)))
+		}
+	}
+	return cmds
+}
+
+// gitSigningKey returns the file user.signingkey names: the OpenSSH private
+// key, which ssh-keygen -Y sign reads itself, or when no private key was
+// written, as with --agent-only, the public key git signs with through
+// ssh-agent.
+func gitSigningKey(files []keyFile) string {
+	var pub string
+	for _, f := range files {
+		if f.Format == "openssh" {
+			if f.Secret {
+				return f.Path
+			}
+			pub = f.Path
+		}
+	}
+	return pub
+}
+
+// absPath returns path made absolute, so the git config holds wherever it
+// is run from.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// shellQuote quotes s for a POSIX shell unless it is plainly safe.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:@+=") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
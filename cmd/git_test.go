@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+)
+
+func TestCheckGit(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func()
+		wantSub string
+	}{
+		{name: "none", set: func() {}},
+		{name: "git signing", set: func() { flagGitSigning, flagEmail = true, "me@example.com" }},
+		{name: "email without git signing", set: func() { flagEmail = "me@example.com" }, wantSub: "--email requires --git-signing"},
+		{name: "no email", set: func() { flagGitSigning = true }, wantSub: "requires --email"},
+		{name: "bad email", set: func() { flagGitSigning, flagEmail = true, "a b@example.com" }, wantSub: "cannot contain whitespace"},
+		{name: "host", set: func() { flagGitSigning, flagEmail, flagHost = true, "me@example.com", true }, wantSub: "cannot be used with --host"},
+		{name: "no openssh key", set: func() { flagGitSigning, flagEmail, flagFormats = true, "me@example.com", []string{"ppk"} }, wantSub: "needs --format openssh or --add-to-agent"},
+		{name: "agent without openssh key", set: func() {
+			flagGitSigning, flagEmail, flagFormats, flagAddToAgent = true, "me@example.com", []string{"ppk"}, true
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			tt.set()
+			err := checkGit()
+			switch {
+			case tt.wantSub == "" && err != nil:
+				t.Errorf("checkGit() = %v, want nil", err)
+			case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+				t.Errorf("checkGit() = %v, want error containing %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"/home/me/.ssh/id_ed25519.pub": "/home/me/.ssh/id_ed25519.pub",
+		"/tmp/my keys/id":              "'/tmp/my keys/id'",
+		"it's":                         `'it'\''s'`,
+		"":                             "''",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGitSigningKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []keyFile
+		want  string
+	}{
+		{
+			name: "private key written",
+			files: []keyFile{
+				{Format: "ppk", Path: "k.ppk", Secret: true},
+				{Format: "openssh", Path: "k", Secret: true},
+				{Format: "allowed-signers", Path: "k.allowed_signers"},
+				{Format: "openssh", Path: "k.pub"},
+			},
+			want: "k",
+		},
+		{
+			name: "key only in the agent",
+			files: []keyFile{
+				{Format: "allowed-signers", Path: "k.allowed_signers"},
+				{Format: "openssh", Path: "k.pub"},
+			},
+			want: "k.pub",
+		},
+	}
+	for _, tt := range tests {
+		if got := gitSigningKey(tt.files); got != tt.want {
+			t.Errorf("%s: gitSigningKey = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRun_GitSigning(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--git-signing", "--email", "me@example.com", "-C", "laptop", "--jobs", "1", "A"})
+	var stdout string
+	stderr := captureStderr(t, func() {
+		stdout = captureStdout(t, func() {
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+		})
+	})
+	if strings.Contains(stdout, "git config") {
+		t.Errorf("stdout should carry only the private key:\n%s", stdout)
+	}
+
+	pubLine, err := os.ReadFile(filepath.Join(dir, "id_ed25519.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err := os.ReadFile(filepath.Join(dir, "id_ed25519.allowed_signers"))
+	if err != nil {
+		t.Fatalf("allowed_signers: %v", err)
+	}
+	if want := `me@example.com namespaces="git" ` + string(pubLine) + "\n"; string(signers) != want {
+		t.Errorf("allowed_signers = %q, want %q", signers, want)
+	}
+	// The temp dir may be reached through a symlink; git config gets the
+	// working directory's own name for it.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"git config --global gpg.format ssh",
+		"git config --global user.signingkey " + filepath.Join(wd, "id_ed25519"),
+		"git config --global gpg.ssh.allowedSignersFile " + filepath.Join(wd, "id_ed25519.allowed_signers"),
+	} {
+		if !strings.Contains(stderr, want+"\n") {
+			t.Errorf("stderr missing %q:\n%s", want, stderr)
+		}
+	}
+
+	// The key written signs for git the way the self-test checked.
+	signer, err := ssh.ParsePrivateKey([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := keyfile.SignSSHSIG(signer, "git", []byte("commit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(pubLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyfile.VerifySSHSIG(pub, "git", []byte("commit"), sig); err != nil {
+		t.Errorf("VerifySSHSIG: %v", err)
+	}
+}
+
+func TestRun_GitSigning_JSON(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--git-signing", "--email", "me@example.com", "--output", "json", "--jobs", "1", "A"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	var rec matchRecord
+	if err := json.Unmarshal([]byte(stdout), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	if want := `me@example.com namespaces="git" ` + rec.AuthorizedKey; rec.AllowedSigners != want {
+		t.Errorf("allowed_signers = %q, want %q", rec.AllowedSigners, want)
+	}
+	if len(rec.GitConfig) != 3 || rec.GitConfig[0] != "git config --global gpg.format ssh" {
+		t.Errorf("git_config = %q", rec.GitConfig)
+	}
+	if f := rec.Files[len(rec.Files)-2]; f.Format != "allowed-signers" || f.Path != "id_ed25519.allowed_signers" {
+		t.Errorf("files = %+v", rec.Files)
+	}
+}
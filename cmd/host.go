@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagHost           bool
+	flagHostname       string
+	flagHashKnownHosts bool
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&flagHost, "host", false, "generate host keys: write ssh_host_<type>_key and print SSHFP records and a known_hosts line")
+	rootCmd.Flags().StringVar(&flagHostname, "hostname", "", "with --host, comma-separated host[:port] names for SSHFP records and known_hosts (default this host's name)")
+	rootCmd.Flags().BoolVar(&flagHashKnownHosts, "hash-known-hosts", false, "with --host, hash host names in known_hosts lines, like ssh-keygen -H")
+}
+
+// sshfpAlgorithms are the SSHFP algorithm numbers of each key type (RFC
+// 4255, 6594 and 7479).
+var sshfpAlgorithms = map[string]int{
+	ssh.KeyAlgoRSA:      1,
+	ssh.KeyAlgoECDSA256: 3,
+	ssh.KeyAlgoECDSA384: 3,
+	ssh.KeyAlgoECDSA521: 3,
+	ssh.KeyAlgoED25519:  4,
+}
+
+// checkHost validates the host key flags.
+func checkHost() error {
+	if !flagHost {
+		if flagHostname != "" || flagHashKnownHosts {
+			return fmt.Errorf("--hostname and --hash-known-hosts require --host")
+		}
+		return nil
+	}
+	if flagPassphraseFile != "" || flagPassphraseEnv != "" || flagAskPassphrase {
+		return fmt.Errorf("--host keys cannot be encrypted; sshd cannot load them")
+	}
+	for _, name := range strings.Split(flagHostname, ",") {
+		if flagHostname != "" && strings.TrimSpace(name) == "" {
+			return fmt.Errorf("--hostname has an empty name")
+		}
+		if strings.ContainsAny(name, " \t\r\n") {
+			return fmt.Errorf("--hostname %q contains whitespace", name)
+		}
+	}
+	return nil
+}
+
+// hostnames returns the --hostname names, or this host's name.
+func hostnames() []string {
+	if flagHostname == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "localhost"
+		}
+		return []string{host}
+	}
+	var names []string
+	for name := range strings.SplitSeq(flagHostname, ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
+// hostPrincipals returns the host names without ports, each once: the
+// names SSHFP records and host certificates are for.
+func hostPrincipals() []string {
+	var names []string
+	for _, name := range hostnames() {
+		// DNS has no ports; [host]:port names share the records of host.
+		if host, _, err := net.SplitHostPort(knownhosts.Normalize(name)); err == nil {
+			name = host
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sshfpRecords returns the SSHFP resource records of r, SHA-1 then SHA-256,
+// for every host name that is not an IP address, as ssh-keygen -r prints
+// them.
+func sshfpRecords(r keygen.Result) ([]string, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.AuthorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	alg, ok := sshfpAlgorithms[pub.Type()]
+	if !ok {
+		return nil, fmt.Errorf("no SSHFP algorithm for %s keys", pub.Type())
+	}
+	sha1Sum, sha256Sum := sha1.Sum(pub.Marshal()), sha256.Sum256(pub.Marshal())
+
+	var records []string
+	for _, name := range hostPrincipals() {
+		if net.ParseIP(name) != nil {
+			continue
+		}
+		records = append(records,
+			fmt.Sprintf("%s IN SSHFP %d 1 %s", name, alg, hex.EncodeToString(sha1Sum[:])),
+			fmt.Sprintf("%s IN SSHFP %d 2 %s", name, alg, hex.EncodeToString(sha256Sum[:])))
+	}
+	return records, nil
+}
+
+// knownHostsLines returns the known_hosts lines for r: one naming every
+// host, or with --hash-known-hosts one per hashed name.
+func knownHostsLines(r keygen.Result) ([]string, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.AuthorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	names := hostnames()
+	if !flagHashKnownHosts {
+		return []string{knownhosts.Line(names, pub)}, nil
+	}
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, knownhosts.HashHostname(knownhosts.Normalize(name))+" "+r.AuthorizedKey)
+	}
+	return lines, nil
+}
+
+// hostLines returns the SSHFP records and known_hosts lines printed for a
+// host key, or nothing without --host.
+func hostLines(r keygen.Result) ([]string, error) {
+	if !flagHost {
+		return nil, nil
+	}
+	records, err := sshfpRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	known, err := knownHostsLines(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(records, known...), nil
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagOutput   string
+	flagProgress string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&flagOutput, "output", "text", "result format on stdout: text (PEM) or json (one object per match)")
+	rootCmd.Flags().StringVar(&flagProgress, "progress", "bar", "progress format: bar (status bar on a terminal) or json (records on stderr)")
+}
+
+// jsonSchemaVersion is the "v" field of every JSON record. It changes only
+// when a field is removed or changes meaning; new fields may be added to a
+// version at any time.
+const jsonSchemaVersion = 1
+
+// progressInterval is how often --progress json writes a record.
+const progressInterval = time.Second
+
+// checkOutputModes validates --output and --progress.
+func checkOutputModes() error {
+	if flagOutput != "text" && flagOutput != "json" {
+		return fmt.Errorf("--output must be text or json, got %q", flagOutput)
+	}
+	if flagProgress != "bar" && flagProgress != "json" {
+		return fmt.Errorf("--progress must be bar or json, got %q", flagProgress)
+	}
+	return nil
+}
+
+// matchRecord is the --output json record for one key found.
+type matchRecord struct {
+	V    int    `json:"v"`
+	Type string `json:"type"` // always "match"
+	// Index counts matches from 1; in --patterns mode it is the target's
+	// position in the patterns file.
+	Index   int    `json:"index"`
+	Target  string `json:"target,omitempty"`
+	KeyType string `json:"key_type"`
+	// Input is what the pattern was tested against, "public_key",
+	// "fingerprint" or "sshfp"; Match is the matched text and Span its byte
+	// offsets [start, end) within that text.
+	Input   string `json:"input"`
+	Pattern string `json:"pattern"`
+	Match   string `json:"match"`
+	Span    [2]int `json:"span"`
+	// AuthorizedKey, SHA256 and MD5 are set for SSH keys, and PublicKey,
+	// the text the pattern was tested against, for other key types.
+	AuthorizedKey string `json:"authorized_key,omitempty"`
+	PublicKey     string `json:"public_key,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+	SHA256        string `json:"sha256,omitempty"`
+	MD5           string `json:"md5,omitempty"`
+	// PrivateKey is the private key file of the first --format, Format.
+	// With --agent-only the private key fields are omitted, and
+	// PrivateKey is also omitted for binary files.
+	PrivateKey  string `json:"private_key,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Encrypted   bool   `json:"encrypted"`
+	PrivateFile string `json:"private_key_file,omitempty"`
+	PublicFile  string `json:"public_key_file"`
+	// Agent reports that the key was added to ssh-agent.
+	Agent bool `json:"agent,omitempty"`
+	// Certificate and KeyID are set with --ca-key.
+	Certificate string `json:"certificate,omitempty"`
+	KeyID       string `json:"key_id,omitempty"`
+	// SSHFP and KnownHosts are set with --host.
+	SSHFP      []string `json:"sshfp,omitempty"`
+	KnownHosts []string `json:"known_hosts,omitempty"`
+	// AllowedSigners and GitConfig are set with --git-signing.
+	AllowedSigners string   `json:"allowed_signers,omitempty"`
+	GitConfig      []string `json:"git_config,omitempty"`
+	// WireGuardPeer is the [Peer] section written with --wg-config.
+	WireGuardPeer string `json:"wireguard_peer,omitempty"`
+	// Files lists every file written for the match.
+	Files []fileRecord `json:"files"`
+	// Keys, Elapsed (seconds) and Worker describe the search when the key
+	// was found.
+	Keys    int64   `json:"keys"`
+	Elapsed float64 `json:"elapsed"`
+	Worker  int     `json:"worker"`
+}
+
+// fileRecord is one file written for a match.
+type fileRecord struct {
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	Secret bool   `json:"secret"`
+}
+
+// newMatchRecord builds the record for r, the index-th match, found by re
+// against its input text and written as files (see keyFiles).
+func newMatchRecord(r keygen.Result, index int, target string, re *regexp.Regexp, input keygen.Input, files []keyFile) (matchRecord, error) {
+	text := r.Text(input)
+	rec := matchRecord{
+		V:          jsonSchemaVersion,
+		Type:       "match",
+		Index:      index,
+		Target:     target,
+		KeyType:    keyLabel(keygen.KeyType(flagType)),
+		Input:      "public_key",
+		Pattern:    re.String(),
+		Comment:    r.Comment,
+		Encrypted:  passphrase != nil,
+		PublicFile: files[len(files)-1].Path,
+		Keys:       r.Keys,
+		Elapsed:    r.Elapsed.Seconds(),
+		Worker:     r.Worker,
+	}
+	if keygen.KeyType(flagType).SSH() {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.AuthorizedKey))
+		if err != nil {
+			return matchRecord{}, fmt.Errorf("parse public key: %w", err)
+		}
+		rec.AuthorizedKey = r.AuthorizedKeyLine()
+		rec.SHA256, rec.MD5 = ssh.FingerprintSHA256(pub), "MD5:"+ssh.FingerprintLegacyMD5(pub)
+	} else {
+		rec.PublicKey = r.AuthorizedKey
+	}
+	if files[0].Secret {
+		rec.Format, rec.PrivateFile = files[0].Format, files[0].Path
+		if !files[0].Binary {
+			rec.PrivateKey = string(files[0].Data)
+		}
+	}
+	if cert := fileCert(files); cert != nil {
+		rec.Certificate, rec.KeyID = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))), cert.KeyId
+	}
+	for _, f := range files {
+		rec.Files = append(rec.Files, fileRecord{Format: f.Format, Path: f.Path, Secret: f.Secret})
+		if f.Format == "allowed-signers" {
+			rec.AllowedSigners = strings.TrimSuffix(string(f.Data), "\n")
+		}
+	}
+	rec.GitConfig = gitConfig(files)
+	rec.WireGuardPeer = strings.Join(wireguardPeer(files), "\n")
+	switch input {
+	case keygen.InputFingerprint:
+		rec.Input = "fingerprint"
+	case keygen.InputSSHFP:
+		rec.Input = "sshfp"
+	}
+	if flagHost {
+		var err error
+		if rec.SSHFP, err = sshfpRecords(r); err != nil {
+			return matchRecord{}, err
+		}
+		if rec.KnownHosts, err = knownHostsLines(r); err != nil {
+			return matchRecord{}, err
+		}
+	}
+	if loc := re.FindStringIndex(text); loc != nil {
+		rec.Span = [2]int{loc[0], loc[1]}
+		rec.Match = text[loc[0]:loc[1]]
+	}
+	return rec, nil
+}
+
+// progressRecord is the --progress json record, mirroring the status bar.
+type progressRecord struct {
+	V       int     `json:"v"`
+	Type    string  `json:"type"` // always "progress"
+	KeyType string  `json:"key_type"`
+	Keys    int64   `json:"keys"`
+	Rate    float64 `json:"rate"` // keys per second
+	Matches int64   `json:"matches"`
+	Elapsed float64 `json:"elapsed"` // seconds
+	Engine  string  `json:"engine"`
+	// The odds fields are omitted while unknown: ETA before the key rate
+	// is known, all of them when the pattern is too complex to estimate.
+	// Chance is reported in single-match and --patterns mode, Expected in
+	// continuous mode.
+	ETA      *float64 `json:"eta,omitempty"` // seconds
+	Chance   *float64 `json:"chance,omitempty"`
+	Expected *float64 `json:"expected,omitempty"`
+	Luck     string   `json:"luck,omitempty"`
+	// Targets is set in --patterns mode.
+	Targets *targetCount `json:"targets,omitempty"`
+}
+
+// targetCount is how many of the --patterns targets have been found.
+type targetCount struct {
+	Found int `json:"found"`
+	Total int `json:"total"`
+}
+
+// newProgressRecord builds the progress record for st.
+func newProgressRecord(st keygen.Stats, kt keygen.KeyType, engine any, pr *progress, targets *keygen.TargetSet) progressRecord {
+	rec := progressRecord{
+		V:       jsonSchemaVersion,
+		Type:    "progress",
+		KeyType: keyLabel(kt),
+		Keys:    st.Keys,
+		Matches: st.Matches,
+		Elapsed: st.Elapsed.Seconds(),
+		Engine:  fmt.Sprint(engine),
+	}
+	if st.Elapsed > 0 {
+		rec.Rate = float64(st.Keys) / st.Elapsed.Seconds()
+	}
+	if o, ok := pr.odds(st); ok {
+		rec.ETA = finite(o.ETA)
+		if pr.continuous {
+			rec.Expected = &o.Expected
+		} else {
+			rec.Chance = &o.Chance
+		}
+		rec.Luck = o.Luck
+	}
+	if targets != nil {
+		rec.Targets = &targetCount{Found: targets.Len() - targets.Remaining(), Total: targets.Len()}
+	}
+	return rec
+}
+
+// finite returns &v, or nil when v is unknown (zero) or not representable
+// in JSON.
+func finite(v float64) *float64 {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
+
+// writeJSON writes v as one line of JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
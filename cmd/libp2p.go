@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func init() {
+	keyOutputs[keygen.KeyTypeLibP2P] = keyOutput{
+		name:     "libp2p_key",
+		suffixes: func() []string { return []string{"", ".id"} },
+		files:    libp2pFiles,
+		public:   libp2pPublic,
+	}
+}
+
+// libp2pFiles returns the protobuf private key for r at base, which
+// go-libp2p loads and ipfs key import takes, and its peer ID at base.id.
+func libp2pFiles(r keygen.Result, base string) ([]keyFile, error) {
+	key, ok := r.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("libp2p key is %T, not ed25519", r.PrivateKey)
+	}
+	return []keyFile{
+		{Format: "libp2p", Path: base, Data: keyfile.LibP2PPrivateKey(key), Secret: true, Binary: true},
+		{Format: "peer-id", Path: base + ".id", Data: []byte(r.AuthorizedKey + "\n")},
+	}, nil
+}
+
+// libp2pPublic returns the peer ID of a libp2p key and the IPNS name its
+// CID gives it.
+func libp2pPublic(r keygen.Result) []string {
+	return []string{r.AuthorizedKey, "/ipns/" + r.Fingerprint}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestRun_LibP2P(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "libp2p", "--jobs", "1", "^12D3KooWA"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	id, err := os.ReadFile(filepath.Join(dir, "libp2p_key.id"))
+	if err != nil {
+		t.Fatalf("peer ID file: %v", err)
+	}
+	if stdout != string(id) || !strings.HasPrefix(stdout, "12D3KooWA") || len(stdout) != 53 {
+		t.Errorf("stdout = %q, want the peer ID file %q", stdout, id)
+	}
+	key, err := os.ReadFile(filepath.Join(dir, "libp2p_key"))
+	if err != nil {
+		t.Fatalf("private key file: %v", err)
+	}
+	if len(key) != 68 || !bytes.HasPrefix(key, []byte{0x08, 0x01, 0x12, 0x40}) {
+		t.Fatalf("private key file = %x, want a protobuf ed25519 PrivateKey", key)
+	}
+	pub := ed25519.NewKeyFromSeed(key[4:36]).Public().(ed25519.PublicKey)
+	if !bytes.Equal(key[36:], pub) || keygen.LibP2PPeerID(pub)+"\n" != string(id) {
+		t.Errorf("private key file does not hold the key of peer ID %q", id)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "libp2p_key")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("private key file mode: %v, %v", info, err)
+	}
+}
+
+func TestRun_LibP2P_CID(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "libp2p", "--fingerprint", "--output", "json", "--jobs", "1", "^bafzaajaiaejcb"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	var rec matchRecord
+	if err := json.Unmarshal([]byte(stdout), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	if rec.KeyType != "libp2p" || !strings.HasPrefix(rec.PublicKey, "12D3KooW") || rec.Match != "bafzaajaiaejcb" {
+		t.Errorf("key type %q, public key %q, match %q", rec.KeyType, rec.PublicKey, rec.Match)
+	}
+	if rec.PrivateKey != "" || rec.PrivateFile != "libp2p_key" || rec.PublicFile != "libp2p_key.id" {
+		t.Errorf("private key %q, private %q, public %q", rec.PrivateKey, rec.PrivateFile, rec.PublicFile)
+	}
+}
+
+func TestPublicLines_LibP2P(t *testing.T) {
+	saveFlags(t)
+	flagType = string(keygen.KeyTypeLibP2P)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := keygen.Result{AuthorizedKey: keygen.LibP2PPeerID(pub), Fingerprint: keygen.LibP2PCID(pub)}
+	got := publicLines(r, nil)
+	if len(got) != 2 || got[0] != r.AuthorizedKey || got[1] != "/ipns/"+r.Fingerprint {
+		t.Errorf("publicLines = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func init() {
+	keyOutputs[keygen.KeyTypeMinisign] = keyOutput{
+		name:     "minisign",
+		suffixes: func() []string { return []string{".key", ".pub"} },
+		files:    minisignFiles,
+		encrypts: true,
+	}
+	keyOutputs[keygen.KeyTypeSignify] = keyOutput{
+		name:     "signify",
+		suffixes: func() []string { return []string{".sec", ".pub"} },
+		files:    signifyFiles,
+		encrypts: true,
+		rounds:   true,
+	}
+}
+
+// signifyKey returns the ed25519 key and key number of a minisign or
+// signify match.
+func signifyKey(r keygen.Result) (ed25519.PrivateKey, [keygen.SignifyKeyNumSize]byte, error) {
+	var keyNum [keygen.SignifyKeyNumSize]byte
+	key, ok := r.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, keyNum, fmt.Errorf("%s key is %T, not ed25519", flagType, r.PrivateKey)
+	}
+	// The public key is "Ed", the key number and the ed25519 public key.
+	blob, err := base64.StdEncoding.DecodeString(r.AuthorizedKey)
+	if err != nil || len(blob) != 2+len(keyNum)+ed25519.PublicKeySize {
+		return nil, keyNum, fmt.Errorf("bad %s public key %q", flagType, r.AuthorizedKey)
+	}
+	copy(keyNum[:], blob[2:])
+	return key, keyNum, nil
+}
+
+// minisignFiles returns the secret key for r at base.key and the public
+// key at base.pub, as minisign -G writes them: encrypted when a passphrase
+// is set, and otherwise as with -W.
+func minisignFiles(r keygen.Result, base string) ([]keyFile, error) {
+	key, keyNum, err := signifyKey(r)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := keyfile.MinisignSecretKey(key, keyNum, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("encode minisign key: %w", err)
+	}
+	return []keyFile{
+		{Format: "minisign", Path: base + ".key", Data: secret, Secret: true},
+		{Format: "minisign", Path: base + ".pub", Data: keyfile.MinisignPublicKey(key.Public().(ed25519.PublicKey), keyNum)},
+	}, nil
+}
+
+// signifyFiles returns the secret key for r at base.sec and the public key
+// at base.pub, as signify -G writes them: encrypted with --kdf-rounds when
+// a passphrase is set, and otherwise as with -n.
+func signifyFiles(r keygen.Result, base string) ([]keyFile, error) {
+	key, keyNum, err := signifyKey(r)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := keyfile.SignifySecretKey(key, keyNum, passphrase, flagKDFRounds)
+	if err != nil {
+		return nil, fmt.Errorf("encode signify key: %w", err)
+	}
+	return []keyFile{
+		{Format: "signify", Path: base + ".sec", Data: secret, Secret: true},
+		{Format: "signify", Path: base + ".pub", Data: keyfile.SignifyPublicKey(key.Public().(ed25519.PublicKey), keyNum)},
+	}, nil
+}
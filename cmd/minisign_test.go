@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signifyBlob returns the comment and decoded blob of a minisign or signify
+// key file.
+func signifyBlob(t *testing.T, file []byte) (string, []byte) {
+	t.Helper()
+	comment, data, ok := strings.Cut(strings.TrimSuffix(string(file), "\n"), "\n")
+	if !ok || !strings.HasPrefix(comment, "untrusted comment: ") {
+		t.Fatalf("key file = %q, want a comment and a blob", file)
+	}
+	blob, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatalf("key file blob %q: %v", data, err)
+	}
+	return strings.TrimPrefix(comment, "untrusted comment: "), blob
+}
+
+func TestRun_Minisign(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "minisign", "--fingerprint", "--jobs", "1", "^CA"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	secret, err := os.ReadFile(filepath.Join(dir, "minisign.key"))
+	if err != nil {
+		t.Fatalf("secret key file: %v", err)
+	}
+	if stdout != string(secret) {
+		t.Errorf("stdout = %q, want the secret key file %q", stdout, secret)
+	}
+	public, err := os.ReadFile(filepath.Join(dir, "minisign.pub"))
+	if err != nil {
+		t.Fatalf("public key file: %v", err)
+	}
+	comment, pub := signifyBlob(t, public)
+	keyID := fmt.Sprintf("%016X", binary.LittleEndian.Uint64(pub[2:10]))
+	if len(pub) != 42 || string(pub[:2]) != "Ed" || comment != "minisign public key "+keyID || !strings.HasPrefix(keyID, "CA") {
+		t.Errorf("public key file = %q", public)
+	}
+	// Unencrypted: "Ed", no KDF, "B2", salt, limits, then the key number.
+	comment, sec := signifyBlob(t, secret)
+	if comment != "minisign secret key" || len(sec) != 158 || string(sec[:6]) != "Ed\x00\x00B2" || string(sec[54:62]) != string(pub[2:10]) {
+		t.Errorf("secret key file = %q", secret)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "minisign.key")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("secret key file mode: %v, %v", info, err)
+	}
+}
+
+func TestRun_Signify(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantRounds uint32
+	}{
+		{name: "unencrypted", args: []string{"^RWR"}},
+		{name: "encrypted", args: []string{"--passphrase-env", "VANITYSSH_TEST_PASS", "-a", "4", "^RWR"}, wantRounds: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := chdirTemp(t)
+			saveFlags(t)
+			t.Setenv("VANITYSSH_TEST_PASS", "hunter2")
+			rootCmd.SetArgs(append([]string{"--type", "signify", "--jobs", "1"}, tt.args...))
+			captureStdout(t, func() {
+				if err := rootCmd.Execute(); err != nil {
+					t.Fatalf("Execute error: %v", err)
+				}
+			})
+
+			public, err := os.ReadFile(filepath.Join(dir, "signify.pub"))
+			if err != nil {
+				t.Fatalf("public key file: %v", err)
+			}
+			if comment, pub := signifyBlob(t, public); comment != "signify public key" || len(pub) != 42 || !strings.HasPrefix(string(public), "untrusted comment: signify public key\nRWR") {
+				t.Errorf("public key file = %q", public)
+			}
+			secret, err := os.ReadFile(filepath.Join(dir, "signify.sec"))
+			if err != nil {
+				t.Fatalf("secret key file: %v", err)
+			}
+			comment, sec := signifyBlob(t, secret)
+			if comment != "signify secret key" || len(sec) != 104 || string(sec[:4]) != "EdBK" {
+				t.Fatalf("secret key file = %q", secret)
+			}
+			if rounds := binary.BigEndian.Uint32(sec[4:8]); rounds != tt.wantRounds {
+				t.Errorf("rounds = %d, want %d", rounds, tt.wantRounds)
+			}
+		})
+	}
+}
+
+func TestRun_Signify_JSON(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "signify", "--output", "json", "--jobs", "1", "x$"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	var rec matchRecord
+	if err := json.Unmarshal([]byte(stdout), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	if rec.KeyType != "signify" || len(rec.PublicKey) != 56 || !strings.HasSuffix(rec.PublicKey, "x") {
+		t.Errorf("key type %q, public key %q", rec.KeyType, rec.PublicKey)
+	}
+	if !strings.HasPrefix(rec.PrivateKey, "untrusted comment: signify secret key\n") || rec.Encrypted {
+		t.Errorf("private key %q, encrypted %v", rec.PrivateKey, rec.Encrypted)
+	}
+	if rec.PrivateFile != "signify.sec" || rec.PublicFile != "signify.pub" {
+		t.Errorf("private %q, public %q", rec.PrivateFile, rec.PublicFile)
+	}
+}
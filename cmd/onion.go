@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"path/filepath"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+// The files of a Tor onion service directory, as tor writes them for
+// HiddenServiceDir.
+const (
+	torSecretKeyFile = "hs_ed25519_secret_key"
+	torPublicKeyFile = "hs_ed25519_public_key"
+	torHostnameFile  = "hostname"
+)
+
+func init() {
+	keyOutputs[keygen.KeyTypeOnion] = keyOutput{
+		name: "onion_service",
+		suffixes: func() []string {
+			return dirSuffixes(torSecretKeyFile, torPublicKeyFile, torHostnameFile)
+		},
+		files: onionFiles,
+	}
+}
+
+// onionFiles returns the onion service directory for r at base: the key
+// files tor loads and the hostname file holding the address.
+func onionFiles(r keygen.Result, base string) ([]keyFile, error) {
+	priv, ok := r.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("onion service key is %T, not ed25519", r.PrivateKey)
+	}
+	return []keyFile{
+		{Format: "tor", Path: filepath.Join(base, torSecretKeyFile), Data: keyfile.TorSecretKey(priv), Secret: true, Binary: true},
+		{Format: "tor", Path: filepath.Join(base, torPublicKeyFile), Data: keyfile.TorPublicKey(priv.Public().(ed25519.PublicKey)), Binary: true},
+		{Format: "onion", Path: filepath.Join(base, torHostnameFile), Data: []byte(r.AuthorizedKey + ".onion\n")},
+	}, nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestRun_Onion(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "onion", "--jobs", "1", "^ab"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	service := filepath.Join(dir, "onion_service")
+	info, err := os.Stat(service)
+	if err != nil {
+		t.Fatalf("service directory: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("service directory mode %o, want 700", perm)
+	}
+	hostname, err := os.ReadFile(filepath.Join(service, "hostname"))
+	if err != nil {
+		t.Fatalf("hostname: %v", err)
+	}
+	if stdout != string(hostname) {
+		t.Errorf("stdout = %q, want the hostname file %q", stdout, hostname)
+	}
+	pubFile, err := os.ReadFile(filepath.Join(service, "hs_ed25519_public_key"))
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	pub := ed25519.PublicKey(bytes.TrimPrefix(pubFile, []byte("== ed25519v1-public: type0 ==\x00\x00\x00")))
+	if addr := keygen.OnionAddress(pub) + ".onion\n"; string(hostname) != addr || !strings.HasPrefix(addr, "ab") {
+		t.Errorf("hostname = %q, public key gives %q", hostname, addr)
+	}
+	secret, err := os.Stat(filepath.Join(service, "hs_ed25519_secret_key"))
+	if err != nil {
+		t.Fatalf("secret key: %v", err)
+	}
+	if perm := secret.Mode().Perm(); perm != 0600 || secret.Size() != 96 {
+		t.Errorf("secret key mode %o size %d, want 600 and 96 bytes", perm, secret.Size())
+	}
+}
+
+func TestRun_Onion_JSON(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "onion", "--output", "json", "--name", "blog", "--jobs", "1", "d$"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	var rec matchRecord
+	if err := json.Unmarshal([]byte(stdout), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	if rec.KeyType != "onion" || len(rec.PublicKey) != 56 || rec.Match != "d" {
+		t.Errorf("key type %q, public key %q, match %q", rec.KeyType, rec.PublicKey, rec.Match)
+	}
+	if rec.AuthorizedKey != "" || rec.SHA256 != "" || rec.PrivateKey != "" {
+		t.Errorf("record has SSH key fields or a binary private key: %+v", rec)
+	}
+	if rec.PrivateFile != filepath.Join("blog", "hs_ed25519_secret_key") || rec.PublicFile != filepath.Join("blog", "hostname") || len(rec.Files) != 3 {
+		t.Errorf("private %q, public %q, files %+v", rec.PrivateFile, rec.PublicFile, rec.Files)
+	}
+}
+
+func TestRun_OnionExists(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	if err := os.MkdirAll(filepath.Join(dir, "onion_service"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "onion_service", "hostname"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"--type", "onion", "a"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Execute error = %v, want already exists", err)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var flagUID string
+
+func init() {
+	rootCmd.Flags().StringVar(&flagUID, "uid", "", `with --type openpgp, the user ID of the key, e.g. "Name <email>"`)
+
+	keyOutputs[keygen.KeyTypeOpenPGP] = keyOutput{
+		name:     "openpgp_key",
+		suffixes: func() []string { return []string{".sec.asc", ".pub.asc"} },
+		files:    openpgpFiles,
+		public:   openpgpPublic,
+	}
+}
+
+// checkOpenPGP validates --uid.
+func checkOpenPGP() error {
+	if keygen.KeyType(flagType) != keygen.KeyTypeOpenPGP {
+		if flagUID != "" {
+			return fmt.Errorf("--uid requires --type openpgp")
+		}
+		return nil
+	}
+	if strings.TrimSpace(flagUID) == "" {
+		return fmt.Errorf(`--type openpgp requires --uid, e.g. --uid "Name <email>"`)
+	}
+	if strings.ContainsAny(flagUID, "\r\n") {
+		return fmt.Errorf("--uid must be a single line")
+	}
+	return nil
+}
+
+// openpgpFiles returns the armored secret key for r at base.sec.asc, which
+// gpg --import takes, and the public key at base.pub.asc, both self-signed
+// now.
+func openpgpFiles(r keygen.Result, base string) ([]keyFile, error) {
+	key, ok := r.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("openpgp key is %T, not ed25519", r.PrivateKey)
+	}
+	signed := time.Now()
+	return []keyFile{
+		{Format: "openpgp", Path: base + ".sec.asc", Data: keyfile.OpenPGPSecretKey(key, r.Created, flagUID, signed), Secret: true},
+		{Format: "openpgp", Path: base + ".pub.asc", Data: keyfile.OpenPGPPublicKey(key, r.Created, flagUID, signed)},
+	}, nil
+}
+
+// openpgpPublic returns the lines identifying an OpenPGP key, as gpg
+// --list-keys prints them.
+func openpgpPublic(r keygen.Result) []string {
+	return []string{
+		"pub   ed25519 " + r.Created.UTC().Format(time.DateOnly) + " [SC]",
+		"      " + r.Fingerprint,
+		"uid   " + flagUID,
+	}
+}
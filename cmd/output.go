@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagOutDir string
+	flagName   string
+	flagForce  bool
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&flagOutDir, "out-dir", ".", "directory to write key files to")
+	rootCmd.Flags().StringVar(&flagName, "name", "", "key file name template: {n}, {fp8}, {type}, {target} (default id_{type}; id_{type}_{n} with -c; {target}_{type} with --patterns; ssh_host_{type}_key with --host; onion_service with --type onion; age_key with --type age; openpgp_key with --type openpgp; minisign or signify with those types; libp2p_key with --type libp2p)")
+	rootCmd.Flags().BoolVar(&flagForce, "force", false, "overwrite existing key files")
+}
+
+// nameFields are the placeholders a --name template may use.
+var nameFields = []string{"{n}", "{fp8}", "{type}", "{target}"}
+
+// errFileExists is returned instead of overwriting a key file without --force.
+var errFileExists = errors.New("already exists; use --force to overwrite")
+
+// nameTemplate returns the --name template in effect for this run.
+func nameTemplate() string {
+	switch {
+	case flagName != "":
+		return flagName
+	case flagHost && flagPatterns != "":
+		return "{target}_ssh_host_{type}_key"
+	case flagHost && flagContinuous:
+		return "ssh_host_{type}_key_{n}"
+	case flagHost:
+		return "ssh_host_{type}_key"
+	case flagPatterns != "":
+		return "{target}_{type}"
+	case flagContinuous:
+		return nameStem() + "_{n}"
+	default:
+		return nameStem()
+	}
+}
+
+// nameStem returns the default name of a single key of the --type.
+func nameStem() string {
+	if out, ok := keyOutputs[keygen.KeyType(flagType)]; ok {
+		return out.name
+	}
+	return "id_{type}"
+}
+
+// checkName rejects --name templates that use unknown placeholders, leave
+// the output directory, or would give every key of the run the same name.
+func checkName(tmpl string) error {
+	if err := checkPlaceholders("--name", tmpl, nameFields); err != nil {
+		return err
+	}
+	if strings.ContainsAny(tmpl, `/\`) || tmpl == "." || tmpl == ".." {
+		return fmt.Errorf("--name must be a file name; use --out-dir for the directory")
+	}
+	switch {
+	case flagPatterns != "" && !strings.Contains(tmpl, "{target}"):
+		return fmt.Errorf("--name must contain {target} with --patterns")
+	case flagContinuous && !strings.Contains(tmpl, "{n}") && !strings.Contains(tmpl, "{fp8}"):
+		return fmt.Errorf("--name must contain {n} or {fp8} with --continuous")
+	}
+	return nil
+}
+
+// keyPath returns the path of the key files for the n-th match, found for
+// target in --patterns mode. Each file appends its suffix (see
+// keyFileSuffixes) to the path.
+func keyPath(r keygen.Result, n int, target string) string {
+	// Fingerprints are standard base64; keep the first eight characters
+	// safe in file names. Key types without one use their public text.
+	fp := r.Fingerprint
+	if fp == "" {
+		fp = r.AuthorizedKey
+	}
+	fp8 := strings.NewReplacer("+", "-", "/", "_").Replace(fp[:min(8, len(fp))])
+	name := strings.NewReplacer(
+		"{n}", strconv.Itoa(n),
+		"{fp8}", fp8,
+		"{type}", keyFileSuffix(),
+		"{target}", target,
+	).Replace(nameTemplate())
+	return filepath.Join(flagOutDir, name)
+}
+
+// checkOutputFree fails fast, before any key is generated, when the first
+// key files of the run already exist. Names that depend on the key itself
+// cannot be checked until it is found.
+func checkOutputFree(targets []keygen.Target) error {
+	if flagForce || strings.Contains(nameTemplate(), "{fp8}") {
+		return nil
+	}
+	names := []string{""}
+	if targets != nil {
+		names = names[:0]
+		for _, tg := range targets {
+			names = append(names, tg.Name)
+		}
+	}
+	for _, name := range names {
+		base := keyPath(keygen.Result{}, 1, name)
+		for _, suffix := range keyFileSuffixes() {
+			if path := base + suffix; fileExists(path) {
+				return fmt.Errorf("%s %w", path, errFileExists)
+			}
+		}
+	}
+	return nil
+}
+
+// writeKeyFiles writes the files of one match, private keys 0600 and
+// public keys 0644. Unless --force is set, no file is written if any
+// exists.
+func writeKeyFiles(files []keyFile) error {
+	if err := os.MkdirAll(filepath.Dir(files[0].Path), 0700); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	if !flagForce {
+		for _, f := range files {
+			if fileExists(f.Path) {
+				return fmt.Errorf("%s: %s %w", f.kind(), f.Path, errFileExists)
+			}
+		}
+	}
+	for _, f := range files {
+		perm := fs.FileMode(0644)
+		if f.Secret {
+			perm = 0600
+		}
+		if err := writeFileAtomic(f.Path, f.Data, perm); err != nil {
+			return fmt.Errorf("%s: %w", f.kind(), err)
+		}
+	}
+	return nil
+}
+
+// kind names f in write errors.
+func (f keyFile) kind() string {
+	if f.Secret {
+		return "write private key"
+	}
+	return "write public key"
+}
+
+// fileExists reports whether path names an existing file or link.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// writeFileAtomic writes data to a temporary file in the destination
+// directory, syncs it and moves it into place, so path never holds a
+// partial key. Without --force an existing path is left alone, even one
+// created after writeKeyFiles checked.
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if flagForce {
+		err = os.Rename(tmp, path)
+	} else {
+		// A hard link fails if path exists, unlike rename.
+		err = os.Link(tmp, path)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s %w", path, errFileExists)
+		}
+		if err == nil {
+			err = os.Remove(tmp)
+		} else if _, serr := os.Lstat(path); errors.Is(serr, fs.ErrNotExist) {
+			// The file system has no hard links.
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a rename to disk where the platform supports syncing a
+// directory; elsewhere it is a no-op.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestKeyPath(t *testing.T) {
+	r := keygen.Result{Fingerprint: "ab+/cdefgh0123"}
+	tests := []struct {
+		name       string
+		tmpl       string
+		outDir     string
+		continuous bool
+		host       bool
+		keyType    string
+		patterns   string
+		n          int
+		target     string
+		want       string
+	}{
+		{name: "default", outDir: ".", want: "id_ed25519"},
+		{name: "default continuous", outDir: ".", continuous: true, n: 3, want: "id_ed25519_3"},
+		{name: "default patterns", outDir: "keys", patterns: "t.txt", target: "alice", want: filepath.Join("keys", "alice_ed25519")},
+		{name: "fp8 is file name safe", tmpl: "vanity_{n}_{fp8}", outDir: "out", n: 7, want: filepath.Join("out", "vanity_7_ab-_cdef")},
+		{name: "type", tmpl: "{type}-key", outDir: ".", want: "ed25519-key"},
+		{name: "host", outDir: "/etc/ssh", host: true, want: filepath.Join("/etc/ssh", "ssh_host_ed25519_key")},
+		{name: "host continuous", outDir: ".", host: true, continuous: true, n: 2, want: "ssh_host_ed25519_key_2"},
+		{name: "host patterns", outDir: ".", host: true, patterns: "t.txt", target: "bastion", want: "bastion_ssh_host_ed25519_key"},
+		{name: "onion", outDir: ".", keyType: "onion", want: "onion_service"},
+		{name: "onion continuous", outDir: ".", keyType: "onion", continuous: true, n: 2, want: "onion_service_2"},
+		{name: "openpgp", outDir: ".", keyType: "openpgp", want: "openpgp_key"},
+		{name: "wireguard", outDir: "peers", keyType: "wireguard", want: filepath.Join("peers", "wireguard")},
+		{name: "onion patterns", outDir: ".", keyType: "onion", patterns: "t.txt", target: "blog", want: "blog_onion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			flagName, flagOutDir, flagContinuous, flagPatterns, flagHost = tt.tmpl, tt.outDir, tt.continuous, tt.patterns, tt.host
+			if tt.keyType != "" {
+				flagType = tt.keyType
+			}
+			if got := keyPath(r, tt.n, tt.target); got != tt.want {
+				t.Errorf("keyPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		name       string
+		tmpl       string
+		continuous bool
+		patterns   string
+		wantSub    string
+	}{
+		{name: "plain", tmpl: "mykey"},
+		{name: "all placeholders", tmpl: "{target}_{type}_{n}_{fp8}", patterns: "t.txt"},
+		{name: "unknown placeholder", tmpl: "key_{date}", wantSub: "unknown placeholder {date}"},
+		{name: "path separator", tmpl: "keys/id", wantSub: "use --out-dir"},
+		{name: "dot dot", tmpl: "..", wantSub: "use --out-dir"},
+		{name: "continuous without number", tmpl: "mykey", continuous: true, wantSub: "{n} or {fp8}"},
+		{name: "continuous with fp8", tmpl: "key_{fp8}", continuous: true},
+		{name: "patterns without target", tmpl: "key_{n}", patterns: "t.txt", wantSub: "{target}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			flagContinuous, flagPatterns = tt.continuous, tt.patterns
+			err := checkName(tt.tmpl)
+			switch {
+			case tt.wantSub == "" && err != nil:
+				t.Errorf("checkName(%q) = %v, want nil", tt.tmpl, err)
+			case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+				t.Errorf("checkName(%q) = %v, want error containing %q", tt.tmpl, err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestWriteKeyFiles_Overwrite(t *testing.T) {
+	dir := t.TempDir()
+	saveFlags(t)
+	priv := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(priv+".pub", []byte("precious"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	flagForce = false
+	files := []keyFile{
+		{Path: priv, Data: []byte("new private"), Secret: true},
+		{Path: priv + ".pub", Data: []byte("new public")},
+	}
+	err := writeKeyFiles(files)
+	if !errors.Is(err, errFileExists) {
+		t.Fatalf("writeKeyFiles error = %v, want errFileExists", err)
+	}
+	if _, err := os.Stat(priv); err == nil {
+		t.Error("private key written although its public key exists")
+	}
+	if b, _ := os.ReadFile(priv + ".pub"); string(b) != "precious" {
+		t.Errorf("existing public key clobbered: %q", b)
+	}
+
+	flagForce = true
+	if err := writeKeyFiles(files); err != nil {
+		t.Fatalf("writeKeyFiles with --force: %v", err)
+	}
+	for path, want := range map[string]string{priv: "new private", priv + ".pub": "new public"} {
+		if b, _ := os.ReadFile(path); string(b) != want {
+			t.Errorf("%s = %q, want %q", path, b, want)
+		}
+	}
+	info, err := os.Stat(priv)
+	if err != nil {
+		t.Fatalf("private key: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("private key permissions = %o, want 0600", perm)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileAtomic_NoClobber(t *testing.T) {
+	dir := t.TempDir()
+	saveFlags(t)
+	flagForce = false
+	path := filepath.Join(dir, "key")
+
+	if err := writeFileAtomic(path, []byte("first"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	// A file that appears between writeKeyFiles' check and the write is
+	// still not replaced.
+	if err := writeFileAtomic(path, []byte("second"), 0600); !errors.Is(err, errFileExists) {
+		t.Fatalf("second writeFileAtomic error = %v, want errFileExists", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "first" {
+		t.Errorf("file = %q, want %q", b, "first")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestRun_OutDirAndName(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--out-dir", "keys/new", "--name", "vanity_{n}_{fp8}", "--jobs", "1", "."})
+	captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	matches, err := filepath.Glob(filepath.Join("keys", "new", "vanity_1_*"))
+	if err != nil || len(matches) != 2 {
+		t.Fatalf("key files = %v (%v), want a private and public key", matches, err)
+	}
+	info, err := os.Stat(filepath.Join("keys", "new"))
+	if err != nil {
+		t.Fatalf("out dir: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("out dir permissions = %o, want 0700", perm)
+	}
+}
+
+func TestRun_RefusesExistingKey(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	if err := os.WriteFile(filepath.Join(dir, "id_ed25519"), []byte("precious"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The check runs before the search, so even an unmatchable-in-practice
+	// pattern fails at once.
+	rootCmd.SetArgs([]string{"--jobs", "1", "^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI" + strings.Repeat("A", 40)})
+	err := rootCmd.Execute()
+	if !errors.Is(err, errFileExists) {
+		t.Fatalf("Execute error = %v, want errFileExists", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "id_ed25519")); string(b) != "precious" {
+		t.Errorf("existing key clobbered: %q", b)
+	}
+}
+
+// assertNoTempFiles fails if an interrupted atomic write left files behind.
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+)
+
+var (
+	flagPassphraseFile string
+	flagPassphraseEnv  string
+	flagAskPassphrase  bool
+	flagKDFRounds      int
+)
+
+// passphrase encrypts every private key written or streamed when non-nil.
+// run sets it from the passphrase flags before starting the search.
+var passphrase []byte
+
+func init() {
+	rootCmd.Flags().StringVar(&flagPassphraseFile, "passphrase-file", "", "encrypt private keys with the passphrase in this file")
+	rootCmd.Flags().StringVar(&flagPassphraseEnv, "passphrase-env", "", "encrypt private keys with the passphrase in this environment variable")
+	rootCmd.Flags().BoolVar(&flagAskPassphrase, "ask-passphrase", false, "prompt for a passphrase to encrypt private keys with")
+	rootCmd.Flags().IntVarP(&flagKDFRounds, "kdf-rounds", "a", 0, fmt.Sprintf("bcrypt KDF rounds for encrypted keys (default %d; %d with --type signify)", keyfile.DefaultRounds, keyfile.SignifyDefaultRounds))
+}
+
+// readPassphrase returns the passphrase selected by the flags, or nil when
+// keys are written unencrypted.
+func readPassphrase() ([]byte, error) {
+	sources := 0
+	for _, set := range []bool{flagPassphraseFile != "", flagPassphraseEnv != "", flagAskPassphrase} {
+		if set {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		return nil, fmt.Errorf("--passphrase-file, --passphrase-env and --ask-passphrase are mutually exclusive")
+	case flagKDFRounds < 0:
+		return nil, fmt.Errorf("--kdf-rounds must not be negative, got %d", flagKDFRounds)
+	case sources == 0 && flagKDFRounds != 0:
+		return nil, fmt.Errorf("--kdf-rounds requires a passphrase")
+	case sources == 0:
+		return nil, nil
+	}
+
+	var pass []byte
+	switch {
+	case flagPassphraseFile != "":
+		b, err := os.ReadFile(flagPassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase file: %w", err)
+		}
+		// Files written with echo or an editor end in a newline that is
+		// not part of the passphrase.
+		pass = bytes.TrimSuffix(bytes.TrimSuffix(b, []byte("\n")), []byte("\r"))
+	case flagPassphraseEnv != "":
+		v, ok := os.LookupEnv(flagPassphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", flagPassphraseEnv)
+		}
+		pass = []byte(v)
+	default:
+		var err error
+		if pass, err = promptPassphrase(); err != nil {
+			return nil, err
+		}
+	}
+	if len(pass) == 0 {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	return pass, nil
+}
+
+// promptPassphrase reads a passphrase twice from the terminal on stdin
+// without echoing it.
+func promptPassphrase() ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("--ask-passphrase needs a terminal on stdin")
+	}
+	read := func(prompt string) ([]byte, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		defer fmt.Fprintln(os.Stderr)
+		return term.ReadPassword(fd)
+	}
+	pass, err := read("Enter passphrase: ")
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	again, err := read("Enter same passphrase again: ")
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	if !bytes.Equal(pass, again) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return pass, nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestRun_Passphrase_EndToEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		args func(t *testing.T) []string
+	}{
+		{
+			name: "file",
+			args: func(t *testing.T) []string {
+				path := filepath.Join(t.TempDir(), "pass")
+				if err := os.WriteFile(path, []byte("hunter2\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				return []string{"--passphrase-file", path}
+			},
+		},
+		{
+			name: "env",
+			args: func(t *testing.T) []string {
+				t.Setenv("VANITYSSH_TEST_PASS", "hunter2")
+				return []string{"--passphrase-env", "VANITYSSH_TEST_PASS", "-a", "4"}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := chdirTemp(t)
+			saveFlags(t)
+			rootCmd.SetArgs(append(tt.args(t), "--jobs", "1", "."))
+
+			got := captureStdout(t, func() {
+				if err := rootCmd.Execute(); err != nil {
+					t.Fatalf("Execute error: %v", err)
+				}
+			})
+
+			priv, err := os.ReadFile(filepath.Join(dir, "id_ed25519"))
+			if err != nil {
+				t.Fatalf("private key file: %v", err)
+			}
+			if got != string(priv) {
+				t.Error("stdout differs from the private key file")
+			}
+			var missing *ssh.PassphraseMissingError
+			if _, err := ssh.ParseRawPrivateKey(priv); !errors.As(err, &missing) {
+				t.Fatalf("ParseRawPrivateKey error = %v, want PassphraseMissingError", err)
+			}
+			key, err := ssh.ParseRawPrivateKeyWithPassphrase(priv, []byte("hunter2"))
+			if err != nil {
+				t.Fatalf("ParseRawPrivateKeyWithPassphrase: %v", err)
+			}
+			pub, err := os.ReadFile(filepath.Join(dir, "id_ed25519.pub"))
+			if err != nil {
+				t.Fatalf("public key file: %v", err)
+			}
+			sshPub, err := ssh.NewPublicKey(key.(*ed25519.PrivateKey).Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))); string(pub) != want {
+				t.Errorf("public key = %q, want %q", pub, want)
+			}
+		})
+	}
+}
+
+func TestRun_PassphraseValidation(t *testing.T) {
+	t.Setenv("VANITYSSH_TEST_EMPTY", "")
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantSub string
+	}{
+		{name: "two sources", args: []string{"--passphrase-env", "X", "--ask-passphrase"}, wantSub: "mutually exclusive"},
+		{name: "rounds without passphrase", args: []string{"-a", "32"}, wantSub: "--kdf-rounds requires a passphrase"},
+		{name: "negative rounds", args: []string{"--passphrase-env", "X", "-a", "-1"}, wantSub: "--kdf-rounds must not be negative"},
+		{name: "unset env", args: []string{"--passphrase-env", "VANITYSSH_TEST_UNSET"}, wantSub: "is not set"},
+		{name: "empty env", args: []string{"--passphrase-env", "VANITYSSH_TEST_EMPTY"}, wantSub: "empty passphrase"},
+		{name: "empty file", args: []string{"--passphrase-file", empty}, wantSub: "empty passphrase"},
+		{name: "missing file", args: []string{"--passphrase-file", empty + ".missing"}, wantSub: "read passphrase file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			rootCmd.SetArgs(append(tt.args, "--jobs", "1", "."))
+			err := rootCmd.Execute()
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantSub) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestHandleResult_Passphrase_ContinuousMode(t *testing.T) {
+	chdirTemp(t)
+	saveFlags(t)
+	flagContinuous = true
+	passphrase = []byte("hunter2")
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := keygen.Result{PrivateKey: priv, PrivateKeyPEM: []byte("plaintext"), Fingerprint: "dGVzdA=="}
+
+	got := captureStdout(t, func() {
+		if err := handleResult(r, 1, anyKey, keygen.InputPublicKey); err != nil {
+			t.Fatalf("handleResult: %v", err)
+		}
+	})
+	if strings.Contains(got, "plaintext") {
+		t.Fatal("continuous mode streamed the unencrypted key")
+	}
+	if _, err := ssh.ParseRawPrivateKeyWithPassphrase([]byte(got), passphrase); err != nil {
+		t.Errorf("streamed key does not decrypt: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+// validTargetName restricts target names to characters that are safe in
+// output filenames.
+var validTargetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// readTargets parses a multi-target patterns file. Each non-blank line that
+// does not start with '#' has the form name=regex; the name becomes the
+// output filename prefix.
+func readTargets(path string) ([]keygen.Target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open patterns file: %w", err)
+	}
+	defer f.Close()
+
+	var targets []keygen.Target
+	seen := make(map[string]int)
+	sc := bufio.NewScanner(f)
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: want name=regex, got %q", path, lineNum, line)
+		}
+		name = strings.TrimSpace(name)
+		if !validTargetName.MatchString(name) {
+			return nil, fmt.Errorf("%s:%d: invalid target name %q (letters, digits, '.', '_' and '-' only)", path, lineNum, name)
+		}
+		if prev, dup := seen[name]; dup {
+			return nil, fmt.Errorf("%s:%d: duplicate target name %q (first defined on line %d)", path, lineNum, name, prev)
+		}
+		seen[name] = lineNum
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid regex: %w", path, lineNum, err)
+		}
+		targets = append(targets, keygen.Target{Name: name, Regex: re})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read patterns file: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no patterns found", path)
+	}
+	return targets, nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePatterns(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write patterns: %v", err)
+	}
+	return path
+}
+
+func TestReadTargets(t *testing.T) {
+	t.Parallel()
+
+	path := writePatterns(t, "# comment\n\n alice = (?i)alice$\nbob=^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIbob\nweb-01.prod=a=b\n")
+	targets, err := readTargets(path)
+	if err != nil {
+		t.Fatalf("readTargets: %v", err)
+	}
+
+	want := []struct{ name, pattern string }{
+		{"alice", " (?i)alice$"},
+		{"bob", "^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIbob"},
+		{"web-01.prod", "a=b"},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets, want %d", len(targets), len(want))
+	}
+	for i, w := range want {
+		if targets[i].Name != w.name || targets[i].Regex.String() != w.pattern {
+			t.Errorf("target %d = %s=%s, want %s=%s", i, targets[i].Name, targets[i].Regex, w.name, w.pattern)
+		}
+	}
+}
+
+func TestReadTargets_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantSub string
+	}{
+		{name: "missing separator", content: "alice\n", wantSub: ":1: want name=regex"},
+		{name: "bad name", content: "../x=abc\n", wantSub: "invalid target name"},
+		{name: "empty name", content: "=abc\n", wantSub: "invalid target name"},
+		{name: "duplicate", content: "a=x\nb=y\na=z\n", wantSub: ":3: duplicate target name \"a\" (first defined on line 1)"},
+		{name: "bad regex", content: "a=[\n", wantSub: ":1: invalid regex"},
+		{name: "empty file", content: "# nothing\n", wantSub: "no patterns found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := readTargets(writePatterns(t, tt.content))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantSub) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantSub)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/danielewood/vanityssh-go/display"
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+// progress tracks the odds of a running search for the status bar.
+type progress struct {
+	prob       func() float64 // per-key probability of the next match; 0 if unknown
+	continuous bool
+	lastMatch  atomic.Int64 // key count at the most recent match
+}
+
+// newProgress returns a progress for a single pattern.
+func newProgress(re *regexp.Regexp, opts keygen.Options, in keygen.Input, continuous bool) *progress {
+	p, err := keygen.MatchProbability(re, opts, in)
+	if err != nil {
+		p = 0
+	}
+	return &progress{prob: func() float64 { return p }, continuous: continuous}
+}
+
+// newTargetsProgress returns a progress for a multi-target search, where the
+// next match is a hit on any pending target.
+func newTargetsProgress(targets *keygen.TargetSet, list []keygen.Target, opts keygen.Options, in keygen.Input) *progress {
+	probs := make(map[string]float64, len(list))
+	for _, tg := range list {
+		p, err := keygen.MatchProbability(tg.Regex, opts, in)
+		if err != nil {
+			return &progress{prob: func() float64 { return 0 }}
+		}
+		probs[tg.Name] = p
+	}
+	return &progress{prob: func() float64 {
+		miss := 1.0
+		for _, tg := range targets.Pending() {
+			miss *= 1 - probs[tg.Name]
+		}
+		return 1 - miss
+	}}
+}
+
+// matched records that a match was reported after keys keys.
+func (pr *progress) matched(keys int64) { pr.lastMatch.Store(keys) }
+
+// odds is the outlook of a running search at one moment.
+type odds struct {
+	// ETA is the mean number of seconds to the next match, or 0 before the
+	// key rate is known.
+	ETA float64
+	// Chance is the probability that a match should have occurred since
+	// the last one (single-match and multi-target mode).
+	Chance float64
+	// Expected is the expected number of matches so far (continuous mode).
+	Expected float64
+	// Luck summarizes Chance, or compares Matches with Expected.
+	Luck string
+}
+
+// odds returns the outlook for st, or false when the match probability is
+// unknown. Key generation is memoryless, so the ETA is the mean time to the
+// next match from now however long the search has run.
+func (pr *progress) odds(st keygen.Stats) (odds, bool) {
+	p := pr.prob()
+	if p <= 0 {
+		return odds{}, false
+	}
+	var o odds
+	if rate := float64(st.Keys) / st.Elapsed.Seconds(); rate > 0 {
+		o.ETA = 1 / p / rate
+	}
+	if pr.continuous {
+		o.Expected = p * float64(st.Keys)
+		o.Luck = luckRatio(st.Matches, o.Expected)
+	} else {
+		o.Chance = keygen.CumulativeProbability(p, st.Keys-pr.lastMatch.Load())
+		o.Luck = luckLabel(o.Chance)
+	}
+	return o, true
+}
+
+// status returns the status bar fields for st, or "" when the match
+// probability is unknown. In single-match and multi-target mode, Chance is
+// the probability that a match should have occurred since the last one; in
+// continuous mode the match count is compared with the expected count
+// instead.
+func (pr *progress) status(st keygen.Stats) string {
+	o, ok := pr.odds(st)
+	if !ok {
+		return ""
+	}
+	eta := "-"
+	if o.ETA > 0 {
+		eta = display.FormatSeconds(o.ETA)
+	}
+	if pr.continuous {
+		return fmt.Sprintf("ETA: %s | Expected: %.1f | Luck: %s", eta, o.Expected, o.Luck)
+	}
+	return fmt.Sprintf("ETA: %s | Chance: %.0f%% | Luck: %s", eta, o.Chance*100, o.Luck)
+}
+
+// luckLabel describes a search that has not matched yet, given the chance
+// that it should have.
+func luckLabel(chance float64) string {
+	switch {
+	case chance < 0.5:
+		return "on track"
+	case chance < 0.9:
+		return "behind"
+	case chance < 0.99:
+		return "unlucky"
+	default:
+		return "very unlucky"
+	}
+}
+
+// luckRatio compares actual with expected matches. Below one expected match
+// the ratio says nothing yet.
+func luckRatio(actual int64, expected float64) string {
+	if expected < 1 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fx", float64(actual)/expected)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+func TestProgress_Status(t *testing.T) {
+	// One in 16 fingerprints ends in "Q=".
+	re := regexp.MustCompile(`Q=$`)
+
+	tests := []struct {
+		name       string
+		continuous bool
+		stats      keygen.Stats
+		lastMatch  int64
+		want       string
+	}{
+		{
+			name:  "no keys yet",
+			stats: keygen.Stats{Elapsed: time.Second},
+			want:  "ETA: - | Chance: 0% | Luck: on track",
+		},
+		{
+			name:  "on track",
+			stats: keygen.Stats{Keys: 8, Elapsed: time.Second},
+			want:  "ETA: 2s | Chance: 40% | Luck: on track",
+		},
+		{
+			name:  "unlucky",
+			stats: keygen.Stats{Keys: 64, Elapsed: 8 * time.Second},
+			want:  "ETA: 2s | Chance: 98% | Luck: unlucky",
+		},
+		{
+			name:      "since last match",
+			stats:     keygen.Stats{Keys: 72, Elapsed: 9 * time.Second},
+			lastMatch: 64,
+			want:      "ETA: 2s | Chance: 40% | Luck: on track",
+		},
+		{
+			name:       "continuous",
+			continuous: true,
+			stats:      keygen.Stats{Keys: 320, Matches: 30, Elapsed: 40 * time.Second},
+			want:       "ETA: 2s | Expected: 20.0 | Luck: 1.50x",
+		},
+		{
+			name:       "continuous below one expected",
+			continuous: true,
+			stats:      keygen.Stats{Keys: 8, Elapsed: time.Second},
+			want:       "ETA: 2s | Expected: 0.5 | Luck: -",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := newProgress(re, keygen.Options{}, keygen.InputFingerprint, tt.continuous)
+			pr.matched(tt.lastMatch)
+			if got := pr.status(tt.stats); got != tt.want {
+				t.Errorf("status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgress_UnknownProbability(t *testing.T) {
+	// Too many automaton states to estimate: no odds in the status bar.
+	pr := newProgress(regexp.MustCompile(`A.{20}B`), keygen.Options{}, keygen.InputPublicKey, false)
+	if got := pr.status(keygen.Stats{Keys: 100, Elapsed: time.Second}); got != "" {
+		t.Errorf("status = %q, want empty", got)
+	}
+}
+
+func TestProgress_RSAModulus(t *testing.T) {
+	// n is odd, so half of 4096-bit RSA keys end in "Q==" and none in "A==".
+	opts := keygen.Options{Type: keygen.KeyTypeRSA, Bits: 4096}
+	pr := newProgress(regexp.MustCompile(`Q==$`), opts, keygen.InputPublicKey, false)
+	st := keygen.Stats{Keys: 1, Elapsed: time.Second}
+	if got, want := pr.status(st), "ETA: 2s | Chance: 50% | Luck: behind"; got != want {
+		t.Errorf("status = %q, want %q", got, want)
+	}
+	pr = newProgress(regexp.MustCompile(`A==$`), opts, keygen.InputPublicKey, false)
+	if got := pr.status(st); got != "" {
+		t.Errorf("impossible pattern: status = %q, want empty", got)
+	}
+}
+
+func TestProgress_Targets(t *testing.T) {
+	list := []keygen.Target{
+		{Name: "q", Regex: regexp.MustCompile(`Q=$`)},
+		{Name: "a", Regex: regexp.MustCompile(`A=$`)},
+	}
+	targets, err := keygen.NewTargetSet(list, keygen.InputFingerprint)
+	if err != nil {
+		t.Fatalf("NewTargetSet: %v", err)
+	}
+	pr := newTargetsProgress(targets, list, keygen.Options{}, keygen.InputFingerprint)
+
+	// Either of two 1-in-16 endings: 31 in 256, at one key per second.
+	st := keygen.Stats{Keys: 31, Elapsed: 31 * time.Second}
+	if got := pr.status(st); !strings.HasPrefix(got, "ETA: 8s |") {
+		t.Errorf("status = %q, want ETA 8s for both targets", got)
+	}
+	targets.Claim([]byte("xxA="))
+	if got := pr.status(st); !strings.HasPrefix(got, "ETA: 16s |") {
+		t.Errorf("status = %q, want ETA 16s with one target left", got)
+	}
+}
+
+func TestLuckLabel(t *testing.T) {
+	tests := []struct {
+		chance float64
+		want   string
+	}{
+		{0, "on track"},
+		{0.49, "on track"},
+		{0.5, "behind"},
+		{0.95, "unlucky"},
+		{0.999, "very unlucky"},
+	}
+	for _, tt := range tests {
+		if got := luckLabel(tt.chance); got != tt.want {
+			t.Errorf("luckLabel(%g) = %q, want %q", tt.chance, got, tt.want)
+		}
+	}
+}
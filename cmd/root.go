@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -20,28 +21,106 @@ import (
 
 var (
 	flagFingerprint bool
+	flagSSHFP       bool
 	flagContinuous  bool
 	flagJobs        int
+	flagPatterns    string
+	flagType        string
+	flagBits        int
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "vanityssh <regex>",
-	Short: "Generate ED25519 SSH keys with vanity public keys",
-	Long: `vanityssh generates ED25519 SSH key pairs at high speed and matches
-the resulting public keys (or SHA256 fingerprints) against a regex pattern.
+	Short: "Generate SSH keys with vanity public keys",
+	Long: `vanityssh generates SSH key pairs (ED25519 by default) at high speed and
+matches the resulting public keys (or SHA256 fingerprints) against a regex
+pattern.
 
-On first match, the key pair is written to id_ed25519 and id_ed25519.pub
-in the current directory. Use --continuous to keep finding keys.
+On first match, the key pair is written to id_<type> and id_<type>.pub
+(id_ed25519, id_rsa, id_ecdsa) in the current directory. Use --continuous to keep
+finding keys; each match is written to its own numbered pair, id_<type>_<n>.
+--out-dir and --name choose where keys go. Existing key files are never
+overwritten without --force. When piping, only the private key is written
+to stdout.
 
-When piping, only the private key is written to stdout.`,
-	Args: cobra.ExactArgs(1),
+--output json writes one JSON object per match to stdout instead, and
+--progress json writes periodic progress records to stderr in place of the
+status bar.
+
+With --patterns, every generated key is tested against all outstanding
+targets from a file of name=regex lines; each match is written to
+<name>_<type> and <name>_<type>.pub for the first target it satisfies, so
+no two targets share a key, and the run ends once every target has been
+found.
+
+RSA keys (--type rsa) are far slower to generate. Each worker pairs every new
+prime with the primes it already holds, so the rate starts low and climbs as
+the pool grows; expect tens to thousands of keys/s per core rather than
+ED25519's tens of thousands.
+
+With --passphrase-file, --passphrase-env or --ask-passphrase, every private
+key written or streamed is encrypted the way ssh-keygen does it (aes256-ctr
+with a bcrypt KDF of --kdf-rounds rounds); no plaintext key leaves the process.
+
+--format chooses the key file formats, and may be repeated: openssh (the
+default), ppk (PuTTY, <name>.ppk), pkcs8 (<name>.pem and <name>.pub.pem) and
+jwk (<name>.jwk and the public key set <name>.jwks). The OpenSSH public key
+line is always written to <name>.pub.
+
+--add-to-agent also loads every key found into the ssh-agent at
+$SSH_AUTH_SOCK, optionally with --lifetime and --confirm constraints. With
+--agent-only the private key goes only to the agent; should the agent refuse
+it, it is written to disk after all and the run fails saying so.
+
+--ca-key signs every key found into an OpenSSH user certificate,
+<name>-cert.pub, with --principals, --valid-for, --key-id, --critical-option
+and --extension as in ssh-keygen -s. --key-id-pattern keeps searching until
+the certificate key ID also matches, so --key-id must use {fp}, the key's
+fingerprint (the certificate's own cannot be matched), or {match}.
+
+--host generates host keys: they are written to ssh_host_<type>_key, and the
+SSHFP DNS records and known_hosts line for the --hostname names (hashed with
+--hash-known-hosts) are printed with them. --ca-key then signs host
+certificates. --sshfp matches the SHA-256 digest of the SSHFP record, in
+lowercase hex, that DNSSEC-aware clients verify.
+
+--git-signing --email writes <name>.allowed_signers for signing git commits
+with the key and prints the git config commands that use it, once the key has
+signed and verified an SSH signature in git's namespace.
+
+--type also searches keys that are not SSH keys, each written to the files
+its own tools use (see the README):
+
+  onion      Tor v3 onion service addresses, as a HiddenServiceDir
+  wireguard  WireGuard public keys; --wg-config adds config snippets
+  age        age recipients (age1...)
+  openpgp    OpenPGP fingerprints, whose key IDs end them ('DEADBEEF$')
+  minisign   minisign public keys, or key IDs with --fingerprint
+  signify    signify public keys, or key IDs with --fingerprint
+  libp2p     libp2p peer IDs (12D3KooW...), or CIDs with --fingerprint`,
+	Args: validateArgs,
 	RunE: run,
 }
 
 func init() {
-	rootCmd.Flags().BoolVarP(&flagFingerprint, "fingerprint", "f", false, "match against SHA256 fingerprint instead of public key")
+	// Flags that describe the search itself are persistent so subcommands
+	// such as estimate see exactly what a real run would do.
+	rootCmd.PersistentFlags().BoolVarP(&flagFingerprint, "fingerprint", "f", false, "match against SHA256 fingerprint instead of public key")
+	rootCmd.PersistentFlags().BoolVar(&flagSSHFP, "sshfp", false, "match against the SSHFP SHA-256 digest (lowercase hex) instead of public key")
+	rootCmd.PersistentFlags().IntVarP(&flagJobs, "jobs", "j", 0, "number of parallel workers (default: number of CPUs)")
+	rootCmd.PersistentFlags().StringVarP(&flagType, "type", "t", string(keygen.KeyTypeED25519), "key type: ed25519, rsa, ecdsa-p256, ecdsa-p384, ecdsa-p521, onion, wireguard, age, openpgp, minisign, signify or libp2p")
+	rootCmd.PersistentFlags().IntVarP(&flagBits, "bits", "b", 0, "RSA modulus size in bits (default 3072)")
 	rootCmd.Flags().BoolVarP(&flagContinuous, "continuous", "c", false, "keep finding keys after a match")
-	rootCmd.Flags().IntVarP(&flagJobs, "jobs", "j", 0, "number of parallel workers (default: number of CPUs)")
+	rootCmd.Flags().StringVar(&flagPatterns, "patterns", "", "file of name=regex targets to search for in one run")
+}
+
+// validateArgs requires exactly one regex argument, or none when the
+// targets come from --patterns.
+func validateArgs(cmd *cobra.Command, args []string) error {
+	if flagPatterns != "" {
+		return cobra.NoArgs(cmd, args)
+	}
+	return cobra.ExactArgs(1)(cmd, args)
 }
 
 // SetVersion sets the version string for the root command.
@@ -54,16 +133,152 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// searchMode returns the key type options and matcher input selected by the
+// persistent flags. The returned Options has no Matcher.
+func searchMode() (keygen.Options, keygen.Input, error) {
+	keyType, err := keygen.ParseKeyType(flagType)
+	if err != nil {
+		return keygen.Options{}, 0, err
+	}
+	if flagBits != 0 && keyType != keygen.KeyTypeRSA {
+		return keygen.Options{}, 0, fmt.Errorf("--bits only applies to --type rsa")
+	}
+	input := keygen.InputPublicKey
+	switch {
+	case flagFingerprint && flagSSHFP:
+		return keygen.Options{}, 0, fmt.Errorf("--fingerprint and --sshfp cannot be used together")
+	case flagFingerprint:
+		input = keygen.InputFingerprint
+	case flagSSHFP:
+		input = keygen.InputSSHFP
+	}
+	return keygen.Options{Type: keyType, Bits: flagBits}, input, nil
+}
+
+// workerCount returns the number of workers --jobs selects.
+func workerCount() (int, error) {
+	if flagJobs < 0 {
+		return 0, fmt.Errorf("--jobs must be non-negative, got %d", flagJobs)
+	}
+	if flagJobs == 0 {
+		return runtime.NumCPU(), nil
+	}
+	return flagJobs, nil
+}
+
 func run(_ *cobra.Command, args []string) error {
-	re, err := regexp.Compile(args[0])
+	typeOpts, input, err := searchMode()
 	if err != nil {
-		return fmt.Errorf("invalid regex: %w", err)
+		return err
+	}
+	keyType := typeOpts.Type
+	if err := checkKeyOutput(keyType); err != nil {
+		return err
+	}
+	if err := checkComment(flagComment); err != nil {
+		return err
+	}
+	if err := checkOutputModes(); err != nil {
+		return err
+	}
+	if err := checkFormat(); err != nil {
+		return err
+	}
+	if err := checkAgent(); err != nil {
+		return err
+	}
+	if err := checkCert(); err != nil {
+		return err
+	}
+	if err := checkHost(); err != nil {
+		return err
+	}
+	if err := checkGit(); err != nil {
+		return err
+	}
+	if err := checkWireGuard(); err != nil {
+		return err
+	}
+	if err := checkOpenPGP(); err != nil {
+		return err
+	}
+
+	// Reject patterns no key of this type can match before starting workers
+	// that would never finish.
+	var matcher keygen.Matcher
+	var targets *keygen.TargetSet
+	var prog *progress
+	var re *regexp.Regexp
+	var list []keygen.Target
+	if flagPatterns != "" {
+		if flagContinuous {
+			return fmt.Errorf("--continuous cannot be used with --patterns")
+		}
+		list, err = readTargets(flagPatterns)
+		if err != nil {
+			return err
+		}
+		for _, tg := range list {
+			if err := keygen.CheckFeasible(tg.Regex, typeOpts, input); err != nil {
+				return fmt.Errorf("target %q: %w", tg.Name, err)
+			}
+		}
+		targets, err = keygen.NewTargetSet(list, input)
+		if err != nil {
+			return err
+		}
+		matcher = targets
+		prog = newTargetsProgress(targets, list, typeOpts, input)
+	} else {
+		re, err = regexp.Compile(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		if err := keygen.CheckFeasible(re, typeOpts, input); err != nil {
+			return err
+		}
+		matcher, err = keygen.NewMatcher(re, input)
+		if err != nil {
+			return err
+		}
+		if keyIDRegexp != nil {
+			matcher = &keyIDMatcher{Matcher: matcher, re: re, id: keyIDRegexp}
+		}
+		prog = newProgress(re, typeOpts, input, flagContinuous)
+	}
+	if err := checkName(nameTemplate()); err != nil {
+		return err
+	}
+	if err := checkOutputFree(list); err != nil {
+		return err
 	}
 
-	display.Init()
-	defer display.Reset()
+	workers, err := workerCount()
+	if err != nil {
+		return err
+	}
+	opts := typeOpts
+	opts.Matcher = matcher
+	searcher, err := keygen.NewSearcher(opts, workers)
+	if err != nil {
+		return err
+	}
+	// Prompt before the status bar takes over the terminal.
+	if passphrase, err = readPassphrase(); err != nil {
+		return err
+	}
+	caSigner = nil
+	if flagCAKey != "" {
+		if caSigner, err = loadCA(); err != nil {
+			return err
+		}
+	}
 
-	startTime := time.Now()
+	// JSON progress records replace the status bar on stderr.
+	if flagProgress == "bar" {
+		display.Init()
+		defer display.Reset()
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -79,49 +294,54 @@ func run(_ *cobra.Command, args []string) error {
 		}
 	}()
 
-	numJobs := flagJobs
-	if numJobs < 0 {
-		return fmt.Errorf("--jobs must be non-negative, got %d", numJobs)
+	if err := searcher.Start(ctx); err != nil {
+		return err
 	}
-	if numJobs == 0 {
-		numJobs = runtime.NumCPU()
-	}
-
-	opts := keygen.Options{
-		Regex:       re,
-		Fingerprint: flagFingerprint,
-	}
-
-	results := make(chan keygen.Result, numJobs)
 	g, gctx := errgroup.WithContext(ctx)
 
-	// Launch workers
-	for i := 0; i < numJobs; i++ {
-		g.Go(func() error {
-			return keygen.FindKeys(gctx, opts, results)
-		})
-	}
-
-	// Result consumer
+	// Result consumer. The results channel closes when the searcher stops,
+	// including when a worker fails; cancel so the status bar exits too.
 	g.Go(func() error {
+		defer cancel()
 		var matchNum int
-		for {
-			select {
-			case r := <-results:
-				matchNum++
-				if err := handleResult(r, matchNum); err != nil {
+		for r := range searcher.Results() {
+			prog.matched(searcher.KeyCount())
+			if targets != nil {
+				done, err := handleTargets(targets, list, r, input)
+				if err != nil || done {
 					return err
 				}
-				if !flagContinuous {
-					cancel()
-					return nil
-				}
-			case <-gctx.Done():
+				continue
+			}
+			matchNum++
+			if err := handleResult(r, matchNum, re, input); err != nil {
+				return err
+			}
+			if !flagContinuous {
 				return nil
 			}
 		}
+		return nil
 	})
 
+	// JSON progress records, ending with one for the final totals.
+	if flagProgress == "json" {
+		g.Go(func() error {
+			ticker := time.NewTicker(progressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+				case <-gctx.Done():
+					return writeJSON(os.Stderr, newProgressRecord(searcher.Stats(), keyType, matcher, prog, targets))
+				}
+				if err := writeJSON(os.Stderr, newProgressRecord(searcher.Stats(), keyType, matcher, prog, targets)); err != nil {
+					return err
+				}
+			}
+		})
+	}
+
 	// Status bar updater
 	g.Go(func() error {
 		ticker := time.NewTicker(250 * time.Millisecond)
@@ -129,15 +349,20 @@ func run(_ *cobra.Command, args []string) error {
 		for {
 			select {
 			case <-ticker.C:
-				if display.IsTTY() {
-					count := keygen.KeyCount()
-					elapsed := time.Since(startTime)
-					rate := int64(float64(count) / elapsed.Seconds())
-					matches := keygen.MatchCount()
-
-					status := fmt.Sprintf("Keys: %s | Rate: %s/s | Matches: %d | Elapsed: %s | Ctrl+C to exit",
-						display.FormatCount(count), display.FormatCount(rate), matches,
-						elapsed.Truncate(time.Second))
+				if display.IsTTY() && flagProgress == "bar" {
+					st := searcher.Stats()
+					rate := int64(float64(st.Keys) / st.Elapsed.Seconds())
+
+					status := fmt.Sprintf("Type: %s | Keys: %s | Rate: %s/s | Matches: %d | Elapsed: %s",
+						keyLabel(keyType), display.FormatCount(st.Keys), display.FormatCount(rate), st.Matches,
+						st.Elapsed.Truncate(time.Second))
+					if odds := prog.status(st); odds != "" {
+						status += " | " + odds
+					}
+					status += fmt.Sprintf(" | Engine: %v | Ctrl+C to exit", matcher)
+					if targets != nil {
+						status = fmt.Sprintf("Targets: %d/%d | %s", targets.Len()-targets.Remaining(), targets.Len(), status)
+					}
 					display.UpdateStatusBar(status)
 				}
 			case <-gctx.Done():
@@ -146,39 +371,165 @@ func run(_ *cobra.Command, args []string) error {
 		}
 	})
 
-	return g.Wait()
+	err = g.Wait()
+	if serr := searcher.Stop(); err == nil {
+		err = serr
+	}
+	return err
 }
 
-func handleResult(r keygen.Result, matchNum int) error {
+// handleResult reports and writes the matchNum-th key, found by re against
+// its input text.
+func handleResult(r keygen.Result, matchNum int, re *regexp.Regexp, input keygen.Input) error {
+	r, err := commentResult(r, re, input)
+	if err != nil {
+		return err
+	}
+	files, err := keyFiles(r, keyPath(r, matchNum, ""), keyID(r, re, input))
+	if err != nil {
+		return err
+	}
+	notes, err := hostLines(r)
+	if err != nil {
+		return err
+	}
+	// Report the match even if saving it failed; the error says what
+	// became of the key.
+	files, saveErr := saveKey(r, files)
+	notes = append(append(notes, gitConfig(files)...), wireguardPeer(files)...)
+	if flagOutput == "json" {
+		if !flagContinuous {
+			display.Reset()
+		}
+		rec, err := newMatchRecord(r, matchNum, "", re, input, files)
+		if err != nil {
+			return err
+		}
+		rec.Agent = flagAddToAgent && saveErr == nil
+		if err := writeJSON(os.Stdout, rec); err != nil {
+			return err
+		}
+		return saveErr
+	}
+	out := stdoutKey(r, files)
 	if flagContinuous {
-		// Continuous mode: show match in scroll region (stderr) + stream PEM to stdout.
+		// Continuous mode: show match in scroll region (stderr), stream PEM
+		// to stdout and keep each match in its own key files.
 		if display.IsTTY() {
-			display.PrintAboveStatus("--- Match #%d ---", matchNum)
-			for line := range strings.SplitSeq(strings.TrimSpace(string(r.PrivateKeyPEM)), "\n") {
+			display.PrintAboveStatus("--- Match #%d: %s ---", matchNum, files[0].Path)
+			if files[0].Secret && !files[0].Binary {
+				for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+					display.PrintAboveStatus("%s", line)
+				}
+			}
+			for _, line := range append(publicLines(r, files), notes...) {
 				display.PrintAboveStatus("%s", line)
 			}
-			display.PrintAboveStatus("%s", r.AuthorizedKey)
-			display.PrintAboveStatus("SHA256:%s", r.Fingerprint)
+		} else {
+			reportNotes(notes)
 		}
-		fmt.Printf("%s", r.PrivateKeyPEM)
-		return nil
+		fmt.Printf("%s", out)
+		return saveErr
 	}
 
-	// Single-match mode: tear down scroll region, print final output, write files.
+	// Single-match mode: tear down scroll region, print final output.
 	if display.IsTTY() {
 		display.Reset()
-		fmt.Printf("%s", r.PrivateKeyPEM)
-		fmt.Printf("%s\n", r.AuthorizedKey)
-		fmt.Printf("SHA256:%s\n", r.Fingerprint)
+		if files[0].Secret && !files[0].Binary {
+			fmt.Printf("%s", out)
+		}
+		for _, line := range append(publicLines(r, files), notes...) {
+			fmt.Println(line)
+		}
 	} else {
-		fmt.Printf("%s", r.PrivateKeyPEM)
+		reportNotes(notes)
+		fmt.Printf("%s", out)
 	}
-	if err := os.WriteFile("id_ed25519", r.PrivateKeyPEM, 0600); err != nil {
-		return fmt.Errorf("write private key: %w", err)
+	return saveErr
+}
+
+// handleTargets claims the first outstanding target that r satisfies and
+// writes the key files for it, named after the target; a key is never
+// written for more than one target. It reports whether all targets have now
+// been found.
+func handleTargets(targets *keygen.TargetSet, list []keygen.Target, r keygen.Result, input keygen.Input) (bool, error) {
+	name, ok := targets.Claim([]byte(r.Text(input)))
+	if !ok {
+		return targets.Remaining() == 0, nil
 	}
-	if err := os.WriteFile("id_ed25519.pub", []byte(r.AuthorizedKey), 0644); err != nil {
-		return fmt.Errorf("write public key: %w", err)
+	i := slices.IndexFunc(list, func(tg keygen.Target) bool { return tg.Name == name })
+	r, err := commentResult(r, list[i].Regex, input)
+	if err != nil {
+		return false, err
+	}
+	files, err := keyFiles(r, keyPath(r, i+1, name), keyID(r, list[i].Regex, input))
+	if err != nil {
+		return false, err
+	}
+	notes, err := hostLines(r)
+	if err != nil {
+		return false, err
+	}
+	files, saveErr := saveKey(r, files)
+	notes = append(append(notes, gitConfig(files)...), wireguardPeer(files)...)
+	if flagOutput == "json" {
+		rec, err := newMatchRecord(r, i+1, name, list[i].Regex, input, files)
+		if err != nil {
+			return false, err
+		}
+		rec.Agent = flagAddToAgent && saveErr == nil
+		if err := writeJSON(os.Stdout, rec); err != nil {
+			return false, err
+		}
 	}
+	// With --progress json, stderr carries only JSON records.
+	if flagProgress != "json" {
+		public := publicLines(r, files)
+		display.PrintAboveStatus("Found %s: %s", name, public[0])
+		for _, line := range public[1:] {
+			display.PrintAboveStatus("  %s", line)
+		}
+		display.PrintAboveStatus("  Saved to %s", files[0].Path)
+		for _, line := range notes {
+			display.PrintAboveStatus("  %s", line)
+		}
+	}
+	if saveErr != nil {
+		return false, fmt.Errorf("%s: %w", name, saveErr)
+	}
+	return targets.Remaining() == 0, nil
+}
+
+// reportNotes prints the lines that go with a match, such as SSHFP records,
+// git config commands or a WireGuard [Peer] section, on stderr when stdout
+// carries only the private key.
+func reportNotes(notes []string) {
+	// With --progress json, stderr carries only JSON records.
+	if flagProgress == "json" {
+		return
+	}
+	for _, line := range notes {
+		display.PrintAboveStatus("%s", line)
+	}
+}
 
-	return nil
+// keyFileSuffix returns the key type part of output file names, following
+// ssh-keygen: id_ed25519, id_rsa, id_ecdsa.
+func keyFileSuffix() string {
+	if strings.HasPrefix(flagType, "ecdsa-") {
+		return "ecdsa"
+	}
+	return flagType
+}
+
+// keyLabel describes the key type for the status bar.
+func keyLabel(kt keygen.KeyType) string {
+	if kt == keygen.KeyTypeRSA {
+		bits := flagBits
+		if bits == 0 {
+			bits = keygen.DefaultRSABits
+		}
+		return fmt.Sprintf("rsa-%d", bits)
+	}
+	return string(kt)
 }
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/danielewood/vanityssh-go/keygen"
+)
+
+var (
+	flagWGConfig   bool
+	flagWGAddress  string
+	flagWGEndpoint string
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&flagWGConfig, "wg-config", false, "with --type wireguard, also write interface.conf and peer.conf snippets and print the [Peer] one")
+	rootCmd.Flags().StringVar(&flagWGAddress, "wg-address", "", "with --wg-config, comma-separated interface addresses, e.g. 10.0.0.2/24; peers route their host addresses to the key")
+	rootCmd.Flags().StringVar(&flagWGEndpoint, "wg-endpoint", "", "with --wg-config, the host:port peers reach the interface at; also sets its ListenPort")
+
+	keyOutputs[keygen.KeyTypeWireGuard] = keyOutput{
+		name:     "wireguard",
+		suffixes: wireguardSuffixes,
+		files:    wireguardFiles,
+	}
+}
+
+// The files of a WireGuard key, named as in wg(8)'s examples, and of the
+// --wg-config snippets.
+const (
+	wgPrivateKeyFile = "privatekey"
+	wgPublicKeyFile  = "publickey"
+	wgInterfaceFile  = "interface.conf"
+	wgPeerFile       = "peer.conf"
+)
+
+// checkWireGuard validates the WireGuard snippet flags.
+func checkWireGuard() error {
+	if keygen.KeyType(flagType) != keygen.KeyTypeWireGuard {
+		if flagWGConfig || flagWGAddress != "" || flagWGEndpoint != "" {
+			return fmt.Errorf("--wg-config, --wg-address and --wg-endpoint require --type wireguard")
+		}
+		return nil
+	}
+	if !flagWGConfig && (flagWGAddress != "" || flagWGEndpoint != "") {
+		return fmt.Errorf("--wg-address and --wg-endpoint require --wg-config")
+	}
+	if _, err := wgAddresses(); err != nil {
+		return err
+	}
+	if flagWGEndpoint != "" {
+		host, port, err := net.SplitHostPort(flagWGEndpoint)
+		if err != nil || host == "" {
+			return fmt.Errorf("--wg-endpoint must be host:port, got %q", flagWGEndpoint)
+		}
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			return fmt.Errorf("--wg-endpoint port must be 1-65535, got %q", port)
+		}
+	}
+	return nil
+}
+
+// wgAddresses parses the --wg-address prefixes.
+func wgAddresses() ([]netip.Prefix, error) {
+	if flagWGAddress == "" {
+		return nil, nil
+	}
+	var prefixes []netip.Prefix
+	for s := range strings.SplitSeq(flagWGAddress, ",") {
+		p, err := netip.ParsePrefix(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("--wg-address %q is not an address/prefix length", strings.TrimSpace(s))
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
+
+// wireguardSuffixes returns the suffixes of the files wireguardFiles writes.
+func wireguardSuffixes() []string {
+	if flagWGConfig {
+		return dirSuffixes(wgPrivateKeyFile, wgInterfaceFile, wgPeerFile, wgPublicKeyFile)
+	}
+	return dirSuffixes(wgPrivateKeyFile, wgPublicKeyFile)
+}
+
+// wireguardFiles returns the key files for r in the directory base, as
+// wg genkey and wg pubkey write them, with the --wg-config snippets
+// between the private and public key.
+func wireguardFiles(r keygen.Result, base string) ([]keyFile, error) {
+	priv, ok := r.PrivateKey.(*ecdh.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("wireguard key is %T, not x25519", r.PrivateKey)
+	}
+	privText := base64.StdEncoding.EncodeToString(priv.Bytes())
+	files := []keyFile{{Format: "wireguard", Path: filepath.Join(base, wgPrivateKeyFile), Data: []byte(privText + "\n"), Secret: true}}
+	if flagWGConfig {
+		iface, peer, err := wgSnippets(privText, r.AuthorizedKey)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			keyFile{Format: "wireguard-config", Path: filepath.Join(base, wgInterfaceFile), Data: []byte(iface), Secret: true},
+			keyFile{Format: "wireguard-config", Path: filepath.Join(base, wgPeerFile), Data: []byte(peer)})
+	}
+	return append(files, keyFile{Format: "wireguard", Path: filepath.Join(base, wgPublicKeyFile), Data: []byte(r.AuthorizedKey + "\n")}), nil
+}
+
+// wgSnippets returns the [Interface] section for the host holding the key
+// and the [Peer] section its peers add, with the lines the --wg-address and
+// --wg-endpoint flags fill in.
+func wgSnippets(privText, pubText string) (iface, peer string, err error) {
+	addrs, err := wgAddresses()
+	if err != nil {
+		return "", "", err
+	}
+	var i, p strings.Builder
+	i.WriteString("[Interface]\nPrivateKey = " + privText + "\n")
+	p.WriteString("[Peer]\nPublicKey = " + pubText + "\n")
+	if len(addrs) > 0 {
+		var ifaceAddrs, allowed []string
+		for _, a := range addrs {
+			ifaceAddrs = append(ifaceAddrs, a.String())
+			allowed = append(allowed, netip.PrefixFrom(a.Addr(), a.Addr().BitLen()).String())
+		}
+		i.WriteString("Address = " + strings.Join(ifaceAddrs, ", ") + "\n")
+		p.WriteString("AllowedIPs = " + strings.Join(allowed, ", ") + "\n")
+	}
+	if flagWGEndpoint != "" {
+		_, port, _ := net.SplitHostPort(flagWGEndpoint)
+		i.WriteString("ListenPort = " + port + "\n")
+		p.WriteString("Endpoint = " + flagWGEndpoint + "\n")
+	}
+	return i.String(), p.String(), nil
+}
+
+// wireguardPeer returns the lines of the [Peer] snippet among files, which
+// are printed with the match.
+func wireguardPeer(files []keyFile) []string {
+	for _, f := range files {
+		if f.Format == "wireguard-config" && filepath.Base(f.Path) == wgPeerFile {
+			return strings.Split(strings.TrimSuffix(string(f.Data), "\n"), "\n")
+		}
+	}
+	return nil
+}
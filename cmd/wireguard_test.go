@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestCheckWireGuard(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func()
+		wantSub string
+	}{
+		{name: "none", set: func() {}},
+		{name: "wireguard", set: func() { flagType = "wireguard" }},
+		{name: "config", set: func() {
+			flagType, flagWGConfig, flagWGAddress, flagWGEndpoint = "wireguard", true, "10.0.0.2/24, fd00::2/64", "[2001:db8::1]:51820"
+		}},
+		{name: "config without wireguard", set: func() { flagWGConfig = true }, wantSub: "require --type wireguard"},
+		{name: "address without config", set: func() { flagType, flagWGAddress = "wireguard", "10.0.0.2/24" }, wantSub: "require --wg-config"},
+		{name: "bad address", set: func() { flagType, flagWGConfig, flagWGAddress = "wireguard", true, "10.0.0.2" }, wantSub: `"10.0.0.2" is not an address/prefix length`},
+		{name: "endpoint without port", set: func() { flagType, flagWGConfig, flagWGEndpoint = "wireguard", true, "vpn.example.com" }, wantSub: "must be host:port"},
+		{name: "endpoint port", set: func() { flagType, flagWGConfig, flagWGEndpoint = "wireguard", true, "vpn:70000" }, wantSub: "port must be 1-65535"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveFlags(t)
+			tt.set()
+			err := checkWireGuard()
+			switch {
+			case tt.wantSub == "" && err != nil:
+				t.Errorf("checkWireGuard() = %v, want nil", err)
+			case tt.wantSub != "" && (err == nil || !strings.Contains(err.Error(), tt.wantSub)):
+				t.Errorf("checkWireGuard() = %v, want error containing %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestWGSnippets(t *testing.T) {
+	saveFlags(t)
+	flagWGAddress, flagWGEndpoint = "10.0.0.2/24,fd00::2/64", "vpn.example.com:51820"
+	iface, peer, err := wgSnippets("PRIV=", "PUB=")
+	if err != nil {
+		t.Fatalf("wgSnippets: %v", err)
+	}
+	wantIface := "[Interface]\nPrivateKey = PRIV=\nAddress = 10.0.0.2/24, fd00::2/64\nListenPort = 51820\n"
+	wantPeer := "[Peer]\nPublicKey = PUB=\nAllowedIPs = 10.0.0.2/32, fd00::2/128\nEndpoint = vpn.example.com:51820\n"
+	if iface != wantIface || peer != wantPeer {
+		t.Errorf("snippets =\n%s%s\nwant\n%s%s", iface, peer, wantIface, wantPeer)
+	}
+
+	flagWGAddress, flagWGEndpoint = "", ""
+	if iface, peer, _ = wgSnippets("PRIV=", "PUB="); iface != "[Interface]\nPrivateKey = PRIV=\n" || peer != "[Peer]\nPublicKey = PUB=\n" {
+		t.Errorf("bare snippets = %q, %q", iface, peer)
+	}
+}
+
+func TestRun_WireGuard(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "wireguard", "--jobs", "1", "^wg"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	privFile, err := os.ReadFile(filepath.Join(dir, "wireguard", "privatekey"))
+	if err != nil {
+		t.Fatalf("private key: %v", err)
+	}
+	if stdout != string(privFile) {
+		t.Errorf("stdout = %q, want the private key file %q", stdout, privFile)
+	}
+	pubFile, err := os.ReadFile(filepath.Join(dir, "wireguard", "publickey"))
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	priv, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(privFile)))
+	if err != nil {
+		t.Fatalf("decode private key: %v", err)
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := base64.StdEncoding.EncodeToString(pub) + "\n"; string(pubFile) != want || !strings.HasPrefix(want, "wg") {
+		t.Errorf("public key file = %q, private key gives %q", pubFile, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wireguard", "peer.conf")); err == nil {
+		t.Error("peer.conf written without --wg-config")
+	}
+}
+
+func TestRun_WireGuardConfig_JSON(t *testing.T) {
+	dir := chdirTemp(t)
+	saveFlags(t)
+	rootCmd.SetArgs([]string{"--type", "wireguard", "--wg-config", "--wg-address", "10.9.0.3/24", "--output", "json", "--jobs", "1", "A"})
+	stdout := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	})
+
+	var rec matchRecord
+	if err := json.Unmarshal([]byte(stdout), &rec); err != nil {
+		t.Fatalf("unmarshal %q: %v", stdout, err)
+	}
+	wantPeer := "[Peer]\nPublicKey = " + rec.PublicKey + "\nAllowedIPs = 10.9.0.3/32"
+	if rec.WireGuardPeer != wantPeer || len(rec.Files) != 4 {
+		t.Errorf("wireguard_peer = %q, want %q; files %+v", rec.WireGuardPeer, wantPeer, rec.Files)
+	}
+	iface, err := os.ReadFile(filepath.Join(dir, "wireguard", "interface.conf"))
+	if err != nil {
+		t.Fatalf("interface.conf: %v", err)
+	}
+	if want := "PrivateKey = " + strings.TrimSpace(rec.PrivateKey) + "\n"; !strings.Contains(string(iface), want) {
+		t.Errorf("interface.conf = %q, want %q", iface, want)
+	}
+	info, err := os.Stat(filepath.Join(dir, "wireguard", "interface.conf"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("interface.conf mode: %v, %v", info, err)
+	}
+}
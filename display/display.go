@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/term"
 )
@@ -127,3 +128,32 @@ func FormatCount(n int64) string {
 	}
 	return string(out)
 }
+
+// secondsPerYear is the length of a Julian year.
+const secondsPerYear = 365.25 * 24 * 3600
+
+// FormatSeconds formats a duration given in seconds, which may be far beyond
+// what time.Duration holds, at the precision a person needs at that scale.
+func FormatSeconds(s float64) string {
+	switch {
+	case math.IsNaN(s) || math.IsInf(s, 1):
+		return "forever"
+	case s < 1:
+		return "<1s"
+	case s < 3600:
+		return time.Duration(s * float64(time.Second)).Round(time.Second).String()
+	case s < 24*3600:
+		m := int64(s / 60)
+		return fmt.Sprintf("%dh%02dm", m/60, m%60)
+	case s < secondsPerYear:
+		return fmt.Sprintf("%.1f days", s/(24*3600))
+	}
+	switch years := s / secondsPerYear; {
+	case years < 10:
+		return fmt.Sprintf("%.1f years", years)
+	case years < 1e12:
+		return FormatCount(int64(years)) + " years"
+	default:
+		return fmt.Sprintf("%.1e years", years)
+	}
+}
@@ -3,6 +3,7 @@ package display
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"regexp"
 	"strings"
@@ -85,6 +86,31 @@ func TestFormatCount(t *testing.T) {
 	}
 }
 
+func TestFormatSeconds(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s    float64
+		want string
+	}{
+		{0.2, "<1s"},
+		{42, "42s"},
+		{90.4, "1m30s"},
+		{3600*5 + 3*60 + 59, "5h03m"},
+		{3 * 24 * 3600, "3.0 days"},
+		{2.5 * secondsPerYear, "2.5 years"},
+		{12345 * secondsPerYear, "12,345 years"},
+		{3e13 * secondsPerYear, "3.0e+13 years"},
+		{math.Inf(1), "forever"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSeconds(tt.s); got != tt.want {
+			t.Errorf("FormatSeconds(%g) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
 func TestIsTTY(t *testing.T) {
 	// Not parallel: modifies package-level state via setTTY.
 
@@ -0,0 +1,86 @@
+package keyfile
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ErrNoEncryption is returned when a passphrase is given to a format that
+// cannot encrypt private keys.
+var ErrNoEncryption = errors.New("format cannot encrypt private keys")
+
+// A File is one file of an encoded key.
+type File struct {
+	// Suffix is appended to the key path to name the file.
+	Suffix string
+	Data   []byte
+	// Secret files hold private key material and are written 0600.
+	Secret bool
+}
+
+// An Encoder encodes key, labelled with comment where the format has room
+// for one, as the files of one format. The first file holds the private
+// key.
+type Encoder func(key crypto.PrivateKey, comment string, opts Options) ([]File, error)
+
+// A Format is a registered key file format.
+type Format struct {
+	Name string
+	// Suffixes are those of the files Encoder returns, in order, so
+	// callers can check the output paths before a key exists.
+	Suffixes []string
+	// Encrypts reports whether the format honours Options.Passphrase.
+	Encrypts bool
+	Encoder  Encoder
+}
+
+// formats holds the registered formats by name. It is written only by
+// Register during package initialization.
+var formats = map[string]Format{}
+
+// Register makes a format available by name. It panics if the name is
+// empty or already registered, like database/sql.Register.
+func Register(f Format) {
+	if f.Name == "" || f.Encoder == nil || len(f.Suffixes) == 0 {
+		panic("keyfile: Register of incomplete format")
+	}
+	if _, dup := formats[f.Name]; dup {
+		panic("keyfile: Register called twice for format " + f.Name)
+	}
+	formats[f.Name] = f
+}
+
+// LookupFormat returns the format registered under name.
+func LookupFormat(name string) (Format, bool) {
+	f, ok := formats[name]
+	return f, ok
+}
+
+// FormatNames returns the names of the registered formats in sorted order.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encode encodes key in format f. A passphrase is refused with
+// ErrNoEncryption unless the format encrypts.
+func (f Format) Encode(key crypto.PrivateKey, comment string, opts Options) ([]File, error) {
+	if len(opts.Passphrase) > 0 && !f.Encrypts {
+		return nil, fmt.Errorf("%s: %w", f.Name, ErrNoEncryption)
+	}
+	files, err := f.Encoder(key, comment, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.EqualFunc(files, f.Suffixes, func(file File, suffix string) bool { return file.Suffix == suffix }) {
+		return nil, fmt.Errorf("%s: encoder returned files other than %q", f.Name, f.Suffixes)
+	}
+	return files, nil
+}
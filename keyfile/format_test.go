@@ -0,0 +1,91 @@
+package keyfile
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFormatNames(t *testing.T) {
+	t.Parallel()
+
+	got := FormatNames()
+	for _, want := range []string{"jwk", "openssh", "pkcs8", "ppk"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("FormatNames() = %q, missing %q", got, want)
+		}
+	}
+	if !slices.IsSorted(got) {
+		t.Errorf("FormatNames() = %q, not sorted", got)
+	}
+}
+
+func TestFormat_Encode(t *testing.T) {
+	t.Parallel()
+
+	keys := testKeys(t)
+	for _, name := range FormatNames() {
+		f, _ := LookupFormat(name)
+		for keyName, key := range keys {
+			t.Run(name+"/"+keyName, func(t *testing.T) {
+				t.Parallel()
+				files, err := f.Encode(key, "me@host", Options{})
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				if !files[0].Secret {
+					t.Error("first file is not the private key")
+				}
+				for _, file := range files {
+					if len(file.Data) == 0 {
+						t.Errorf("file %q is empty", file.Suffix)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFormat_Encode_Passphrase(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range FormatNames() {
+		f, _ := LookupFormat(name)
+		_, err := f.Encode(key, "", Options{Passphrase: []byte("pw"), Rounds: 1})
+		switch {
+		case f.Encrypts && err != nil:
+			t.Errorf("%s: Encode with passphrase: %v", name, err)
+		case !f.Encrypts && !errors.Is(err, ErrNoEncryption):
+			t.Errorf("%s: Encode with passphrase error = %v, want ErrNoEncryption", name, err)
+		}
+	}
+}
+
+func TestFormat_Encode_WrongSuffixes(t *testing.T) {
+	t.Parallel()
+
+	f := Format{Name: "bad", Suffixes: []string{".a"}, Encoder: func(crypto.PrivateKey, string, Options) ([]File, error) {
+		return []File{{Suffix: ".b", Secret: true}}, nil
+	}}
+	if _, err := f.Encode(nil, "", Options{}); err == nil {
+		t.Error("Encode accepted files that do not match Suffixes")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering openssh twice did not panic")
+		}
+	}()
+	f, _ := LookupFormat("openssh")
+	Register(f)
+}
@@ -0,0 +1,151 @@
+package keyfile
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// jwk is a JSON Web Key (RFC 7517) for the key types vanityssh generates.
+// Private members are empty in a public key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+func init() {
+	Register(Format{
+		Name:     "jwk",
+		Suffixes: []string{".jwk", ".jwks"},
+		Encoder: func(key crypto.PrivateKey, _ string, _ Options) ([]File, error) {
+			priv, err := MarshalJWK(key)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			set, err := MarshalJWKS(signer.Public())
+			if err != nil {
+				return nil, err
+			}
+			return []File{{Suffix: ".jwk", Data: priv, Secret: true}, {Suffix: ".jwks", Data: set}}, nil
+		},
+	})
+}
+
+// MarshalJWK encodes key as a private JSON Web Key. Its kid is the SSH
+// SHA256 fingerprint of the key, as ssh-keygen -l prints it.
+func MarshalJWK(key crypto.PrivateKey) ([]byte, error) {
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	j, err := publicJWK(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		j.D = b64url(k.Seed())
+	case *rsa.PrivateKey:
+		if k.Precomputed.Qinv == nil {
+			k.Precompute()
+		}
+		j.D, j.P, j.Q = b64url(k.D.Bytes()), b64url(k.Primes[0].Bytes()), b64url(k.Primes[1].Bytes())
+		j.DP, j.DQ, j.QI = b64url(k.Precomputed.Dp.Bytes()), b64url(k.Precomputed.Dq.Bytes()), b64url(k.Precomputed.Qinv.Bytes())
+	case *ecdsa.PrivateKey:
+		d, err := k.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		j.D = b64url(d)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return marshalJSON(j)
+}
+
+// MarshalJWKS encodes pub as a JSON Web Key Set holding that one key, for
+// publishing to verifiers.
+func MarshalJWKS(pub crypto.PublicKey) ([]byte, error) {
+	j, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(struct {
+		Keys []jwk `json:"keys"`
+	}{[]jwk{j}})
+}
+
+// publicJWK returns the public members of the JWK for pub, with the
+// signature algorithm the key is used with.
+func publicJWK(pub crypto.PublicKey) (jwk, error) {
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return jwk{}, fmt.Errorf("convert public key: %w", err)
+	}
+	j := jwk{Use: "sig", Kid: ssh.FingerprintSHA256(sshPub)}
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		j.Kty, j.Crv, j.Alg, j.X = "OKP", "Ed25519", "EdDSA", b64url(k)
+	case *rsa.PublicKey:
+		j.Kty, j.Alg = "RSA", "RS256"
+		j.N, j.E = b64url(k.N.Bytes()), b64url(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		alg := map[string]string{"P-256": "ES256", "P-384": "ES384", "P-521": "ES512"}[k.Curve.Params().Name]
+		if alg == "" {
+			return jwk{}, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+		// The uncompressed point is 0x04 followed by the fixed-size
+		// coordinates.
+		point, err := k.Bytes()
+		if err != nil {
+			return jwk{}, err
+		}
+		size := (len(point) - 1) / 2
+		j.Kty, j.Crv, j.Alg = "EC", k.Curve.Params().Name, alg
+		j.X, j.Y = b64url(point[1:1+size]), b64url(point[1+size:])
+	default:
+		return jwk{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return j, nil
+}
+
+// b64url is the unpadded base64url encoding JWK uses for binary members.
+func b64url(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// marshalJSON returns v as indented JSON ending in a newline.
+func marshalJSON(v any) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,127 @@
+package keyfile
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// unb64url decodes a JWK member, failing the test on bad encoding.
+func unb64url(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
+
+func TestMarshalJWK(t *testing.T) {
+	t.Parallel()
+
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			out, err := MarshalJWK(key)
+			if err != nil {
+				t.Fatalf("MarshalJWK: %v", err)
+			}
+			var j jwk
+			if err := json.Unmarshal(out, &j); err != nil {
+				t.Fatalf("unmarshal JWK: %v", err)
+			}
+			pub, err := ssh.NewPublicKey(key.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if j.Kid != ssh.FingerprintSHA256(pub) || j.Use != "sig" {
+				t.Errorf("kid = %q, use = %q, want the SSH fingerprint and sig", j.Kid, j.Use)
+			}
+
+			switch k := key.(type) {
+			case ed25519.PrivateKey:
+				if j.Kty != "OKP" || j.Crv != "Ed25519" || j.Alg != "EdDSA" {
+					t.Errorf("kty/crv/alg = %s/%s/%s", j.Kty, j.Crv, j.Alg)
+				}
+				if !ed25519.NewKeyFromSeed(unb64url(t, j.D)).Equal(k) || string(unb64url(t, j.X)) != string(k.Public().(ed25519.PublicKey)) {
+					t.Error("JWK holds a different key")
+				}
+			case *rsa.PrivateKey:
+				n := new(big.Int).SetBytes(unb64url(t, j.N))
+				d := new(big.Int).SetBytes(unb64url(t, j.D))
+				p := new(big.Int).SetBytes(unb64url(t, j.P))
+				q := new(big.Int).SetBytes(unb64url(t, j.Q))
+				if j.Kty != "RSA" || n.Cmp(k.N) != 0 || d.Cmp(k.D) != 0 || new(big.Int).Mul(p, q).Cmp(k.N) != 0 {
+					t.Error("JWK holds a different RSA key")
+				}
+				if e := new(big.Int).SetBytes(unb64url(t, j.E)); e.Int64() != int64(k.E) {
+					t.Errorf("e = %v, want %d", e, k.E)
+				}
+				if j.DP == "" || j.DQ == "" || j.QI == "" {
+					t.Error("JWK lacks the CRT members")
+				}
+			case *ecdsa.PrivateKey:
+				d, err := k.Bytes()
+				if err != nil {
+					t.Fatal(err)
+				}
+				point, err := k.PublicKey.Bytes()
+				if err != nil {
+					t.Fatal(err)
+				}
+				x, y := unb64url(t, j.X), unb64url(t, j.Y)
+				if j.Kty != "EC" || j.Crv != k.Curve.Params().Name || string(unb64url(t, j.D)) != string(d) {
+					t.Errorf("JWK = %+v", j)
+				}
+				if string(append(append([]byte{4}, x...), y...)) != string(point) {
+					t.Error("JWK holds a different public point")
+				}
+			}
+		})
+	}
+}
+
+func TestMarshalJWKS(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := MarshalJWKS(key.Public())
+	if err != nil {
+		t.Fatalf("MarshalJWKS: %v", err)
+	}
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(out, &set); err != nil {
+		t.Fatalf("unmarshal JWKS: %v", err)
+	}
+	if len(set.Keys) != 1 {
+		t.Fatalf("JWKS holds %d keys, want 1", len(set.Keys))
+	}
+	k := set.Keys[0]
+	if k["kty"] != "OKP" || k["crv"] != "Ed25519" || k["x"] != base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)) {
+		t.Errorf("JWKS key = %v", k)
+	}
+	for _, private := range []string{"d", "p", "q", "dp", "dq", "qi"} {
+		if _, ok := k[private]; ok {
+			t.Errorf("public JWKS has private member %q", private)
+		}
+	}
+}
+
+func TestMarshalJWK_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := MarshalJWK("not a key"); err == nil {
+		t.Error("unsupported key: want error")
+	}
+}
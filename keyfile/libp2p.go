@@ -0,0 +1,15 @@
+package keyfile
+
+import "crypto/ed25519"
+
+// libp2pKeyType is the KeyType enum value of Ed25519 in libp2p's
+// PrivateKey and PublicKey protobuf messages.
+const libp2pKeyType = 1
+
+// LibP2PPrivateKey returns key as the protobuf PrivateKey message that
+// go-libp2p's crypto.MarshalPrivateKey writes and kubo imports with
+// ipfs key import: field 1, the key type, and field 2, the 64-byte seed and
+// public key.
+func LibP2PPrivateKey(key ed25519.PrivateKey) []byte {
+	return append([]byte{0x08, libp2pKeyType, 0x12, ed25519.PrivateKeySize}, key...)
+}
@@ -0,0 +1,22 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+)
+
+func TestLibP2PPrivateKey(t *testing.T) {
+	t.Parallel()
+
+	// The ed25519 private key test vector of the libp2p peer ID spec.
+	want, err := hex.DecodeString("080112407e0830617c4a7de83925dfb2694556b12936c477a0e1feb2e148ec9da60fee7d1ed1e8fae2c4a144b8be8fd4b47bf3d3b34b871c3cacf6010f0e42d474fce27e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := ed25519.NewKeyFromSeed(want[4:36])
+	if got := LibP2PPrivateKey(key); !bytes.Equal(got, want) {
+		t.Errorf("LibP2PPrivateKey = %x, want %x", got, want)
+	}
+}
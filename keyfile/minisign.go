@@ -0,0 +1,100 @@
+package keyfile
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+// minisign's scrypt limits for encrypted secret keys, as libsodium's
+// crypto_pwhash_scryptsalsa208sha256 opslimit and memlimit: its
+// "sensitive" preset, scrypt with N=2^20, r=8, p=1 and 1 GiB of memory.
+const (
+	minisignOpsLimit = 1 << 25
+	minisignMemLimit = 1 << 30
+)
+
+const (
+	minisignKDF      = "Sc"
+	minisignCheckAlg = "B2"
+	minisignSaltLen  = 32
+)
+
+// MinisignPublicKey returns the minisign public key file of pub with key
+// number keyNum, as minisign -G writes it, with the key ID in its comment.
+func MinisignPublicKey(pub ed25519.PublicKey, keyNum [8]byte) []byte {
+	blob := append(append([]byte(signifyAlgorithm), keyNum[:]...), pub...)
+	return signifyFile(fmt.Sprintf("minisign public key %016X", binary.LittleEndian.Uint64(keyNum[:])), blob)
+}
+
+// MinisignSecretKey returns the minisign secret key file of key with key
+// number keyNum. A non-empty passphrase encrypts it with scrypt, as
+// minisign -G does; otherwise it is stored in the clear, as with
+// minisign -G -W.
+func MinisignSecretKey(key ed25519.PrivateKey, keyNum [8]byte, passphrase []byte) ([]byte, error) {
+	return minisignSecretKey(key, keyNum, passphrase, minisignOpsLimit, minisignMemLimit)
+}
+
+// minisignSecretKey is MinisignSecretKey with the scrypt limits as
+// parameters, so tests need not spend 1 GiB on each key.
+func minisignSecretKey(key ed25519.PrivateKey, keyNum [8]byte, passphrase []byte, opsLimit, memLimit uint64) ([]byte, error) {
+	// The key number, secret key and checksum, encrypted as one.
+	secret := append(append([]byte{}, keyNum[:]...), key...)
+	check := blake2b.Sum256(append([]byte(signifyAlgorithm), secret...))
+	secret = append(secret, check[:]...)
+
+	kdf, comment := []byte{0, 0}, "minisign secret key"
+	salt := make([]byte, minisignSaltLen)
+	if len(passphrase) == 0 {
+		opsLimit, memLimit = 0, 0
+	} else {
+		kdf, comment = []byte(minisignKDF), "minisign encrypted secret key"
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("generate salt: %w", err)
+		}
+		logN, r, p := scryptParams(opsLimit, memLimit)
+		stream, err := scrypt.Key(passphrase, salt, 1<<logN, r, p, len(secret))
+		if err != nil {
+			return nil, fmt.Errorf("derive key: %w", err)
+		}
+		for i := range secret {
+			secret[i] ^= stream[i]
+		}
+	}
+
+	blob := append([]byte(signifyAlgorithm), kdf...)
+	blob = append(blob, minisignCheckAlg...)
+	blob = append(blob, salt...)
+	blob = binary.LittleEndian.AppendUint64(blob, opsLimit)
+	blob = binary.LittleEndian.AppendUint64(blob, memLimit)
+	blob = append(blob, secret...)
+	return signifyFile(comment, blob), nil
+}
+
+// scryptParams returns the scrypt parameters libsodium derives from
+// opsLimit and memLimit (pickparams in crypto_pwhash_scryptsalsa208sha256).
+func scryptParams(opsLimit, memLimit uint64) (logN uint, r, p int) {
+	opsLimit = max(opsLimit, 32768)
+	r = 8
+	var maxN uint64
+	if opsLimit < memLimit/32 {
+		p = 1
+		maxN = opsLimit / uint64(r*4)
+	} else {
+		maxN = memLimit / uint64(r*128)
+	}
+	for logN = 1; logN < 63; logN++ {
+		if 1<<logN > maxN/2 {
+			break
+		}
+	}
+	if opsLimit >= memLimit/32 {
+		maxrp := min((opsLimit/4)/(1<<logN), 0x3fffffff)
+		p = int(maxrp) / r
+	}
+	return logN, r, p
+}
@@ -0,0 +1,108 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestScryptParams(t *testing.T) {
+	t.Parallel()
+
+	// libsodium's presets for crypto_pwhash_scryptsalsa208sha256.
+	tests := []struct {
+		name               string
+		opsLimit, memLimit uint64
+		logN               uint
+		r, p               int
+	}{
+		{"interactive", 1 << 19, 1 << 24, 14, 8, 1},
+		{"sensitive", minisignOpsLimit, minisignMemLimit, 20, 8, 1},
+		{"cpu bound", 1 << 25, 1 << 24, 14, 8, 64},
+		{"memory bound", 1 << 15, 1 << 30, 10, 8, 1},
+	}
+	for _, tt := range tests {
+		logN, r, p := scryptParams(tt.opsLimit, tt.memLimit)
+		if logN != tt.logN || r != tt.r || p != tt.p {
+			t.Errorf("%s: scryptParams = 2^%d, %d, %d; want 2^%d, %d, %d", tt.name, logN, r, p, tt.logN, tt.r, tt.p)
+		}
+	}
+}
+
+func TestMinisignPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyNum := [8]byte{0x4d, 0x3c, 0x2b, 0x1a, 0x7f, 0x4e, 0x8a, 0x05}
+	comment, blob := signifyBlob(t, MinisignPublicKey(pub, keyNum))
+	if comment != "minisign public key 058A4E7F1A2B3C4D" {
+		t.Errorf("comment = %q", comment)
+	}
+	if want := append(append([]byte("Ed"), keyNum[:]...), pub...); !bytes.Equal(blob, want) {
+		t.Errorf("blob = %x, want %x", blob, want)
+	}
+}
+
+func TestMinisignSecretKey(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyNum := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	const opsLimit, memLimit = 1 << 15, 1 << 20
+	for _, tt := range []struct {
+		name       string
+		passphrase []byte
+		kdf        string
+		comment    string
+	}{
+		{"clear", nil, "\x00\x00", "minisign secret key"},
+		{"encrypted", []byte("hunter2"), "Sc", "minisign encrypted secret key"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file, err := minisignSecretKey(key, keyNum, tt.passphrase, opsLimit, memLimit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comment, blob := signifyBlob(t, file)
+			if comment != tt.comment || len(blob) != 158 {
+				t.Fatalf("comment %q, blob of %d bytes", comment, len(blob))
+			}
+			if string(blob[:2]) != "Ed" || string(blob[2:4]) != tt.kdf || string(blob[4:6]) != "B2" {
+				t.Errorf("algorithms %q", blob[:6])
+			}
+			salt := blob[6:38]
+			ops, mem := binary.LittleEndian.Uint64(blob[38:]), binary.LittleEndian.Uint64(blob[46:])
+			secret := bytes.Clone(blob[54:])
+			if tt.passphrase != nil {
+				if ops != opsLimit || mem != memLimit {
+					t.Errorf("limits %d, %d; want %d, %d", ops, mem, opsLimit, memLimit)
+				}
+				logN, r, p := scryptParams(ops, mem)
+				stream, err := scrypt.Key(tt.passphrase, salt, 1<<logN, r, p, len(secret))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range secret {
+					secret[i] ^= stream[i]
+				}
+			}
+			check := blake2b.Sum256(append([]byte("Ed"), secret[:72]...))
+			if !bytes.Equal(secret[:8], keyNum[:]) || !bytes.Equal(secret[8:72], key) || !bytes.Equal(secret[72:], check[:]) {
+				t.Error("secret key does not decrypt to the key number, key and checksum")
+			}
+		})
+	}
+}
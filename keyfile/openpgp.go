@@ -0,0 +1,189 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"time"
+)
+
+// OpenPGP packet tags (RFC 4880, section 4.3).
+const (
+	openpgpTagSignature = 2
+	openpgpTagSecretKey = 5
+	openpgpTagPublicKey = 6
+	openpgpTagUserID    = 13
+)
+
+// OpenPGP algorithm numbers and the signature and subpacket types of a
+// user ID self-signature.
+const (
+	openpgpAlgoEdDSA       = 22
+	openpgpHashSHA256      = 8
+	openpgpSigPositiveCert = 0x13
+
+	openpgpSubCreated       = 2
+	openpgpSubPrefCipher    = 11
+	openpgpSubIssuer        = 16
+	openpgpSubPrefHash      = 21
+	openpgpSubPrefComp      = 22
+	openpgpSubKeyFlags      = 27
+	openpgpSubFeatures      = 30
+	openpgpSubIssuerFpr     = 33
+	openpgpKeyFlagsCertSign = 0x03
+)
+
+// openpgpEd25519OID is the curve OID of legacy EdDSA keys,
+// 1.3.6.1.4.1.11591.15.1.
+var openpgpEd25519OID = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+
+// OpenPGPSecretKey returns the ASCII-armored OpenPGP secret key of key,
+// created at created, with user ID uid and a self-signature made at signed:
+// an unencrypted v4 EdDSA key that can certify and sign, as gpg
+// --export-secret-keys --armor writes it.
+func OpenPGPSecretKey(key ed25519.PrivateKey, created time.Time, uid string, signed time.Time) []byte {
+	pub := OpenPGPPublicKeyBody(key.Public().(ed25519.PublicKey), created)
+	secret := openpgpMPI(nil, key.Seed())
+	var sum uint16
+	for _, b := range secret {
+		sum += uint16(b)
+	}
+	body := append(append(append(bytes.Clone(pub), 0), secret...), byte(sum>>8), byte(sum))
+
+	packets := openpgpPacket(nil, openpgpTagSecretKey, body)
+	packets = openpgpPacket(packets, openpgpTagUserID, []byte(uid))
+	packets = openpgpPacket(packets, openpgpTagSignature, openpgpSelfSignature(key, pub, uid, signed))
+	return openpgpArmor("PGP PRIVATE KEY BLOCK", packets)
+}
+
+// OpenPGPPublicKey returns the ASCII-armored OpenPGP public key that
+// OpenPGPSecretKey returns the secret key of.
+func OpenPGPPublicKey(key ed25519.PrivateKey, created time.Time, uid string, signed time.Time) []byte {
+	pub := OpenPGPPublicKeyBody(key.Public().(ed25519.PublicKey), created)
+	packets := openpgpPacket(nil, openpgpTagPublicKey, pub)
+	packets = openpgpPacket(packets, openpgpTagUserID, []byte(uid))
+	packets = openpgpPacket(packets, openpgpTagSignature, openpgpSelfSignature(key, pub, uid, signed))
+	return openpgpArmor("PGP PUBLIC KEY BLOCK", packets)
+}
+
+// OpenPGPPublicKeyBody returns the body of the v4 public key packet of the
+// ed25519 key pub created at created, which its fingerprint hashes.
+func OpenPGPPublicKeyBody(pub ed25519.PublicKey, created time.Time) []byte {
+	b := binary.BigEndian.AppendUint32([]byte{4}, uint32(created.Unix()))
+	b = append(b, openpgpAlgoEdDSA, byte(len(openpgpEd25519OID)))
+	b = append(b, openpgpEd25519OID...)
+	return openpgpMPI(b, append([]byte{0x40}, pub...))
+}
+
+// openpgpFingerprint returns the v4 fingerprint of the public key packet
+// body pub.
+func openpgpFingerprint(pub []byte) [sha1.Size]byte {
+	h := sha1.New()
+	h.Write([]byte{0x99, byte(len(pub) >> 8), byte(len(pub))})
+	h.Write(pub)
+	var fp [sha1.Size]byte
+	h.Sum(fp[:0])
+	return fp
+}
+
+// openpgpSelfSignature returns the body of the positive certification of
+// uid by key, whose public key packet body is pub.
+func openpgpSelfSignature(key ed25519.PrivateKey, pub []byte, uid string, signed time.Time) []byte {
+	fp := openpgpFingerprint(pub)
+	var hashed []byte
+	hashed = openpgpSubpacket(hashed, openpgpSubCreated, binary.BigEndian.AppendUint32(nil, uint32(signed.Unix()))...)
+	hashed = openpgpSubpacket(hashed, openpgpSubKeyFlags, openpgpKeyFlagsCertSign)
+	hashed = openpgpSubpacket(hashed, openpgpSubPrefCipher, 9, 8, 7) // AES-256, AES-192, AES-128
+	hashed = openpgpSubpacket(hashed, openpgpSubPrefHash, 10, 9, 8)  // SHA-512, SHA-384, SHA-256
+	hashed = openpgpSubpacket(hashed, openpgpSubPrefComp, 2, 3, 1)   // ZLIB, BZip2, ZIP
+	hashed = openpgpSubpacket(hashed, openpgpSubFeatures, 0x01)      // modification detection
+	hashed = openpgpSubpacket(hashed, openpgpSubIssuerFpr, append([]byte{4}, fp[:]...)...)
+
+	head := []byte{4, openpgpSigPositiveCert, openpgpAlgoEdDSA, openpgpHashSHA256}
+	head = binary.BigEndian.AppendUint16(head, uint16(len(hashed)))
+	head = append(head, hashed...)
+
+	h := sha256.New()
+	h.Write([]byte{0x99, byte(len(pub) >> 8), byte(len(pub))})
+	h.Write(pub)
+	h.Write(binary.BigEndian.AppendUint32([]byte{0xb4}, uint32(len(uid))))
+	h.Write([]byte(uid))
+	h.Write(head)
+	h.Write(binary.BigEndian.AppendUint32([]byte{4, 0xff}, uint32(len(head))))
+	digest := h.Sum(nil)
+	sig := ed25519.Sign(key, digest)
+
+	unhashed := openpgpSubpacket(nil, openpgpSubIssuer, fp[len(fp)-8:]...)
+	body := binary.BigEndian.AppendUint16(head, uint16(len(unhashed)))
+	body = append(body, unhashed...)
+	body = append(body, digest[:2]...)
+	body = openpgpMPI(body, sig[:32])
+	return openpgpMPI(body, sig[32:])
+}
+
+// openpgpPacket appends the packet with tag and body to b, in the new
+// packet format.
+func openpgpPacket(b []byte, tag byte, body []byte) []byte {
+	b = append(b, 0xc0|tag)
+	switch n := len(body); {
+	case n < 192:
+		b = append(b, byte(n))
+	case n < 8384:
+		n -= 192
+		b = append(b, byte(n>>8)+192, byte(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xff), uint32(n))
+	}
+	return append(b, body...)
+}
+
+// openpgpSubpacket appends the signature subpacket of type typ to b.
+func openpgpSubpacket(b []byte, typ byte, data ...byte) []byte {
+	return append(append(b, byte(1+len(data)), typ), data...)
+}
+
+// openpgpMPI appends the big-endian integer v to b as an MPI: its length
+// in bits, then its bytes without leading zeros.
+func openpgpMPI(b, v []byte) []byte {
+	v = bytes.TrimLeft(v, "\x00")
+	n := 0
+	if len(v) > 0 {
+		n = (len(v)-1)*8 + bits.Len8(v[0])
+	}
+	return append(binary.BigEndian.AppendUint16(b, uint16(n)), v...)
+}
+
+// openpgpArmor wraps data in ASCII armor of the given type, with gpg's
+// 64-column lines and the CRC-24 checksum line.
+func openpgpArmor(typ string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("-----BEGIN " + typ + "-----\n\n")
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 64 {
+		b.WriteString(enc[:64] + "\n")
+		enc = enc[64:]
+	}
+	crc := openpgpCRC24(data)
+	b.WriteString(enc + "\n=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) + "\n")
+	b.WriteString("-----END " + typ + "-----\n")
+	return b.Bytes()
+}
+
+// openpgpCRC24 returns the armor checksum of data (RFC 4880, section 6.1).
+func openpgpCRC24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, c := range data {
+		crc ^= uint32(c) << 16
+		for range 8 {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
@@ -0,0 +1,208 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openpgpDearmor decodes armored, checking its type and CRC-24.
+func openpgpDearmor(t *testing.T, armored []byte, typ string) []byte {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(string(armored), "\n"), "\n")
+	if lines[0] != "-----BEGIN "+typ+"-----" || lines[1] != "" || lines[len(lines)-1] != "-----END "+typ+"-----" {
+		t.Fatalf("armor framing:\n%s", armored)
+	}
+	crcLine := lines[len(lines)-2]
+	data, err := base64.StdEncoding.DecodeString(strings.Join(lines[2:len(lines)-2], ""))
+	if err != nil {
+		t.Fatalf("armor body: %v", err)
+	}
+	for _, l := range lines[2 : len(lines)-2] {
+		if len(l) > 64 {
+			t.Errorf("armor line of %d characters", len(l))
+		}
+	}
+	crc := openpgpCRC24(data)
+	if want := "=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}); crcLine != want {
+		t.Errorf("checksum line %q, want %q", crcLine, want)
+	}
+	return data
+}
+
+// openpgpPackets splits data into new-format packets, returning their tags
+// and bodies.
+func openpgpPackets(t *testing.T, data []byte) ([]byte, [][]byte) {
+	t.Helper()
+	var tags []byte
+	var bodies [][]byte
+	for len(data) > 0 {
+		if data[0]&0xc0 != 0xc0 {
+			t.Fatalf("packet header %#x is not in the new format", data[0])
+		}
+		tags = append(tags, data[0]&0x3f)
+		var n, hlen int
+		switch l := data[1]; {
+		case l < 192:
+			n, hlen = int(l), 2
+		case l < 224:
+			n, hlen = (int(l)-192)<<8+int(data[2])+192, 3
+		default:
+			n, hlen = int(binary.BigEndian.Uint32(data[2:])), 6
+		}
+		bodies = append(bodies, data[hlen:hlen+n])
+		data = data[hlen+n:]
+	}
+	return tags, bodies
+}
+
+func TestOpenPGPPacketLengths(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 191, 192, 8383, 8384, 70000} {
+		body := bytes.Repeat([]byte{7}, n)
+		tags, bodies := openpgpPackets(t, openpgpPacket(nil, openpgpTagUserID, body))
+		if len(tags) != 1 || tags[0] != openpgpTagUserID || !bytes.Equal(bodies[0], body) {
+			t.Errorf("length %d does not round-trip", n)
+		}
+	}
+}
+
+func TestOpenPGPMPI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		v    []byte
+		want string
+	}{
+		{[]byte{0x00, 0x00, 0x01}, "000101"},
+		{[]byte{0x40, 0xff}, "000f40ff"},
+		{[]byte{0x80}, "000880"},
+		{nil, "0000"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(openpgpMPI(nil, tt.v)); got != tt.want {
+			t.Errorf("openpgpMPI(%x) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestOpenPGPSecretKey(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, signed := time.Unix(1700000000, 0), time.Unix(1700000100, 0)
+	uid := "Test User <test@example.com>"
+
+	secret := openpgpDearmor(t, OpenPGPSecretKey(key, created, uid, signed), "PGP PRIVATE KEY BLOCK")
+	public := openpgpDearmor(t, OpenPGPPublicKey(key, created, uid, signed), "PGP PUBLIC KEY BLOCK")
+	tags, bodies := openpgpPackets(t, secret)
+	if !bytes.Equal(tags, []byte{openpgpTagSecretKey, openpgpTagUserID, openpgpTagSignature}) {
+		t.Fatalf("secret key packets %v", tags)
+	}
+	pubTags, pubBodies := openpgpPackets(t, public)
+	if !bytes.Equal(pubTags, []byte{openpgpTagPublicKey, openpgpTagUserID, openpgpTagSignature}) {
+		t.Fatalf("public key packets %v", pubTags)
+	}
+
+	pub := pubBodies[0]
+	if want := OpenPGPPublicKeyBody(key.Public().(ed25519.PublicKey), created); !bytes.Equal(pub, want) {
+		t.Errorf("public key packet %x, want %x", pub, want)
+	}
+	if !bytes.HasPrefix(bodies[0], pub) {
+		t.Fatal("secret key packet does not start with the public key")
+	}
+	rest := bodies[0][len(pub):]
+	if rest[0] != 0 || !bytes.Equal(openpgpMPI(nil, key.Seed()), rest[1:len(rest)-2]) {
+		t.Errorf("secret key material %x is not the unencrypted seed", rest)
+	}
+	if string(bodies[1]) != uid || !bytes.Equal(bodies[2], pubBodies[2]) {
+		t.Errorf("user ID %q, or the signatures differ", bodies[1])
+	}
+
+	// The signature ends in two 32-byte MPIs whose values are R and S,
+	// over the hashed data the packet describes.
+	sig := bodies[2]
+	hashedLen := int(binary.BigEndian.Uint16(sig[4:]))
+	head := sig[:6+hashedLen]
+	h := sha256.New()
+	h.Write([]byte{0x99, byte(len(pub) >> 8), byte(len(pub))})
+	h.Write(pub)
+	h.Write(binary.BigEndian.AppendUint32([]byte{0xb4}, uint32(len(uid))))
+	h.Write([]byte(uid))
+	h.Write(head)
+	h.Write(binary.BigEndian.AppendUint32([]byte{4, 0xff}, uint32(len(head))))
+	digest := h.Sum(nil)
+	unhashedLen := int(binary.BigEndian.Uint16(sig[len(head):]))
+	tail := sig[len(head)+2+unhashedLen:]
+	if !bytes.Equal(tail[:2], digest[:2]) {
+		t.Errorf("digest prefix %x, want %x", tail[:2], digest[:2])
+	}
+	var rs []byte
+	for mpis := tail[2:]; len(mpis) > 0; {
+		n := (int(binary.BigEndian.Uint16(mpis)) + 7) / 8
+		v := make([]byte, 32)
+		copy(v[32-n:], mpis[2:2+n])
+		rs = append(rs, v...)
+		mpis = mpis[2+n:]
+	}
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), digest, rs) {
+		t.Error("self-signature does not verify")
+	}
+}
+
+// TestOpenPGPSecretKey_GPG imports a key into gpg, when it is installed,
+// and checks its fingerprint, self-signature and that it signs.
+func TestOpenPGPSecretKey_GPG(t *testing.T) {
+	t.Parallel()
+
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg not installed")
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	home := t.TempDir()
+	keyPath := filepath.Join(home, "key.asc")
+	if err := os.WriteFile(keyPath, OpenPGPSecretKey(key, created, "Test <test@example.com>", time.Now()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(stdin string, args ...string) string {
+		cmd := exec.Command(gpg, append([]string{"--homedir", home, "--batch", "--no-tty"}, args...)...)
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("gpg %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	run("", "--import", keyPath)
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run() })
+
+	fp := openpgpFingerprint(OpenPGPPublicKeyBody(key.Public().(ed25519.PublicKey), created))
+	listing := run("", "--with-colons", "--check-sigs")
+	if !strings.Contains(listing, "fpr:::::::::"+strings.ToUpper(hex.EncodeToString(fp[:]))+":") {
+		t.Errorf("gpg lists another fingerprint:\n%s", listing)
+	}
+	if !strings.Contains(listing, "sig:!:") {
+		t.Errorf("gpg does not find a good self-signature:\n%s", listing)
+	}
+	signed := run("hello\n", "--pinentry-mode", "loopback", "--passphrase", "", "--sign", "--armor")
+	run(signed, "--verify")
+}
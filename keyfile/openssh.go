@@ -0,0 +1,182 @@
+package keyfile
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultRounds is the bcrypt KDF work factor ssh-keygen uses without -a.
+const DefaultRounds = 16
+
+// saltLen is the bcrypt KDF salt length ssh-keygen uses.
+const saltLen = 16
+
+const opensshMagic = "openssh-key-v1\x00"
+
+// Options controls how a private key is written.
+type Options struct {
+	// Passphrase encrypts the key when non-empty.
+	Passphrase []byte
+	// Rounds is the bcrypt KDF work factor of the openssh format; zero
+	// means DefaultRounds.
+	Rounds int
+	// PPKVersion is the version of the ppk format; zero means
+	// PPKVersion3.
+	PPKVersion int
+}
+
+func init() {
+	Register(Format{
+		Name:     "openssh",
+		Suffixes: []string{""},
+		Encrypts: true,
+		Encoder: func(key crypto.PrivateKey, comment string, opts Options) ([]File, error) {
+			b, err := MarshalOpenSSH(key, comment, opts)
+			if err != nil {
+				return nil, err
+			}
+			return []File{{Data: b, Secret: true}}, nil
+		},
+	})
+}
+
+func (o Options) rounds() int {
+	if o.Rounds == 0 {
+		return DefaultRounds
+	}
+	return o.Rounds
+}
+
+// MarshalOpenSSH encodes key in the OpenSSH private key format as a PEM
+// block. With a passphrase it is encrypted the way ssh-keygen does it:
+// aes256-ctr with key and IV from bcrypt_pbkdf over a random salt.
+// Supported keys are ed25519.PrivateKey, *rsa.PrivateKey and
+// *ecdsa.PrivateKey.
+func MarshalOpenSSH(key crypto.PrivateKey, comment string, opts Options) ([]byte, error) {
+	if opts.Rounds < 0 {
+		return nil, fmt.Errorf("invalid KDF rounds %d", opts.Rounds)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	pub, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("convert public key: %w", err)
+	}
+	private, err := privateFields(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	block := append(check[:], check[:]...)
+	block = appendString(block, []byte(pub.Type()))
+	block = append(block, private...)
+	block = appendString(block, []byte(comment))
+
+	w := struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{CipherName: "none", KdfName: "none", NumKeys: 1, PubKey: pub.Marshal()}
+
+	if len(opts.Passphrase) == 0 {
+		w.PrivKeyBlock = pad(block, 8)
+	} else {
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		kdfOpts := struct {
+			Salt   []byte
+			Rounds uint32
+		}{salt, uint32(opts.rounds())}
+		k, err := bcryptPBKDF(opts.Passphrase, salt, opts.rounds(), 32+aes.BlockSize)
+		if err != nil {
+			return nil, err
+		}
+		c, err := aes.NewCipher(k[:32])
+		if err != nil {
+			return nil, err
+		}
+		w.PrivKeyBlock = pad(block, aes.BlockSize)
+		cipher.NewCTR(c, k[32:]).XORKeyStream(w.PrivKeyBlock, w.PrivKeyBlock)
+		w.CipherName, w.KdfName, w.KdfOpts = "aes256-ctr", "bcrypt", string(ssh.Marshal(kdfOpts))
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "OPENSSH PRIVATE KEY",
+		Bytes: append([]byte(opensshMagic), ssh.Marshal(w)...),
+	}), nil
+}
+
+// privateFields returns the key-type-specific private section, which
+// follows the key type name and precedes the comment.
+func privateFields(key crypto.PrivateKey) ([]byte, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		// The "private" half is the 32-byte seed followed by the public key.
+		return ssh.Marshal(struct{ Pub, Priv []byte }{k[32:], k}), nil
+	case *ed25519.PrivateKey:
+		return privateFields(*k)
+	case *rsa.PrivateKey:
+		if k.Precomputed.Qinv == nil {
+			k.Precompute()
+		}
+		return ssh.Marshal(struct{ N, E, D, Iqmp, P, Q *big.Int }{
+			k.N, big.NewInt(int64(k.E)), k.D, k.Precomputed.Qinv, k.Primes[0], k.Primes[1],
+		}), nil
+	case *ecdsa.PrivateKey:
+		curve := map[string]string{"P-256": "nistp256", "P-384": "nistp384", "P-521": "nistp521"}[k.Curve.Params().Name]
+		if curve == "" {
+			return nil, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+		point, err := k.PublicKey.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		d, err := k.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return ssh.Marshal(struct {
+			Curve string
+			Pub   []byte
+			D     *big.Int
+		}{curve, point, new(big.Int).SetBytes(d)}), nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// pad appends the 1, 2, 3, ... padding OpenSSH uses up to a multiple of
+// blockSize.
+func pad(b []byte, blockSize int) []byte {
+	for i := byte(1); len(b)%blockSize != 0; i++ {
+		b = append(b, i)
+	}
+	return b
+}
+
+// appendString appends an SSH string (uint32 length + bytes).
+func appendString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,163 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testKeys returns one private key of every supported type.
+func testKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	keys := map[string]crypto.Signer{"ed25519": edKey, "rsa": rsaKey}
+	for name, curve := range map[string]elliptic.Curve{"p256": elliptic.P256(), "p384": elliptic.P384(), "p521": elliptic.P521()} {
+		k, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("ecdsa.GenerateKey: %v", err)
+		}
+		keys["ecdsa-"+name] = k
+	}
+	return keys
+}
+
+// sameKey reports whether parsed is the private key of want.
+func sameKey(t *testing.T, parsed any, want crypto.Signer) bool {
+	t.Helper()
+	got, ok := parsed.(crypto.Signer)
+	if !ok {
+		t.Fatalf("parsed key %T is not a crypto.Signer", parsed)
+	}
+	a, err := ssh.NewPublicKey(got.Public())
+	if err != nil {
+		t.Fatalf("NewPublicKey: %v", err)
+	}
+	b, err := ssh.NewPublicKey(want.Public())
+	if err != nil {
+		t.Fatalf("NewPublicKey: %v", err)
+	}
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
+
+func TestMarshalOpenSSH_Unencrypted(t *testing.T) {
+	t.Parallel()
+
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			out, err := MarshalOpenSSH(key, "me@host", Options{})
+			if err != nil {
+				t.Fatalf("MarshalOpenSSH: %v", err)
+			}
+			parsed, err := ssh.ParseRawPrivateKey(out)
+			if err != nil {
+				t.Fatalf("ParseRawPrivateKey: %v", err)
+			}
+			if !sameKey(t, parsed, key) {
+				t.Error("parsed key differs from original")
+			}
+		})
+	}
+}
+
+func TestMarshalOpenSSH_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	pass := []byte("correct horse")
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			out, err := MarshalOpenSSH(key, "", Options{Passphrase: pass, Rounds: 4})
+			if err != nil {
+				t.Fatalf("MarshalOpenSSH: %v", err)
+			}
+
+			var missing *ssh.PassphraseMissingError
+			if _, err := ssh.ParseRawPrivateKey(out); !errors.As(err, &missing) {
+				t.Errorf("parse without passphrase: err = %v, want PassphraseMissingError", err)
+			}
+			if _, err := ssh.ParseRawPrivateKeyWithPassphrase(out, []byte("wrong")); err == nil {
+				t.Error("parse with wrong passphrase succeeded")
+			}
+			parsed, err := ssh.ParseRawPrivateKeyWithPassphrase(out, pass)
+			if err != nil {
+				t.Fatalf("ParseRawPrivateKeyWithPassphrase: %v", err)
+			}
+			if !sameKey(t, parsed, key) {
+				t.Error("decrypted key differs from original")
+			}
+		})
+	}
+}
+
+func TestMarshalOpenSSH_Header(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want string // cipher, KDF and KDF options as they appear in the file
+	}{
+		{"plain", Options{}, "\x00\x00\x00\x04none\x00\x00\x00\x04none\x00\x00\x00\x00"},
+		{"default rounds", Options{Passphrase: []byte("x")}, "\x00\x00\x00\x0aaes256-ctr\x00\x00\x00\x06bcrypt\x00\x00\x00\x18\x00\x00\x00\x10"},
+		{"custom rounds", Options{Passphrase: []byte("x"), Rounds: 5}, "\x00\x00\x00\x0aaes256-ctr\x00\x00\x00\x06bcrypt\x00\x00\x00\x18\x00\x00\x00\x10"},
+	}
+	for _, tt := range tests {
+		out, err := MarshalOpenSSH(key, "", tt.opts)
+		if err != nil {
+			t.Fatalf("%s: MarshalOpenSSH: %v", tt.name, err)
+		}
+		block, _ := pem.Decode(out)
+		if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+			t.Fatalf("%s: not an OPENSSH PRIVATE KEY PEM block", tt.name)
+		}
+		body := bytes.TrimPrefix(block.Bytes, []byte(opensshMagic))
+		if !bytes.HasPrefix(body, []byte(tt.want)) {
+			t.Errorf("%s: header = %q, want prefix %q", tt.name, body[:len(tt.want)], tt.want)
+		}
+		if tt.opts.Passphrase != nil {
+			// Rounds follow the 16-byte salt inside the KDF options.
+			off := len(tt.want) + saltLen
+			rounds := int(body[off])<<24 | int(body[off+1])<<16 | int(body[off+2])<<8 | int(body[off+3])
+			if rounds != tt.opts.rounds() {
+				t.Errorf("%s: rounds = %d, want %d", tt.name, rounds, tt.opts.rounds())
+			}
+		}
+	}
+}
+
+func TestMarshalOpenSSH_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := MarshalOpenSSH("not a key", "", Options{}); err == nil {
+		t.Error("unsupported key: want error, got nil")
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if _, err := MarshalOpenSSH(key, "", Options{Passphrase: []byte("x"), Rounds: -1}); err == nil {
+		t.Error("negative rounds: want error, got nil")
+	}
+}
@@ -0,0 +1,149 @@
+package keyfile
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+)
+
+// pkcs8Iterations is the PBKDF2-HMAC-SHA256 iteration count for encrypted
+// PKCS #8 keys, OWASP's recommendation for that PRF.
+const pkcs8Iterations = 600_000
+
+// pkcs8SaltLen is the PBKDF2 salt length for encrypted PKCS #8 keys.
+const pkcs8SaltLen = 16
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo and the PBES2 parameters follow RFC 8018.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int
+	PRF        pkix.AlgorithmIdentifier
+}
+
+func init() {
+	Register(Format{
+		Name:     "pkcs8",
+		Suffixes: []string{".pem", ".pub.pem"},
+		Encrypts: true,
+		Encoder: func(key crypto.PrivateKey, _ string, opts Options) ([]File, error) {
+			priv, err := MarshalPKCS8(key, opts.Passphrase)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			pub, err := MarshalPKIXPublicKey(signer.Public())
+			if err != nil {
+				return nil, err
+			}
+			return []File{{Suffix: ".pem", Data: priv, Secret: true}, {Suffix: ".pub.pem", Data: pub}}, nil
+		},
+	})
+}
+
+// MarshalPKCS8 encodes key as a PKCS #8 "PRIVATE KEY" PEM block. With a
+// passphrase it is an "ENCRYPTED PRIVATE KEY" block instead, using PBES2
+// with PBKDF2-HMAC-SHA256 and aes256-cbc, which OpenSSL reads.
+func MarshalPKCS8(key crypto.PrivateKey, passphrase []byte) ([]byte, error) {
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+
+	salt := make([]byte, pkcs8SaltLen)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	k, err := pbkdf2.Key(sha256.New, string(passphrase), salt, pkcs8Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	// PKCS #7 padding: n bytes of value n, a whole block if already aligned.
+	n := aes.BlockSize - len(der)%aes.BlockSize
+	for range n {
+		der = append(der, byte(n))
+	}
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(der, der)
+
+	kdf, err := asn1.Marshal(pbkdf2Params{
+		Salt:       salt,
+		Iterations: pkcs8Iterations,
+		KeyLength:  32,
+		PRF:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivDER, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdf}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivDER}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	info, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: der,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: info}), nil
+}
+
+// MarshalPKIXPublicKey encodes pub as a SubjectPublicKeyInfo "PUBLIC KEY"
+// PEM block.
+func MarshalPKIXPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
@@ -0,0 +1,133 @@
+package keyfile
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+)
+
+// decryptPKCS8 decrypts an "ENCRYPTED PRIVATE KEY" block written by
+// MarshalPKCS8, checking the PBES2 parameters on the way.
+func decryptPKCS8(t *testing.T, block *pem.Block, passphrase []byte) []byte {
+	t.Helper()
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		t.Fatalf("unmarshal EncryptedPrivateKeyInfo: %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		t.Fatalf("algorithm = %v, want PBES2", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		t.Fatalf("unmarshal PBES2 params: %v", err)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		t.Fatalf("unmarshal PBKDF2 params: %v", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) || !kdf.PRF.Algorithm.Equal(oidHMACWithSHA256) || !params.EncryptionScheme.Algorithm.Equal(oidAES256CBC) {
+		t.Fatalf("PBES2 params = %+v, PRF %v", params, kdf.PRF.Algorithm)
+	}
+	if kdf.Iterations != pkcs8Iterations || len(kdf.Salt) != pkcs8SaltLen || kdf.KeyLength != 32 {
+		t.Errorf("PBKDF2 iterations %d, salt %d bytes, key length %d", kdf.Iterations, len(kdf.Salt), kdf.KeyLength)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		t.Fatalf("unmarshal IV: %v", err)
+	}
+
+	k, err := pbkdf2.Key(sha256.New, string(passphrase), kdf.Salt, kdf.Iterations, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := aes.NewCipher(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der := info.EncryptedData
+	cipher.NewCBCDecrypter(c, iv).CryptBlocks(der, der)
+	n := int(der[len(der)-1])
+	if n == 0 || n > aes.BlockSize {
+		t.Fatalf("bad padding %d", n)
+	}
+	return der[:len(der)-n]
+}
+
+func TestMarshalPKCS8(t *testing.T) {
+	t.Parallel()
+
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			out, err := MarshalPKCS8(key, nil)
+			if err != nil {
+				t.Fatalf("MarshalPKCS8: %v", err)
+			}
+			block, _ := pem.Decode(out)
+			if block == nil || block.Type != "PRIVATE KEY" {
+				t.Fatalf("PEM block = %v, want PRIVATE KEY", block)
+			}
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				t.Fatalf("ParsePKCS8PrivateKey: %v", err)
+			}
+			if !sameKey(t, parsed, key) {
+				t.Error("parsed key differs from original")
+			}
+		})
+	}
+}
+
+func TestMarshalPKCS8_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass := []byte("correct horse")
+	out, err := MarshalPKCS8(key, pass)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8: %v", err)
+	}
+	block, _ := pem.Decode(out)
+	if block == nil || block.Type != "ENCRYPTED PRIVATE KEY" {
+		t.Fatalf("PEM block = %v, want ENCRYPTED PRIVATE KEY", block)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(decryptPKCS8(t, block, pass))
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKey: %v", err)
+	}
+	if !sameKey(t, parsed, key) {
+		t.Error("decrypted key differs from original")
+	}
+}
+
+func TestMarshalPKIXPublicKey(t *testing.T) {
+	t.Parallel()
+
+	for name, key := range testKeys(t) {
+		out, err := MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatalf("%s: MarshalPKIXPublicKey: %v", name, err)
+		}
+		block, _ := pem.Decode(out)
+		if block == nil || block.Type != "PUBLIC KEY" {
+			t.Fatalf("%s: PEM block = %v, want PUBLIC KEY", name, block)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			t.Fatalf("%s: ParsePKIXPublicKey: %v", name, err)
+		}
+		if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(key.Public()) {
+			t.Errorf("%s: parsed public key differs", name)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"slices"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+// PPK format versions.
+const (
+	PPKVersion2 = 2
+	PPKVersion3 = 3
+)
+
+// Argon2id parameters for encrypted PPK v3 files: PuTTYgen's memory and
+// parallelism, with a fixed pass count in place of its 100ms time target.
+const (
+	ppkArgon2Memory      = 8192 // KiB
+	ppkArgon2Passes      = 13
+	ppkArgon2Parallelism = 1
+	ppkArgon2SaltLen     = 16
+)
+
+// ppkLineLen is the base64 line length of the Public-Lines and
+// Private-Lines sections.
+const ppkLineLen = 64
+
+// PPKOptions controls how a PuTTY private key is written.
+type PPKOptions struct {
+	// Passphrase encrypts the key when non-empty.
+	Passphrase []byte
+	// Version is PPKVersion2 or PPKVersion3; zero means PPKVersion3.
+	Version int
+}
+
+func init() {
+	Register(Format{
+		Name:     "ppk",
+		Suffixes: []string{".ppk"},
+		Encrypts: true,
+		Encoder: func(key crypto.PrivateKey, comment string, opts Options) ([]File, error) {
+			b, err := MarshalPPK(key, comment, PPKOptions{Passphrase: opts.Passphrase, Version: opts.PPKVersion})
+			if err != nil {
+				return nil, err
+			}
+			return []File{{Suffix: ".ppk", Data: b, Secret: true}}, nil
+		},
+	})
+}
+
+// MarshalPPK encodes key as a PuTTY private key file. Version 3 derives the
+// encryption and MAC keys with Argon2id; version 2, for PuTTY before 0.75,
+// uses SHA-1. Encrypted files use aes256-cbc. Supported keys are the same
+// as for MarshalOpenSSH.
+func MarshalPPK(key crypto.PrivateKey, comment string, opts PPKOptions) ([]byte, error) {
+	version := opts.Version
+	if version == 0 {
+		version = PPKVersion3
+	}
+	if version != PPKVersion2 && version != PPKVersion3 {
+		return nil, fmt.Errorf("unsupported PPK version %d", opts.Version)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	pub, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("convert public key: %w", err)
+	}
+	private, err := ppkPrivateBlob(key)
+	if err != nil {
+		return nil, err
+	}
+
+	encryption := "none"
+	if len(opts.Passphrase) > 0 {
+		encryption = "aes256-cbc"
+		// Pad to the cipher block size with the start of the blob's SHA-1,
+		// as PuTTY does, rather than with predictable bytes.
+		sum := sha1.Sum(private)
+		private = append(private, sum[:(aes.BlockSize-len(private)%aes.BlockSize)%aes.BlockSize]...)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "PuTTY-User-Key-File-%d: %s\n", version, pub.Type())
+	fmt.Fprintf(&out, "Encryption: %s\n", encryption)
+	fmt.Fprintf(&out, "Comment: %s\n", comment)
+	writePPKLines(&out, "Public", pub.Marshal())
+
+	var cipherKey, iv, macKey []byte
+	var newMAC func() hash.Hash
+	switch {
+	case version == PPKVersion2:
+		newMAC = sha1.New
+		mk := sha1.Sum(append([]byte("putty-private-key-file-mac-key"), opts.Passphrase...))
+		macKey = mk[:]
+		if encryption != "none" {
+			k0 := sha1.Sum(append([]byte{0, 0, 0, 0}, opts.Passphrase...))
+			k1 := sha1.Sum(append([]byte{0, 0, 0, 1}, opts.Passphrase...))
+			cipherKey = append(k0[:], k1[:]...)[:32]
+			iv = make([]byte, aes.BlockSize)
+		}
+	case encryption == "none":
+		// Version 3 without a passphrase MACs with an empty key.
+		newMAC = sha256.New
+	default:
+		newMAC = sha256.New
+		salt := make([]byte, ppkArgon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		k := argon2.IDKey(opts.Passphrase, salt, ppkArgon2Passes, ppkArgon2Memory, ppkArgon2Parallelism, 32+aes.BlockSize+32)
+		cipherKey, iv, macKey = k[:32], k[32:32+aes.BlockSize], k[32+aes.BlockSize:]
+		fmt.Fprintf(&out, "Key-Derivation: Argon2id\n")
+		fmt.Fprintf(&out, "Argon2-Memory: %d\n", ppkArgon2Memory)
+		fmt.Fprintf(&out, "Argon2-Passes: %d\n", ppkArgon2Passes)
+		fmt.Fprintf(&out, "Argon2-Parallelism: %d\n", ppkArgon2Parallelism)
+		fmt.Fprintf(&out, "Argon2-Salt: %x\n", salt)
+	}
+
+	// The MAC covers the plaintext, padding included.
+	mac := hmac.New(newMAC, macKey)
+	for _, field := range [][]byte{[]byte(pub.Type()), []byte(encryption), []byte(comment), pub.Marshal(), private} {
+		mac.Write(appendString(nil, field))
+	}
+
+	if cipherKey != nil {
+		c, err := aes.NewCipher(cipherKey)
+		if err != nil {
+			return nil, err
+		}
+		private = slices.Clone(private)
+		cipher.NewCBCEncrypter(c, iv).CryptBlocks(private, private)
+	}
+	writePPKLines(&out, "Private", private)
+	fmt.Fprintf(&out, "Private-MAC: %s\n", hex.EncodeToString(mac.Sum(nil)))
+	return out.Bytes(), nil
+}
+
+// writePPKLines writes b as a "<name>-Lines: N" header followed by its
+// base64 encoding in lines of ppkLineLen characters.
+func writePPKLines(out *bytes.Buffer, name string, b []byte) {
+	enc := base64.StdEncoding.EncodeToString(b)
+	lines := (len(enc) + ppkLineLen - 1) / ppkLineLen
+	fmt.Fprintf(out, "%s-Lines: %d\n", name, lines)
+	for len(enc) > 0 {
+		n := min(ppkLineLen, len(enc))
+		out.WriteString(enc[:n] + "\n")
+		enc = enc[n:]
+	}
+}
+
+// ppkPrivateBlob returns PuTTY's private key blob for key.
+func ppkPrivateBlob(key crypto.PrivateKey) ([]byte, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		// PuTTY stores the seed as an unsigned little-endian integer, so
+		// its most significant zero bytes are dropped.
+		seed := k.Seed()
+		end := len(seed)
+		for end > 0 && seed[end-1] == 0 {
+			end--
+		}
+		return appendString(nil, seed[:end]), nil
+	case *ed25519.PrivateKey:
+		return ppkPrivateBlob(*k)
+	case *rsa.PrivateKey:
+		if k.Precomputed.Qinv == nil {
+			k.Precompute()
+		}
+		return ssh.Marshal(struct{ D, P, Q, Iqmp *big.Int }{
+			k.D, k.Primes[0], k.Primes[1], k.Precomputed.Qinv,
+		}), nil
+	case *ecdsa.PrivateKey:
+		d, err := k.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return ssh.Marshal(struct{ D *big.Int }{new(big.Int).SetBytes(d)}), nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
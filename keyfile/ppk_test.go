@@ -0,0 +1,299 @@
+package keyfile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+// ppkFile is a parsed PuTTY private key file.
+type ppkFile struct {
+	version         int
+	algorithm       string
+	encryption      string
+	comment         string
+	headers         map[string]string
+	public, private []byte
+	mac             string
+}
+
+// parsePPK splits a PPK file into its headers and sections.
+func parsePPK(t *testing.T, data []byte) ppkFile {
+	t.Helper()
+	f := ppkFile{headers: map[string]string{}}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	readLines := func(n string) []byte {
+		count, err := strconv.Atoi(n)
+		if err != nil {
+			t.Fatalf("bad line count %q", n)
+		}
+		var b64 strings.Builder
+		for range count {
+			if !sc.Scan() {
+				t.Fatal("truncated PPK section")
+			}
+			if len(sc.Text()) > ppkLineLen {
+				t.Errorf("base64 line of %d characters", len(sc.Text()))
+			}
+			b64.WriteString(sc.Text())
+		}
+		b, err := base64.StdEncoding.DecodeString(b64.String())
+		if err != nil {
+			t.Fatalf("decode section: %v", err)
+		}
+		return b
+	}
+	for sc.Scan() {
+		name, value, ok := strings.Cut(sc.Text(), ": ")
+		if !ok {
+			t.Fatalf("malformed PPK line %q", sc.Text())
+		}
+		switch {
+		case strings.HasPrefix(name, "PuTTY-User-Key-File-"):
+			f.version, _ = strconv.Atoi(strings.TrimPrefix(name, "PuTTY-User-Key-File-"))
+			f.algorithm = value
+		case name == "Public-Lines":
+			f.public = readLines(value)
+		case name == "Private-Lines":
+			f.private = readLines(value)
+		case name == "Private-MAC":
+			f.mac = value
+		case name == "Encryption":
+			f.encryption = value
+		case name == "Comment":
+			f.comment = value
+		default:
+			f.headers[name] = value
+		}
+	}
+	return f
+}
+
+// decryptPPK checks the MAC of f and returns its plaintext private blob,
+// following the PuTTY key file format documentation.
+func decryptPPK(t *testing.T, f ppkFile, passphrase []byte) []byte {
+	t.Helper()
+	private := f.private
+	var macKey []byte
+	var newMAC func() hash.Hash
+	switch f.version {
+	case 2:
+		newMAC = sha1.New
+		mk := sha1.Sum(append([]byte("putty-private-key-file-mac-key"), passphrase...))
+		macKey = mk[:]
+		if f.encryption == "aes256-cbc" {
+			k0 := sha1.Sum(append([]byte{0, 0, 0, 0}, passphrase...))
+			k1 := sha1.Sum(append([]byte{0, 0, 0, 1}, passphrase...))
+			c, err := aes.NewCipher(append(k0[:], k1[:12]...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			private = bytes.Clone(private)
+			cipher.NewCBCDecrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(private, private)
+		}
+	case 3:
+		newMAC = sha256.New
+		if f.encryption == "aes256-cbc" {
+			if f.headers["Key-Derivation"] != "Argon2id" {
+				t.Fatalf("Key-Derivation = %q", f.headers["Key-Derivation"])
+			}
+			num := func(h string) uint32 {
+				n, err := strconv.ParseUint(f.headers[h], 10, 32)
+				if err != nil {
+					t.Fatalf("%s: %v", h, err)
+				}
+				return uint32(n)
+			}
+			salt, err := hex.DecodeString(f.headers["Argon2-Salt"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			k := argon2.IDKey(passphrase, salt, num("Argon2-Passes"), num("Argon2-Memory"), uint8(num("Argon2-Parallelism")), 80)
+			c, err := aes.NewCipher(k[:32])
+			if err != nil {
+				t.Fatal(err)
+			}
+			private = bytes.Clone(private)
+			cipher.NewCBCDecrypter(c, k[32:48]).CryptBlocks(private, private)
+			macKey = k[48:]
+		}
+	default:
+		t.Fatalf("unexpected version %d", f.version)
+	}
+
+	mac := hmac.New(newMAC, macKey)
+	for _, field := range [][]byte{[]byte(f.algorithm), []byte(f.encryption), []byte(f.comment), f.public, private} {
+		mac.Write(appendString(nil, field))
+	}
+	if got := hex.EncodeToString(mac.Sum(nil)); got != f.mac {
+		t.Fatalf("Private-MAC = %s, want %s", f.mac, got)
+	}
+	return private
+}
+
+// checkPPKKey verifies that the private blob belongs to want.
+func checkPPKKey(t *testing.T, private []byte, want crypto.Signer) {
+	t.Helper()
+	switch k := want.(type) {
+	case ed25519.PrivateKey:
+		var blob struct {
+			Seed []byte
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &blob); err != nil {
+			t.Fatalf("unmarshal ed25519 blob: %v", err)
+		}
+		seed := make([]byte, ed25519.SeedSize)
+		copy(seed, blob.Seed)
+		if !ed25519.NewKeyFromSeed(seed).Equal(k) {
+			t.Error("PPK holds a different ed25519 key")
+		}
+	case *rsa.PrivateKey:
+		var blob struct {
+			D, P, Q, Iqmp *big.Int
+			Rest          []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &blob); err != nil {
+			t.Fatalf("unmarshal rsa blob: %v", err)
+		}
+		if new(big.Int).Mul(blob.P, blob.Q).Cmp(k.N) != 0 || blob.D.Cmp(k.D) != 0 {
+			t.Error("PPK holds a different RSA key")
+		}
+		if new(big.Int).Mod(new(big.Int).Mul(blob.Iqmp, blob.Q), blob.P).Cmp(big.NewInt(1)) != 0 {
+			t.Error("iqmp is not q^-1 mod p")
+		}
+	case *ecdsa.PrivateKey:
+		var blob struct {
+			D    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &blob); err != nil {
+			t.Fatalf("unmarshal ecdsa blob: %v", err)
+		}
+		if blob.D.Cmp(k.D) != 0 {
+			t.Error("PPK holds a different ECDSA key")
+		}
+	}
+}
+
+func TestMarshalPPK(t *testing.T) {
+	t.Parallel()
+
+	for name, key := range testKeys(t) {
+		for _, version := range []int{PPKVersion2, PPKVersion3} {
+			for _, pass := range []string{"", "correct horse"} {
+				t.Run(name+"/v"+strconv.Itoa(version)+"/"+strconv.Quote(pass), func(t *testing.T) {
+					t.Parallel()
+					data, err := MarshalPPK(key, "vanity@test", PPKOptions{Passphrase: []byte(pass), Version: version})
+					if err != nil {
+						t.Fatalf("MarshalPPK: %v", err)
+					}
+					f := parsePPK(t, data)
+					pub, err := ssh.NewPublicKey(key.Public())
+					if err != nil {
+						t.Fatal(err)
+					}
+					if f.version != version || f.algorithm != pub.Type() || f.comment != "vanity@test" {
+						t.Errorf("header = v%d %s %q", f.version, f.algorithm, f.comment)
+					}
+					if !bytes.Equal(f.public, pub.Marshal()) {
+						t.Error("Public-Lines do not hold the public key")
+					}
+					wantEnc := "none"
+					if pass != "" {
+						wantEnc = "aes256-cbc"
+					}
+					if f.encryption != wantEnc {
+						t.Errorf("Encryption = %q, want %q", f.encryption, wantEnc)
+					}
+					checkPPKKey(t, decryptPPK(t, f, []byte(pass)), key)
+				})
+			}
+		}
+	}
+}
+
+func TestMarshalPPK_Argon2Headers(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalPPK(key, "", PPKOptions{Passphrase: []byte("pw")})
+	if err != nil {
+		t.Fatalf("MarshalPPK: %v", err)
+	}
+	f := parsePPK(t, data)
+	want := map[string]string{
+		"Key-Derivation":     "Argon2id",
+		"Argon2-Memory":      "8192",
+		"Argon2-Passes":      strconv.Itoa(ppkArgon2Passes),
+		"Argon2-Parallelism": "1",
+	}
+	for h, v := range want {
+		if f.headers[h] != v {
+			t.Errorf("%s = %q, want %q", h, f.headers[h], v)
+		}
+	}
+	if len(f.headers["Argon2-Salt"]) != 2*ppkArgon2SaltLen {
+		t.Errorf("Argon2-Salt = %q, want %d hex bytes", f.headers["Argon2-Salt"], ppkArgon2SaltLen)
+	}
+	// The headers must come in PuTTY's order, between the two sections.
+	order := []string{"Public-Lines", "Key-Derivation", "Argon2-Memory", "Argon2-Passes", "Argon2-Parallelism", "Argon2-Salt", "Private-Lines", "Private-MAC"}
+	last := -1
+	for _, h := range order {
+		i := bytes.Index(data, []byte("\n"+h+": "))
+		if i <= last {
+			t.Errorf("%s out of order", h)
+		}
+		last = i
+	}
+}
+
+func TestMarshalPPK_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarshalPPK(key, "", PPKOptions{Version: 4}); err == nil {
+		t.Error("version 4: want error")
+	}
+	if _, err := MarshalPPK("not a key", "", PPKOptions{}); err == nil {
+		t.Error("unsupported key: want error")
+	}
+}
+
+func TestPPKPrivateBlob_Ed25519TrimsHighZeroBytes(t *testing.T) {
+	t.Parallel()
+
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0], seed[1] = 1, 2
+	blob, err := ppkPrivateBlob(ed25519.NewKeyFromSeed(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0, 0, 0, 2, 1, 2}; !bytes.Equal(blob, want) {
+		t.Errorf("blob = %x, want %x", blob, want)
+	}
+}
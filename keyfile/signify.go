@@ -0,0 +1,70 @@
+package keyfile
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// SignifyDefaultRounds is signify's bcrypt_pbkdf round count for encrypted
+// secret keys.
+const SignifyDefaultRounds = 42
+
+const (
+	signifyAlgorithm = "Ed"
+	signifyKDF       = "BK"
+	signifySaltLen   = 16
+	signifyCheckLen  = 8
+)
+
+// SignifyPublicKey returns the signify public key file of pub with key
+// number keyNum, as signify -G writes it.
+func SignifyPublicKey(pub ed25519.PublicKey, keyNum [8]byte) []byte {
+	blob := append(append([]byte(signifyAlgorithm), keyNum[:]...), pub...)
+	return signifyFile("signify public key", blob)
+}
+
+// SignifySecretKey returns the signify secret key file of key with key
+// number keyNum. A non-empty passphrase encrypts it with rounds of
+// bcrypt_pbkdf (SignifyDefaultRounds when zero), as signify -G does;
+// otherwise it is stored in the clear, as with signify -G -n.
+func SignifySecretKey(key ed25519.PrivateKey, keyNum [8]byte, passphrase []byte, rounds int) ([]byte, error) {
+	if len(passphrase) == 0 {
+		rounds = 0
+	} else if rounds == 0 {
+		rounds = SignifyDefaultRounds
+	}
+	salt := make([]byte, signifySaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	check := sha512.Sum512(key)
+	secret := []byte(key)
+	if rounds > 0 {
+		stream, err := bcryptPBKDF(passphrase, salt, rounds, ed25519.PrivateKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("derive key: %w", err)
+		}
+		secret = make([]byte, ed25519.PrivateKeySize)
+		for i := range secret {
+			secret[i] = key[i] ^ stream[i]
+		}
+	}
+
+	blob := append([]byte(signifyAlgorithm), signifyKDF...)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(rounds))
+	blob = append(blob, salt...)
+	blob = append(blob, check[:signifyCheckLen]...)
+	blob = append(blob, keyNum[:]...)
+	blob = append(blob, secret...)
+	return signifyFile("signify secret key", blob), nil
+}
+
+// signifyFile returns the two-line key file format minisign and signify
+// share: an untrusted comment and the base64 blob.
+func signifyFile(comment string, blob []byte) []byte {
+	return []byte("untrusted comment: " + comment + "\n" + base64.StdEncoding.EncodeToString(blob) + "\n")
+}
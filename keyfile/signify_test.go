@@ -0,0 +1,103 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// signifyBlob splits a minisign or signify key file into its comment and
+// decoded blob.
+func signifyBlob(t *testing.T, file []byte) (string, []byte) {
+	t.Helper()
+	lines := strings.Split(string(file), "\n")
+	if len(lines) != 3 || lines[2] != "" {
+		t.Fatalf("key file is not two lines: %q", file)
+	}
+	comment, ok := strings.CutPrefix(lines[0], "untrusted comment: ")
+	if !ok {
+		t.Fatalf("first line %q is not an untrusted comment", lines[0])
+	}
+	blob, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		t.Fatalf("decode %q: %v", lines[1], err)
+	}
+	return comment, blob
+}
+
+func TestSignifyPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyNum := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	comment, blob := signifyBlob(t, SignifyPublicKey(pub, keyNum))
+	if comment != "signify public key" {
+		t.Errorf("comment = %q", comment)
+	}
+	if want := append(append([]byte("Ed"), keyNum[:]...), pub...); !bytes.Equal(blob, want) {
+		t.Errorf("blob = %x, want %x", blob, want)
+	}
+}
+
+func TestSignifySecretKey(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyNum := [8]byte{8, 7, 6, 5, 4, 3, 2, 1}
+	for _, tt := range []struct {
+		name       string
+		passphrase []byte
+		rounds     int
+		wantRounds uint32
+	}{
+		{"clear", nil, 0, 0},
+		{"clear ignores rounds", nil, 16, 0},
+		{"encrypted", []byte("hunter2"), 0, SignifyDefaultRounds},
+		{"encrypted rounds", []byte("hunter2"), 4, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file, err := SignifySecretKey(key, keyNum, tt.passphrase, tt.rounds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comment, blob := signifyBlob(t, file)
+			if comment != "signify secret key" || len(blob) != 104 {
+				t.Fatalf("comment %q, blob of %d bytes", comment, len(blob))
+			}
+			if string(blob[:4]) != "EdBK" {
+				t.Errorf("algorithms %q, want EdBK", blob[:4])
+			}
+			rounds := binary.BigEndian.Uint32(blob[4:])
+			if rounds != tt.wantRounds {
+				t.Errorf("rounds = %d, want %d", rounds, tt.wantRounds)
+			}
+			salt, check, num, secret := blob[8:24], blob[24:32], blob[32:40], bytes.Clone(blob[40:])
+			if rounds > 0 {
+				stream, err := bcryptPBKDF(tt.passphrase, salt, int(rounds), len(secret))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range secret {
+					secret[i] ^= stream[i]
+				}
+			}
+			sum := sha512.Sum512(secret)
+			if !bytes.Equal(secret, key) || !bytes.Equal(check, sum[:8]) || !bytes.Equal(num, keyNum[:]) {
+				t.Errorf("secret key does not decrypt to the key, key number and checksum")
+			}
+		})
+	}
+}
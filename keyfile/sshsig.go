@@ -0,0 +1,119 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrBadSignature is returned by VerifySSHSIG for a signature that does not
+// verify.
+var ErrBadSignature = errors.New("SSH signature does not verify")
+
+const (
+	sshsigMagic   = "SSHSIG"
+	sshsigVersion = 1
+	sshsigHash    = "sha512"
+	sshsigPEMType = "SSH SIGNATURE"
+)
+
+// sshsigBlob is the SSHSIG signature (PROTOCOL.sshsig) after the magic
+// preamble.
+type sshsigBlob struct {
+	Version   uint32
+	PublicKey []byte
+	Namespace string
+	Reserved  string
+	HashAlg   string
+	Signature []byte
+}
+
+// sshsigSignedData returns the data an SSHSIG signature covers: the
+// preamble, namespace and hash of message.
+func sshsigSignedData(namespace string, message []byte) []byte {
+	h := sha512.Sum512(message)
+	return append([]byte(sshsigMagic), ssh.Marshal(struct {
+		Namespace, Reserved, HashAlg string
+		Hash                         []byte
+	}{namespace, "", sshsigHash, h[:]})...)
+}
+
+// SignSSHSIG signs message for namespace and returns the armored signature,
+// as ssh-keygen -Y sign -n namespace writes it. RSA keys sign with
+// rsa-sha2-512.
+func SignSSHSIG(signer ssh.Signer, namespace string, message []byte) ([]byte, error) {
+	data := sshsigSignedData(namespace, message)
+	var sig *ssh.Signature
+	var err error
+	if as, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		sig, err = as.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = signer.Sign(rand.Reader, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	blob := append([]byte(sshsigMagic), ssh.Marshal(sshsigBlob{
+		Version:   sshsigVersion,
+		PublicKey: signer.PublicKey().Marshal(),
+		Namespace: namespace,
+		HashAlg:   sshsigHash,
+		Signature: ssh.Marshal(*sig),
+	})...)
+	return armor(blob), nil
+}
+
+// VerifySSHSIG checks that the armored signature is pub's signature of
+// message for namespace.
+func VerifySSHSIG(pub ssh.PublicKey, namespace string, message, armored []byte) error {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != sshsigPEMType {
+		return fmt.Errorf("%w: not an armored SSH signature", ErrBadSignature)
+	}
+	rest, ok := bytes.CutPrefix(block.Bytes, []byte(sshsigMagic))
+	if !ok {
+		return fmt.Errorf("%w: bad magic", ErrBadSignature)
+	}
+	var blob sshsigBlob
+	if err := ssh.Unmarshal(rest, &blob); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	switch {
+	case blob.Version != sshsigVersion:
+		return fmt.Errorf("%w: version %d", ErrBadSignature, blob.Version)
+	case blob.Namespace != namespace:
+		return fmt.Errorf("%w: namespace %q, want %q", ErrBadSignature, blob.Namespace, namespace)
+	case blob.HashAlg != sshsigHash:
+		return fmt.Errorf("%w: hash %q", ErrBadSignature, blob.HashAlg)
+	case !bytes.Equal(blob.PublicKey, pub.Marshal()):
+		return fmt.Errorf("%w: signed by another key", ErrBadSignature)
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(blob.Signature, &sig); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	if err := pub.Verify(sshsigSignedData(namespace, message), &sig); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	return nil
+}
+
+// armor wraps blob in SSH SIGNATURE armor with ssh-keygen's 70-column
+// lines.
+func armor(blob []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("-----BEGIN " + sshsigPEMType + "-----\n")
+	enc := base64.StdEncoding.EncodeToString(blob)
+	for len(enc) > 70 {
+		b.WriteString(enc[:70] + "\n")
+		enc = enc[70:]
+	}
+	b.WriteString(enc + "\n-----END " + sshsigPEMType + "-----\n")
+	return b.Bytes()
+}
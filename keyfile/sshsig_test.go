@@ -0,0 +1,152 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshsigSigners returns a signer of every key type vanityssh generates.
+func sshsigSigners(t *testing.T) map[string]ssh.Signer {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := map[string]ssh.Signer{}
+	for name, key := range map[string]any{"ed25519": edKey, "rsa": rsaKey, "ecdsa": ecKey} {
+		s, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers[name] = s
+	}
+	return signers
+}
+
+func TestSSHSIG_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	msg := []byte("vanityssh self-test\n")
+	for name, s := range sshsigSigners(t) {
+		sig, err := SignSSHSIG(s, "git", msg)
+		if err != nil {
+			t.Fatalf("%s: SignSSHSIG: %v", name, err)
+		}
+		if !bytes.HasPrefix(sig, []byte("-----BEGIN SSH SIGNATURE-----\n")) {
+			t.Errorf("%s: signature armor:\n%s", name, sig)
+		}
+		if err := VerifySSHSIG(s.PublicKey(), "git", msg, sig); err != nil {
+			t.Errorf("%s: VerifySSHSIG: %v", name, err)
+		}
+		if err := VerifySSHSIG(s.PublicKey(), "file", msg, sig); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: other namespace: err = %v, want ErrBadSignature", name, err)
+		}
+		if err := VerifySSHSIG(s.PublicKey(), "git", []byte("tampered"), sig); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: other message: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+}
+
+func TestSSHSIG_WrongKey(t *testing.T) {
+	t.Parallel()
+
+	signers := sshsigSigners(t)
+	sig, err := SignSSHSIG(signers["ed25519"], "git", []byte("m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySSHSIG(signers["rsa"].PublicKey(), "git", []byte("m"), sig); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("err = %v, want ErrBadSignature", err)
+	}
+	if err := VerifySSHSIG(signers["rsa"].PublicKey(), "git", []byte("m"), []byte("junk")); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("junk: err = %v, want ErrBadSignature", err)
+	}
+}
+
+// TestSSHSIG_SSHKeygen cross-checks signatures with ssh-keygen -Y when it
+// is installed.
+func TestSSHSIG_SSHKeygen(t *testing.T) {
+	t.Parallel()
+
+	keygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	dir := t.TempDir()
+	msg := []byte("vanityssh self-test\n")
+	msgPath := filepath.Join(dir, "msg")
+	if err := os.WriteFile(msgPath, msg, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range sshsigSigners(t) {
+		sig, err := SignSSHSIG(s, "git", msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers := filepath.Join(dir, name+".allowed")
+		line := "me@example.com " + string(ssh.MarshalAuthorizedKey(s.PublicKey()))
+		sigPath := filepath.Join(dir, name+".sig")
+		if err := os.WriteFile(signers, []byte(line), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(sigPath, sig, 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(keygen, "-Y", "verify", "-f", signers, "-I", "me@example.com", "-n", "git", "-s", sigPath)
+		cmd.Stdin = bytes.NewReader(msg)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s: ssh-keygen -Y verify: %v\n%s", name, err, out)
+		}
+	}
+}
+
+func TestVerifySSHSIG_SSHKeygenSignature(t *testing.T) {
+	t.Parallel()
+
+	keygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if out, err := exec.Command(keygen, "-q", "-t", "ed25519", "-N", "", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v\n%s", err, out)
+	}
+	cmd := exec.Command(keygen, "-Y", "sign", "-f", keyPath, "-n", "git")
+	cmd.Stdin = strings.NewReader("hello\n")
+	sig, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("ssh-keygen -Y sign: %v", err)
+	}
+	pubLine, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(pubLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySSHSIG(pub, "git", []byte("hello\n"), sig); err != nil {
+		t.Errorf("VerifySSHSIG(ssh-keygen signature): %v", err)
+	}
+}
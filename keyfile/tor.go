@@ -0,0 +1,30 @@
+package keyfile
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+)
+
+// Tor's key files start with a 32-byte header naming their contents,
+// padded with NULs.
+const (
+	torSecretKeyHeader = "== ed25519v1-secret: type0 ==\x00\x00\x00"
+	torPublicKeyHeader = "== ed25519v1-public: type0 ==\x00\x00\x00"
+)
+
+// TorSecretKey returns the hs_ed25519_secret_key file of an onion service
+// with key: the header and Tor's 64-byte expanded key, the clamped scalar
+// and PRF key that SHA-512 derives from the seed.
+func TorSecretKey(key ed25519.PrivateKey) []byte {
+	h := sha512.Sum512(key.Seed())
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	return append([]byte(torSecretKeyHeader), h[:]...)
+}
+
+// TorPublicKey returns the hs_ed25519_public_key file of an onion service
+// with key pub.
+func TorPublicKey(pub ed25519.PublicKey) []byte {
+	return append([]byte(torPublicKeyHeader), pub...)
+}
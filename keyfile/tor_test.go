@@ -0,0 +1,67 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"math/big"
+	"slices"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// montgomeryU maps an ed25519 public key to the u-coordinate of the same
+// point on Curve25519: u = (1+y)/(1-y) mod p.
+func montgomeryU(pub ed25519.PublicKey) []byte {
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	le := slices.Clone(pub)
+	le[31] &= 0x7f
+	slices.Reverse(le)
+	y := new(big.Int).SetBytes(le)
+	num := new(big.Int).Add(big.NewInt(1), y)
+	den := new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, p)
+	u := num.Mul(num, den.ModInverse(den, p))
+	u.Mod(u, p)
+	out := u.FillBytes(make([]byte, 32))
+	slices.Reverse(out)
+	return out
+}
+
+func TestTorSecretKey(t *testing.T) {
+	t.Parallel()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := TorSecretKey(priv)
+	if len(got) != 96 || !bytes.HasPrefix(got, []byte("== ed25519v1-secret: type0 ==\x00\x00\x00")) {
+		t.Fatalf("secret key file = %q", got)
+	}
+	scalar := got[32:64]
+	if scalar[0]&7 != 0 || scalar[31]&0xc0 != 0x40 {
+		t.Errorf("scalar is not clamped: %x", scalar)
+	}
+	// The scalar must be the one behind pub: compare on Curve25519.
+	u, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := montgomeryU(pub); !bytes.Equal(u, want) {
+		t.Errorf("scalar gives u = %x, public key has %x", u, want)
+	}
+}
+
+func TestTorPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := TorPublicKey(pub)
+	if len(got) != 64 || !bytes.HasPrefix(got, []byte("== ed25519v1-public: type0 ==\x00\x00\x00")) || !bytes.Equal(got[32:], pub) {
+		t.Errorf("public key file = %q", got)
+	}
+}
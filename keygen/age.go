@@ -0,0 +1,64 @@
+package keygen
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// KeyTypeAge generates X25519 identities for age, matched on their
+// recipient.
+const KeyTypeAge KeyType = "age"
+
+const (
+	ageRecipientHRP = "age"
+	ageIdentityHRP  = "age-secret-key-"
+	ageKeySize      = 32
+)
+
+// ageLayout is the 62-character recipient: "age1", the bech32 data of the
+// public key and its six checksum characters. The last data character
+// carries one key bit and four padding bits, so it is always q or s.
+var ageLayout = Layout{
+	Prefix:   ageRecipientHRP + "1",
+	RawLen:   ageKeySize,
+	Encoding: Bech32Encoding,
+	Tail:     6,
+	Checksum: bech32Tail(ageRecipientHRP, (ageKeySize*8+4)/5),
+}
+
+func init() {
+	sourceTypes[KeyTypeAge] = sourceType{
+		layouts:   map[Input]Layout{InputPublicKey: ageLayout},
+		newSource: func(Input) keySource { return &ageSource{} },
+	}
+}
+
+// ageSource generates X25519 identities, as age-keygen does.
+type ageSource struct {
+	priv *ecdh.PrivateKey
+}
+
+func (s *ageSource) next(dst []byte) error {
+	var err error
+	if s.priv, err = ecdh.X25519().GenerateKey(rand.Reader); err != nil {
+		return fmt.Errorf("generate x25519 key: %w", err)
+	}
+	copy(dst, s.priv.PublicKey().Bytes())
+	return nil
+}
+
+func (s *ageSource) result() (Result, error) {
+	return Result{PrivateKey: s.priv, AuthorizedKey: AgeRecipient(s.priv.PublicKey())}, nil
+}
+
+// AgeRecipient returns the age recipient ("age1...") of pub.
+func AgeRecipient(pub *ecdh.PublicKey) string {
+	return bech32Encode(ageRecipientHRP, pub.Bytes())
+}
+
+// AgeIdentity returns the age identity ("AGE-SECRET-KEY-1...") of priv.
+func AgeIdentity(priv *ecdh.PrivateKey) string {
+	return strings.ToUpper(bech32Encode(ageIdentityHRP, priv.Bytes()))
+}
@@ -0,0 +1,80 @@
+package keygen
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAgeRecipientAndIdentity(t *testing.T) {
+	t.Parallel()
+
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := AgeRecipient(priv.PublicKey())
+	if len(recipient) != ageLayout.TextLen() || !strings.HasPrefix(recipient, "age1") || !bech32Valid(recipient) {
+		t.Errorf("recipient %q: want %d characters of valid bech32", recipient, ageLayout.TextLen())
+	}
+	if got := bech32Bytes(recipient); !bytes.Equal(got, priv.PublicKey().Bytes()) {
+		t.Errorf("recipient holds %x, want the public key %x", got, priv.PublicKey().Bytes())
+	}
+
+	identity := AgeIdentity(priv)
+	lower := strings.ToLower(identity)
+	if !strings.HasPrefix(identity, "AGE-SECRET-KEY-1") || identity != strings.ToUpper(identity) || !bech32Valid(lower) {
+		t.Errorf("identity %q is not uppercase bech32", identity)
+	}
+	if got := bech32Bytes(lower); !bytes.Equal(got, priv.Bytes()) {
+		t.Errorf("identity holds %x, want the private key", got)
+	}
+}
+
+func TestAgeLayoutEncoder(t *testing.T) {
+	t.Parallel()
+
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := make([]byte, ageLayout.TextLen()-len(ageLayout.Prefix))
+	ageLayout.encoder()(text, priv.PublicKey().Bytes())
+	if got, want := ageLayout.Prefix+string(text), AgeRecipient(priv.PublicKey()); got != want {
+		t.Errorf("encoder = %q, want %q", got, want)
+	}
+}
+
+func TestFindKeys_Age(t *testing.T) {
+	t.Parallel()
+
+	// Anchored suffixes reach into the checksum and are matched on the text.
+	for _, pattern := range []string{`^age1ac`, `(?i)^AGE1Z`, `[0-9]$`, `x7`} {
+		re := regexp.MustCompile(pattern)
+		m, err := NewMatcher(re, InputPublicKey)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		errc := make(chan error, 1)
+		go func() { errc <- FindKeys(ctx, Options{Matcher: m, Type: KeyTypeAge}, results) }()
+		r := <-results
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatalf("FindKeys: %v", err)
+		}
+
+		priv, ok := r.PrivateKey.(*ecdh.PrivateKey)
+		if !ok {
+			t.Fatalf("PrivateKey is %T, want *ecdh.PrivateKey", r.PrivateKey)
+		}
+		if want := AgeRecipient(priv.PublicKey()); r.AuthorizedKey != want || !re.MatchString(want) {
+			t.Errorf("%q: AuthorizedKey = %q, want matching %q", pattern, r.AuthorizedKey, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package keygen
+
+// ahoCorasick is a byte-level Aho-Corasick automaton compiled to a full DFA,
+// so scanning a candidate costs one table lookup per byte regardless of how
+// many literals it holds.
+type ahoCorasick struct {
+	delta [][256]int32 // state transitions
+	out   [][]int32    // literal ids ending at each state
+	lens  []int        // length of each literal, by id
+}
+
+// newAhoCorasick builds an automaton over lits. Literal i is reported with
+// id i. When fold is set, lits must be lowercase and scan lowercases input.
+func newAhoCorasick(lits [][]byte) *ahoCorasick {
+	ac := &ahoCorasick{
+		delta: make([][256]int32, 1),
+		out:   make([][]int32, 1),
+		lens:  make([]int, len(lits)),
+	}
+
+	// Build the trie; 0 doubles as "no edge" since the root is never a child.
+	for id, lit := range lits {
+		ac.lens[id] = len(lit)
+		s := int32(0)
+		for _, c := range lit {
+			if ac.delta[s][c] == 0 {
+				ac.delta = append(ac.delta, [256]int32{})
+				ac.out = append(ac.out, nil)
+				ac.delta[s][c] = int32(len(ac.delta) - 1)
+			}
+			s = ac.delta[s][c]
+		}
+		ac.out[s] = append(ac.out[s], int32(id))
+	}
+
+	// Breadth-first: compute failure links and fill missing edges with the
+	// failure state's edges, turning the trie into a DFA.
+	fail := make([]int32, len(ac.delta))
+	var queue []int32
+	for c := range 256 {
+		if s := ac.delta[0][c]; s != 0 {
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		ac.out[s] = append(ac.out[s], ac.out[fail[s]]...)
+		for c := range 256 {
+			next := ac.delta[s][c]
+			if next == 0 {
+				ac.delta[s][c] = ac.delta[fail[s]][c]
+				continue
+			}
+			fail[next] = ac.delta[fail[s]][c]
+			queue = append(queue, next)
+		}
+	}
+	return ac
+}
+
+// scan feeds text through the automaton, calling hit with the id and end
+// offset of every literal occurrence. Scanning stops early when hit returns
+// true; scan reports whether it did.
+func (ac *ahoCorasick) scan(text []byte, fold bool, hit func(id, end int) bool) bool {
+	s := int32(0)
+	for i, c := range text {
+		if fold {
+			c = lower(c)
+		}
+		s = ac.delta[s][c]
+		for _, id := range ac.out[s] {
+			if hit(int(id), i+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
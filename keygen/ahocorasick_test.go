@@ -0,0 +1,70 @@
+package keygen
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestAhoCorasick_FindsAllOccurrences(t *testing.T) {
+	t.Parallel()
+
+	lits := [][]byte{[]byte("he"), []byte("she"), []byte("his"), []byte("hers")}
+	ac := newAhoCorasick(lits)
+
+	type hit struct{ id, end int }
+	var got []hit
+	ac.scan([]byte("ushers"), false, func(id, end int) bool {
+		got = append(got, hit{id, end})
+		return false
+	})
+
+	want := []hit{{1, 4}, {0, 4}, {3, 6}}
+	slices.SortFunc(got, func(a, b hit) int { return a.end*10 + a.id - b.end*10 - b.id })
+	slices.SortFunc(want, func(a, b hit) int { return a.end*10 + a.id - b.end*10 - b.id })
+	if !slices.Equal(got, want) {
+		t.Errorf("hits = %v, want %v", got, want)
+	}
+}
+
+func TestAhoCorasick_Fold(t *testing.T) {
+	t.Parallel()
+
+	ac := newAhoCorasick([][]byte{[]byte("abc")})
+	var found bool
+	ac.scan([]byte("xxABcx"), true, func(id, end int) bool {
+		found = id == 0 && end == 5
+		return true
+	})
+	if !found {
+		t.Error("folded scan did not find ABc")
+	}
+	if ac.scan([]byte("xxABcx"), false, func(int, int) bool { return true }) {
+		t.Error("exact scan found ABc")
+	}
+}
+
+func TestAhoCorasick_MatchesNaiveSearch(t *testing.T) {
+	t.Parallel()
+
+	lits := [][]byte{[]byte("a"), []byte("aa"), []byte("aab"), []byte("ba"), []byte("bab")}
+	ac := newAhoCorasick(lits)
+	text := []byte("aababbaabaaab")
+
+	counts := make([]int, len(lits))
+	ac.scan(text, false, func(id, _ int) bool {
+		counts[id]++
+		return false
+	})
+	for id, lit := range lits {
+		var want int
+		for i := range text {
+			if bytes.HasPrefix(text[i:], lit) {
+				want++
+			}
+		}
+		if counts[id] != want {
+			t.Errorf("literal %q: %d hits, want %d", lit, counts[id], want)
+		}
+	}
+}
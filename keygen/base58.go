@@ -0,0 +1,27 @@
+package keygen
+
+// base58Encode writes the base58btc text of src into dst, which must be as
+// long as the text: every leading zero byte of src is a leading '1', and
+// so is every unused leading character of dst.
+func base58Encode(dst, src []byte) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	// dst holds the digits of the number read so far, most significant
+	// first; each byte multiplies it by 256 and adds itself. Digits above
+	// high are still zero and need no work until a carry reaches them.
+	high := len(dst) - 1
+	for _, b := range src {
+		carry := uint32(b)
+		i := len(dst) - 1
+		for ; i >= 0 && (i > high || carry != 0); i-- {
+			carry += uint32(dst[i]) << 8
+			dst[i] = byte(carry % 58)
+			carry /= 58
+		}
+		high = i
+	}
+	for i, v := range dst {
+		dst[i] = base58Alphabet[v]
+	}
+}
@@ -0,0 +1,35 @@
+package keygen
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBase58Encode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		hex  string
+		want string
+	}{
+		{"", ""},
+		{"00", "1"},
+		{"61", "2g"},
+		{"626262", "a3gV"},
+		{"516b6fcd0f", "ABnLTmg"},
+		{"48656c6c6f20576f726c6421", "2NEpo7TZRRrLZSi2U"},
+		{"00000000287fb4cd", "111233QC4"},
+		{"ffffffffff", "VtB5VXc"},
+	}
+	for _, tt := range tests {
+		src, err := hex.DecodeString(tt.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := make([]byte, len(tt.want))
+		base58Encode(dst, src)
+		if string(dst) != tt.want {
+			t.Errorf("base58Encode(%s) = %q, want %q", tt.hex, dst, tt.want)
+		}
+	}
+}
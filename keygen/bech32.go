@@ -0,0 +1,77 @@
+package keygen
+
+import "strings"
+
+// bech32Generator holds the BCH code generator constants of BIP 173.
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// bech32Polymod feeds the 5-bit value v into the checksum state chk.
+func bech32Polymod(chk uint32, v byte) uint32 {
+	top := chk >> 25
+	chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+	for i, g := range bech32Generator {
+		if top>>i&1 == 1 {
+			chk ^= g
+		}
+	}
+	return chk
+}
+
+// bech32Checksum returns the six checksum values of the 5-bit data values
+// under the lowercase human-readable part hrp.
+func bech32Checksum(hrp string, data []byte) [6]byte {
+	chk := uint32(1)
+	for i := range len(hrp) {
+		chk = bech32Polymod(chk, hrp[i]>>5)
+	}
+	chk = bech32Polymod(chk, 0)
+	for i := range len(hrp) {
+		chk = bech32Polymod(chk, hrp[i]&31)
+	}
+	for _, v := range data {
+		chk = bech32Polymod(chk, v)
+	}
+	for range 6 {
+		chk = bech32Polymod(chk, 0)
+	}
+	chk ^= 1
+	var sum [6]byte
+	for i := range sum {
+		sum[i] = byte(chk>>(5*(5-i))) & 31
+	}
+	return sum
+}
+
+// bech32Encode returns the lowercase bech32 string of raw under hrp. Unlike
+// BIP 173 it sets no length limit, as age does.
+func bech32Encode(hrp string, raw []byte) string {
+	l := Layout{RawLen: len(raw), Encoding: Bech32Encoding}
+	data := make([]byte, l.dataLen())
+	for d := range data {
+		data[d] = digitAt(raw, d, 5)
+	}
+	var b strings.Builder
+	b.WriteString(hrp + "1")
+	for _, v := range data {
+		b.WriteByte(bech32Alphabet[v])
+	}
+	for _, v := range bech32Checksum(hrp, data) {
+		b.WriteByte(bech32Alphabet[v])
+	}
+	return b.String()
+}
+
+// bech32Tail returns a Layout.Checksum writing the bech32 checksum under
+// hrp of raw bytes that encode to dataLen characters.
+func bech32Tail(hrp string, dataLen int) func(raw, dst []byte) {
+	return func(raw, dst []byte) {
+		var buf [128]byte
+		data := buf[:dataLen]
+		for d := range data {
+			data[d] = digitAt(raw, d, 5)
+		}
+		for i, v := range bech32Checksum(hrp, data) {
+			dst[i] = bech32Alphabet[v]
+		}
+	}
+}
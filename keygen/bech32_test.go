@@ -0,0 +1,82 @@
+package keygen
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+// bech32Valid reports whether s is a valid lowercase bech32 string, by
+// recomputing its checksum.
+func bech32Valid(s string) bool {
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || len(s)-sep-1 < 6 {
+		return false
+	}
+	var values []byte
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Alphabet, s[i])
+		if v < 0 {
+			return false
+		}
+		values = append(values, byte(v))
+	}
+	data, sum := values[:len(values)-6], values[len(values)-6:]
+	want := bech32Checksum(s[:sep], data)
+	return bytes.Equal(sum, want[:])
+}
+
+// bech32Bytes decodes the data of a bech32 string to 8-bit bytes,
+// dropping the padding bits.
+func bech32Bytes(s string) []byte {
+	data := s[strings.LastIndexByte(s, '1')+1 : len(s)-6]
+	var out []byte
+	var acc, n uint
+	for i := range len(data) {
+		acc = acc<<5 | uint(strings.IndexByte(bech32Alphabet, data[i]))
+		n += 5
+		if n >= 8 {
+			n -= 8
+			out = append(out, byte(acc>>n))
+		}
+	}
+	return out
+}
+
+func TestBech32Checksum_BIP173(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11" + strings.Repeat("q", 82) + "c8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	} {
+		if !bech32Valid(s) {
+			t.Errorf("valid BIP 173 string %q fails the checksum", s)
+		}
+	}
+	if bech32Valid("abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx") {
+		t.Error("corrupted string passes the checksum")
+	}
+}
+
+func TestBech32Encode(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 1, 5, 32, 64} {
+		raw := make([]byte, n)
+		if _, err := rand.Read(raw); err != nil {
+			t.Fatalf("rand.Read: %v", err)
+		}
+		s := bech32Encode("test", raw)
+		if !strings.HasPrefix(s, "test1") || !bech32Valid(s) {
+			t.Errorf("len %d: %q is not valid bech32", n, s)
+		}
+		if got := bech32Bytes(s); !bytes.Equal(got, raw) && n > 0 {
+			t.Errorf("len %d: decodes to %x, want %x", n, got, raw)
+		}
+	}
+}
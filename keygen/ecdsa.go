@@ -0,0 +1,69 @@
+package keygen
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+)
+
+// ECDSA key types, one per NIST curve supported by OpenSSH.
+const (
+	KeyTypeECDSAP256 KeyType = "ecdsa-p256"
+	KeyTypeECDSAP384 KeyType = "ecdsa-p384"
+	KeyTypeECDSAP521 KeyType = "ecdsa-p521"
+)
+
+// ecdsaCurve pairs a curve with its SSH wire format.
+type ecdsaCurve struct {
+	curve elliptic.Curve
+	wire  wireFormat
+}
+
+// ecdsaCurves maps each ECDSA key type to its curve and SSH wire format:
+// string("ecdsa-sha2-nistpNNN") + string("nistpNNN") + string(0x04 || X || Y).
+// The uncompressed point tag 0x04 is part of the constant header.
+var ecdsaCurves = map[KeyType]ecdsaCurve{
+	KeyTypeECDSAP256: newECDSACurve(elliptic.P256(), "nistp256"),
+	KeyTypeECDSAP384: newECDSACurve(elliptic.P384(), "nistp384"),
+	KeyTypeECDSAP521: newECDSACurve(elliptic.P521(), "nistp521"),
+}
+
+func newECDSACurve(curve elliptic.Curve, name string) ecdsaCurve {
+	bitSize := curve.Params().BitSize
+	coordLen := (bitSize + 7) / 8
+	wire := newWireFormat("ecdsa-sha2-"+name, 2*coordLen,
+		appendString(nil, name), uint32Bytes(uint32(1+2*coordLen)), []byte{0x04})
+	// Coordinates are below the field prime, so on P-521 the top 7 bits of
+	// each are always zero.
+	if spare := coordLen*8 - bitSize; spare > 0 {
+		mask := byte(0xff) << (8 - spare)
+		wire.fixed = []FixedBits{{Index: 0, Mask: mask}, {Index: coordLen, Mask: mask}}
+	}
+	return ecdsaCurve{curve: curve, wire: wire}
+}
+
+// ecdsaSource generates ECDSA key pairs on one curve.
+type ecdsaSource struct {
+	curve elliptic.Curve
+	priv  *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSource) next(dst []byte) error {
+	var err error
+	s.priv, err = ecdsa.GenerateKey(s.curve, rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate ecdsa key: %w", err)
+	}
+	point, err := s.priv.PublicKey.Bytes()
+	if err != nil {
+		return fmt.Errorf("encode ecdsa public key: %w", err)
+	}
+	copy(dst, point[1:])
+	return nil
+}
+
+func (s *ecdsaSource) last() (crypto.PrivateKey, crypto.PublicKey) {
+	return s.priv, &s.priv.PublicKey
+}
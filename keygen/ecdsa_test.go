@@ -0,0 +1,84 @@
+package keygen
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFindKeys_ECDSA(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		kt     KeyType
+		prefix string
+		bits   int
+	}{
+		{KeyTypeECDSAP256, "ecdsa-sha2-nistp256 ", 256},
+		{KeyTypeECDSAP384, "ecdsa-sha2-nistp384 ", 384},
+		{KeyTypeECDSAP521, "ecdsa-sha2-nistp521 ", 521},
+	}
+	for _, tt := range tests {
+		for _, in := range []Input{InputPublicKey, InputFingerprint} {
+			t.Run(string(tt.kt)+"/"+in.String(), func(t *testing.T) {
+				t.Parallel()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+
+				re := regexp.MustCompile(`[0-9][A-Z]`)
+				m, err := NewMatcher(re, in)
+				if err != nil {
+					t.Fatalf("NewMatcher: %v", err)
+				}
+				results := make(chan Result, 1)
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- FindKeys(ctx, Options{Matcher: m, Type: tt.kt}, results)
+				}()
+
+				var r Result
+				select {
+				case r = <-results:
+				case <-ctx.Done():
+					t.Fatal("timed out")
+				}
+				cancel()
+				if err := <-errCh; err != nil {
+					t.Fatalf("FindKeys error: %v", err)
+				}
+
+				if !strings.HasPrefix(r.AuthorizedKey, tt.prefix) {
+					t.Errorf("AuthorizedKey = %q, want prefix %q", r.AuthorizedKey, tt.prefix)
+				}
+				if !re.MatchString(r.Text(in)) {
+					t.Errorf("%s %q does not match", in, r.Text(in))
+				}
+
+				raw, err := ssh.ParseRawPrivateKey(r.PrivateKeyPEM)
+				if err != nil {
+					t.Fatalf("ParseRawPrivateKey: %v", err)
+				}
+				key, ok := raw.(*ecdsa.PrivateKey)
+				if !ok {
+					t.Fatalf("private key type = %T, want *ecdsa.PrivateKey", raw)
+				}
+				if key.Curve.Params().BitSize != tt.bits {
+					t.Errorf("curve = %d bits, want %d", key.Curve.Params().BitSize, tt.bits)
+				}
+				pub, err := ssh.NewPublicKey(&key.PublicKey)
+				if err != nil {
+					t.Fatalf("NewPublicKey: %v", err)
+				}
+				if getAuthorizedKey(pub) != r.AuthorizedKey || getFingerprint(pub) != r.Fingerprint {
+					t.Error("private key does not match reported public key")
+				}
+			})
+		}
+	}
+}
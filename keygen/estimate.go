@@ -0,0 +1,163 @@
+package keygen
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+)
+
+// ErrTooComplex is returned by MatchProbability when the pattern needs more
+// automaton states than it is willing to track.
+var ErrTooComplex = errors.New("pattern too complex to estimate")
+
+// maxEstimateStates bounds the distinct thread sets MatchProbability tracks.
+const maxEstimateStates = 1 << 14
+
+// MatchProbability returns the probability that one random key generated
+// with o matches re in the representation in; o.Matcher is ignored. Every
+// position is modelled as uniform over the characters it can hold, as
+// CheckFeasible derives them, and the regex automaton is run over the whole
+// distribution at once, so overlapping and repeated matches are accounted
+// for exactly. Bits every key shares, such as an RSA modulus's top and low
+// bits, come from the layout; other structure, such as which points lie on
+// a curve, is not modelled.
+func MatchProbability(re *regexp.Regexp, o Options, in Input) (float64, error) {
+	if re == nil {
+		return 0, ErrNilRegex
+	}
+	l, err := o.layout(in)
+	if err != nil {
+		return 0, err
+	}
+	n, err := newNFA(re, l.alphabets())
+	if err != nil {
+		return 0, err
+	}
+	return n.probability()
+}
+
+// KeysForProbability returns how many keys must be generated for the chance
+// of at least one match to reach q when each key matches with probability p.
+// It is +Inf when p is zero.
+func KeysForProbability(p, q float64) float64 {
+	if p >= 1 {
+		return 1
+	}
+	return math.Log1p(-q) / math.Log1p(-p)
+}
+
+// CumulativeProbability returns the chance that at least one of keys
+// independent keys matches when each matches with probability p.
+func CumulativeProbability(p float64, keys int64) float64 {
+	if p >= 1 {
+		return 1
+	}
+	return -math.Expm1(float64(keys) * math.Log1p(-p))
+}
+
+// dfa lazily determinizes an nfa: each state is a set of threads, interned
+// so transitions can be memoized.
+type dfa struct {
+	*nfa
+	states [][]uint32
+	ids    map[string]int
+	trans  map[uint64]int // state<<8 | character -> next state, or matchedState
+	cur    *threads
+}
+
+// matchedState marks a transition into a match; the match is absorbing.
+const matchedState = -1
+
+// probability propagates the distribution over automaton states through the
+// text, collecting the mass that reaches a match.
+func (n *nfa) probability() (float64, error) {
+	d := &dfa{
+		nfa:   n,
+		ids:   make(map[string]int),
+		trans: make(map[uint64]int),
+		cur:   newThreads(len(n.prog.Inst)),
+	}
+	d.cur.reset()
+	n.add(d.cur, uint32(n.prog.Start), 0)
+	start, err := d.intern()
+	if err != nil || start == matchedState {
+		return 1, err
+	}
+
+	dist := map[int]float64{start: 1}
+	var matched float64
+	for pos, set := range n.sets {
+		w := 1 / float64(set.len())
+		next := make(map[int]float64, len(dist))
+		for id, pr := range dist {
+			for c := range byte(0x80) {
+				if !set.has(c) {
+					continue
+				}
+				to, err := d.step(id, c, pos)
+				if err != nil {
+					return 0, err
+				}
+				if to == matchedState {
+					matched += pr * w
+				} else {
+					next[to] += pr * w
+				}
+			}
+		}
+		dist = next
+	}
+	return math.Min(matched, 1), nil
+}
+
+// step returns the state reached from state id by reading c at pos.
+// Transitions only depend on pos through end-of-text assertions, so
+// interior positions share one memo.
+func (d *dfa) step(id int, c byte, pos int) (int, error) {
+	end := pos+1 == len(d.sets)
+	key := uint64(id)<<8 | uint64(c)
+	if !end {
+		if to, ok := d.trans[key]; ok {
+			return to, nil
+		}
+	}
+	d.cur.reset()
+	for _, pc := range d.states[id] {
+		if d.accept[pc].has(c) {
+			d.add(d.cur, d.prog.Inst[pc].Out, pos+1)
+		}
+	}
+	if !d.anchored {
+		d.add(d.cur, uint32(d.prog.Start), pos+1)
+	}
+	to, err := d.intern()
+	if err == nil && !end {
+		d.trans[key] = to
+	}
+	return to, err
+}
+
+// intern returns the id of the thread set in d.cur, or matchedState if it
+// contains a match.
+func (d *dfa) intern() (int, error) {
+	if d.matched(d.cur) {
+		return matchedState, nil
+	}
+	pcs := slices.Clone(d.cur.pcs)
+	slices.Sort(pcs)
+	key := make([]byte, 0, 4*len(pcs))
+	for _, pc := range pcs {
+		key = binary.LittleEndian.AppendUint32(key, pc)
+	}
+	if id, ok := d.ids[string(key)]; ok {
+		return id, nil
+	}
+	if len(d.states) == maxEstimateStates {
+		return 0, ErrTooComplex
+	}
+	d.ids[string(key)] = len(d.states)
+	d.states = append(d.states, pcs)
+	return len(d.states) - 1, nil
+}
@@ -0,0 +1,116 @@
+package keygen
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"testing"
+)
+
+func TestMatchProbability(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    Options
+		in      Input
+		want    float64
+	}{
+		{"constant prefix", `^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI[A-P]`, Options{}, InputPublicKey, 1},
+		{"impossible", `^ssh-ed25519 AAAB`, Options{}, InputPublicKey, 0},
+		{"folded fingerprint prefix", `(?i)^abc`, Options{}, InputFingerprint, math.Pow(2.0/64, 3)},
+		{"folded key suffix", `(?i)vanity$`, Options{}, InputPublicKey, math.Pow(2.0/64, 6)},
+		{"padding bits", `Q=$`, Options{}, InputFingerprint, 1.0 / 16},
+		{"partly fixed position", `^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA`, Options{}, InputPublicKey, 1.0 / 16},
+		// 42 free positions and a last data position of 16 characters.
+		{"unanchored", `A`, Options{}, InputFingerprint, 1 - math.Pow(63.0/64, 42)*15/16},
+		{"alternation", `^(A|B)`, Options{}, InputFingerprint, 2.0 / 64},
+		{"SSHFP digest prefix", `^c0ffee`, Options{}, InputSSHFP, math.Pow(1.0/16, 6)},
+		{"folded SSHFP digest", `(?i)^C0FFEE`, Options{}, InputSSHFP, math.Pow(1.0/16, 6)},
+		{"openpgp key ID", `DEADBEEF$`, Options{Type: KeyTypeOpenPGP}, InputFingerprint, math.Pow(1.0/16, 8)},
+		{"age prefix", `^age1dw`, Options{Type: KeyTypeAge}, InputPublicKey, math.Pow(1.0/32, 2)},
+		{"age checksum", `xx$`, Options{Type: KeyTypeAge}, InputPublicKey, math.Pow(1.0/32, 2)},
+		{"libp2p peer ID", `^12D3KooW`, Options{Type: KeyTypeLibP2P}, InputPublicKey, 1},
+		{"libp2p peer ID suffix", `xy$`, Options{Type: KeyTypeLibP2P}, InputPublicKey, math.Pow(1.0/58, 2)},
+		{"onion prefix", `^tor`, Options{Type: KeyTypeOnion}, InputPublicKey, math.Pow(1.0/32, 3)},
+		{"onion version byte", `[aq]d$`, Options{Type: KeyTypeOnion}, InputPublicKey, 1.0 / 2},
+		{"ecdsa-p384 end", `A==$`, Options{Type: KeyTypeECDSAP384}, InputPublicKey, 1.0 / 4},
+		{"rsa odd modulus", `A==$`, Options{Type: KeyTypeRSA, Bits: 4096}, InputPublicKey, 0},
+		{"rsa modulus end", `Q==$`, Options{Type: KeyTypeRSA, Bits: 4096}, InputPublicKey, 1.0 / 2},
+		{"rsa 2048 modulus end", `a$`, Options{Type: KeyTypeRSA, Bits: 2048}, InputPublicKey, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := MatchProbability(regexp.MustCompile(tt.pattern), tt.opts, tt.in)
+			if err != nil {
+				t.Fatalf("MatchProbability: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-12*math.Max(tt.want, 1e-300) && got != tt.want {
+				t.Errorf("MatchProbability(%q) = %g, want %g", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatchProbability_Overlaps checks a self-overlapping pattern against
+// a direct count: "AA" in the 43 data characters of a fingerprint, where
+// the last one is 'A' with probability 1/16.
+func TestMatchProbability_Overlaps(t *testing.T) {
+	t.Parallel()
+
+	// noA and endA are the probabilities that positions 0..i hold no "AA"
+	// and position i is not or is 'A'.
+	var noA, endA float64 = 63.0 / 64, 1.0 / 64
+	for i := 1; i < 43; i++ {
+		pA := 1.0 / 64
+		if i == 42 {
+			pA = 1.0 / 16
+		}
+		noA, endA = (noA+endA)*(1-pA), noA*pA
+	}
+	want := 1 - (noA + endA)
+
+	got, err := MatchProbability(regexp.MustCompile(`AA`), Options{}, InputFingerprint)
+	if err != nil {
+		t.Fatalf("MatchProbability: %v", err)
+	}
+	if math.Abs(got-want) > 1e-12 {
+		t.Errorf("MatchProbability(AA) = %g, want %g", got, want)
+	}
+}
+
+func TestMatchProbability_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := MatchProbability(nil, Options{}, InputPublicKey); !errors.Is(err, ErrNilRegex) {
+		t.Errorf("nil regex: err = %v, want ErrNilRegex", err)
+	}
+	// Every set of recent 'A' positions is a distinct state: 2^20 of them.
+	re := regexp.MustCompile(`A.{20}B`)
+	if _, err := MatchProbability(re, Options{}, InputPublicKey); !errors.Is(err, ErrTooComplex) {
+		t.Errorf("err = %v, want ErrTooComplex", err)
+	}
+}
+
+func TestKeysForProbability(t *testing.T) {
+	t.Parallel()
+
+	p := 1.0 / 1024
+	if got := KeysForProbability(p, 0.5); math.Abs(got-709.4) > 0.1 {
+		t.Errorf("median = %g, want ~709.4", got)
+	}
+	if got := CumulativeProbability(p, int64(math.Round(KeysForProbability(p, 0.9)))); math.Abs(got-0.9) > 1e-3 {
+		t.Errorf("CumulativeProbability(p90) = %g, want ~0.9", got)
+	}
+	if got := KeysForProbability(1, 0.9); got != 1 {
+		t.Errorf("KeysForProbability(1, 0.9) = %g, want 1", got)
+	}
+	if got := KeysForProbability(0, 0.5); !math.IsInf(got, 1) {
+		t.Errorf("KeysForProbability(0, 0.5) = %g, want +Inf", got)
+	}
+	if got := CumulativeProbability(p, 0); got != 0 {
+		t.Errorf("CumulativeProbability(p, 0) = %g, want 0", got)
+	}
+}
@@ -0,0 +1,497 @@
+package keygen
+
+import (
+	"fmt"
+	"math/bits"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// InfeasibleError reports a pattern that no key of the selected type can
+// match, because the characters it requires at some position never appear
+// there in the representation.
+type InfeasibleError struct {
+	Input  Input
+	Offset int    // 0-based position in the representation
+	Reason string // what the pattern needs at Offset and why it cannot get it
+	Hint   string // how to rewrite the pattern
+}
+
+func (e *InfeasibleError) Error() string {
+	return fmt.Sprintf("pattern can never match the %s: %s; %s", e.Input, e.Reason, e.Hint)
+}
+
+// CheckFeasible reports whether re can match the representation in of keys
+// generated with o; o.Matcher is ignored. The regex is simulated over the
+// set of characters each position can hold: the constant prefix and header,
+// the base64 alphabet, and the bits and '=' forced by padding. The analysis
+// over-approximates (word boundaries are assumed to hold), so it never
+// rejects a pattern that can match. It returns an *InfeasibleError naming
+// the offset where every attempt fails.
+func CheckFeasible(re *regexp.Regexp, o Options, in Input) error {
+	if re == nil {
+		return ErrNilRegex
+	}
+	l, err := o.layout(in)
+	if err != nil {
+		return err
+	}
+	n, err := newNFA(re, l.alphabets())
+	if err != nil {
+		return err
+	}
+	if n.feasible() {
+		return nil
+	}
+	return n.explain(n.failure(), o.keyType(), in, l)
+}
+
+// charSet is a set of ASCII characters. Every representation is ASCII.
+type charSet [2]uint64
+
+func (s *charSet) add(c byte) { s[c>>6&1] |= 1 << (c & 63) }
+
+func (s charSet) has(c byte) bool { return c < 0x80 && s[c>>6]&(1<<(c&63)) != 0 }
+
+func (s charSet) and(t charSet) charSet { return charSet{s[0] & t[0], s[1] & t[1]} }
+
+func (s charSet) or(t charSet) charSet { return charSet{s[0] | t[0], s[1] | t[1]} }
+
+func (s charSet) len() int { return bits.OnesCount64(s[0]) + bits.OnesCount64(s[1]) }
+
+// String formats s as a quoted character when it holds one, and as a
+// regexp character class otherwise.
+func (s charSet) String() string {
+	if s.len() == 1 {
+		for c := range byte(0x80) {
+			if s.has(c) {
+				return strconv.Quote(string(c))
+			}
+		}
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for c := 0; c < 0x80; c++ {
+		if !s.has(byte(c)) {
+			continue
+		}
+		end := c
+		for end+1 < 0x80 && s.has(byte(end+1)) {
+			end++
+		}
+		writeClassChar(&b, byte(c))
+		if end-c >= 2 {
+			b.WriteByte('-')
+			writeClassChar(&b, byte(end))
+			c = end
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func writeClassChar(b *strings.Builder, c byte) {
+	switch {
+	case c == '\\' || c == ']' || c == '[' || c == '^' || c == '-':
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	case c < 0x20 || c == 0x7f:
+		fmt.Fprintf(b, `\x%02x`, c)
+	default:
+		b.WriteByte(c)
+	}
+}
+
+// alphabets returns the set of characters each position of the text can
+// hold.
+func (l Layout) alphabets() []charSet {
+	sets := make([]charSet, 0, l.TextLen())
+	for i := range len(l.Prefix) {
+		var s charSet
+		s.add(l.Prefix[i])
+		sets = append(sets, s)
+	}
+	data := l.dataLen()
+	alphabet := l.alphabet()
+	for d := range data {
+		var s charSet
+		for v := range byte(len(alphabet)) {
+			if l.digitPossible(d, v) {
+				s.add(alphabet[v])
+			}
+		}
+		sets = append(sets, s)
+	}
+	for range l.padLen() {
+		var s charSet
+		s.add('=')
+		sets = append(sets, s)
+	}
+	// Checksum characters can be anything in the alphabet, and so can the
+	// digits of a big number, except those leading ones where its smallest
+	// and largest values still agree on the digits before.
+	lo, hi := l.numberBounds()
+	for t := range l.Tail {
+		first, last := 0, len(alphabet)-1
+		if lo != nil && string(lo[:t]) == string(hi[:t]) {
+			first, last = strings.IndexByte(alphabet, lo[t]), strings.IndexByte(alphabet, hi[t])
+		}
+		var s charSet
+		for i := first; i <= last; i++ {
+			s.add(alphabet[i])
+		}
+		sets = append(sets, s)
+	}
+	return sets
+}
+
+// numberBounds returns the Tail text of the smallest and largest raw bytes
+// of a layout whose Encoding writes one big number (zero Bits): Head, then
+// all zero or all one bits but the Fixed ones, then Foot. It returns nils
+// for other layouts.
+func (l Layout) numberBounds() (lo, hi []byte) {
+	if l.encoding().Bits != 0 || l.Tail == 0 {
+		return nil, nil
+	}
+	text := func(fill byte) []byte {
+		raw := make([]byte, l.RawLen)
+		for i := range raw {
+			raw[i] = fill
+		}
+		for _, f := range l.Fixed {
+			raw[f.Index] = raw[f.Index]&^f.Mask | f.Value&f.Mask
+		}
+		copy(raw, l.Head)
+		copy(raw[l.footStart():], l.Foot)
+		dst := make([]byte, l.Tail)
+		l.Checksum(raw, dst)
+		return dst
+	}
+	return text(0), text(0xff)
+}
+
+// digitPossible reports whether data position d can hold the digit value
+// v: bits inside Head and Foot and the Fixed bits must agree with it, and
+// bits past the end of the raw bytes are always zero.
+func (l Layout) digitPossible(d int, v byte) bool {
+	bits := l.encoding().Bits
+	for k := range bits {
+		if fixed, ok := l.fixedBit(d*bits + k); ok && fixed != v>>(bits-1-k)&1 {
+			return false
+		}
+	}
+	return true
+}
+
+// tailReason explains why the last characters of the text cannot hold the
+// full alphabet.
+func (l Layout) tailReason() string {
+	if len(l.Foot) > 0 {
+		return "the constant bytes it ends with"
+	}
+	for _, f := range l.Fixed {
+		if f.Index != l.RawLen-1 {
+			continue
+		}
+		if bits := l.encoding().Bits; bits > 0 && l.RawLen*8%bits == 0 {
+			return "the bits every key ends with"
+		}
+		return "base64 padding and the bits every key ends with"
+	}
+	return "base64 padding"
+}
+
+// nfa simulates a compiled regexp over per-position character sets instead
+// of a concrete string: a thread advances when any character allowed at the
+// position satisfies its instruction.
+type nfa struct {
+	prog     *syntax.Prog
+	accept   []charSet // per instruction: ASCII characters a rune instruction consumes
+	nonASCII []bool    // per instruction: whether it also consumes non-ASCII runes
+	sets     []charSet
+	anchored bool
+}
+
+func newNFA(re *regexp.Regexp, sets []charSet) (*nfa, error) {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	n := &nfa{
+		prog:     prog,
+		accept:   make([]charSet, len(prog.Inst)),
+		nonASCII: make([]bool, len(prog.Inst)),
+		sets:     sets,
+		anchored: prog.StartCond()&syntax.EmptyBeginText != 0,
+	}
+	for pc := range prog.Inst {
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			for c := range byte(0x80) {
+				if c != '\n' || inst.Op == syntax.InstRuneAny {
+					n.accept[pc].add(c)
+				}
+			}
+			n.nonASCII[pc] = true
+		case syntax.InstRune, syntax.InstRune1:
+			for c := range byte(0x80) {
+				if inst.MatchRune(rune(c)) {
+					n.accept[pc].add(c)
+				}
+			}
+			for i := 1; i < len(inst.Rune); i += 2 {
+				n.nonASCII[pc] = n.nonASCII[pc] || inst.Rune[i] > 0x7f
+			}
+			if len(inst.Rune) == 1 {
+				n.nonASCII[pc] = inst.Rune[0] > 0x7f
+			}
+		}
+	}
+	return n, nil
+}
+
+// threads is the set of instructions that consume input or match at one
+// position.
+type threads struct {
+	pcs     []uint32
+	visited []uint32 // every instruction marked in seen, for reset
+	seen    []bool
+}
+
+func newThreads(n int) *threads { return &threads{seen: make([]bool, n)} }
+
+func (t *threads) reset() {
+	for _, pc := range t.visited {
+		t.seen[pc] = false
+	}
+	t.pcs, t.visited = t.pcs[:0], t.visited[:0]
+}
+
+// add follows the empty transitions from pc at text position pos, collecting
+// the instructions that consume input or match.
+func (n *nfa) add(t *threads, pc uint32, pos int) {
+	if t.seen[pc] {
+		return
+	}
+	t.seen[pc] = true
+	t.visited = append(t.visited, pc)
+	inst := &n.prog.Inst[pc]
+	switch inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		n.add(t, inst.Out, pos)
+		n.add(t, inst.Arg, pos)
+	case syntax.InstCapture, syntax.InstNop:
+		n.add(t, inst.Out, pos)
+	case syntax.InstEmptyWidth:
+		if n.emptyOK(syntax.EmptyOp(inst.Arg), pos) {
+			n.add(t, inst.Out, pos)
+		}
+	case syntax.InstFail:
+	default:
+		t.pcs = append(t.pcs, pc)
+	}
+}
+
+// emptyOK reports whether the zero-width assertions op can hold at pos. No
+// representation contains a newline, so line anchors behave like text
+// anchors; word boundaries depend on the neighbours and are assumed to hold.
+func (n *nfa) emptyOK(op syntax.EmptyOp, pos int) bool {
+	if op&(syntax.EmptyBeginText|syntax.EmptyBeginLine) != 0 && pos != 0 {
+		return false
+	}
+	if op&(syntax.EmptyEndText|syntax.EmptyEndLine) != 0 && pos != len(n.sets) {
+		return false
+	}
+	return true
+}
+
+// step advances the threads in cur over position pos into next and reports
+// whether any thread consumed a character.
+func (n *nfa) step(cur, next *threads, pos int) bool {
+	moved := false
+	for _, pc := range cur.pcs {
+		inst := &n.prog.Inst[pc]
+		if inst.Op == syntax.InstMatch || n.accept[pc].and(n.sets[pos]).len() == 0 {
+			continue
+		}
+		moved = true
+		n.add(next, inst.Out, pos+1)
+	}
+	return moved
+}
+
+func (n *nfa) matched(t *threads) bool {
+	for _, pc := range t.pcs {
+		if n.prog.Inst[pc].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// feasible runs the unanchored search over the whole text and reports
+// whether a match is reachable.
+func (n *nfa) feasible() bool {
+	cur, next := newThreads(len(n.prog.Inst)), newThreads(len(n.prog.Inst))
+	start := uint32(n.prog.Start)
+	for pos := 0; ; pos++ {
+		if !n.anchored || pos == 0 {
+			n.add(cur, start, pos)
+		}
+		if n.matched(cur) {
+			return true
+		}
+		if pos == len(n.sets) || len(cur.pcs) == 0 && n.anchored {
+			return false
+		}
+		next.reset()
+		n.step(cur, next, pos)
+		cur, next = next, cur
+	}
+}
+
+// failure describes where the attempts starting at one offset die.
+type failure struct {
+	start    int
+	at       int
+	wanted   charSet // characters the surviving threads accept at at
+	nonASCII bool
+}
+
+// failure runs an anchored attempt from every start offset and returns the
+// one that consumes the most characters before dying. Ties go to later
+// starts, so that failures against the padding point at the padding, unless
+// the later attempt merely ran out of text.
+func (n *nfa) failure() failure {
+	cur, next := newThreads(len(n.prog.Inst)), newThreads(len(n.prog.Inst))
+	best := failure{at: -1}
+	for s := 0; s <= len(n.sets); s++ {
+		cur.reset()
+		n.add(cur, uint32(n.prog.Start), s)
+		if len(cur.pcs) == 0 {
+			continue
+		}
+		f := failure{start: s, at: s}
+		for pos := s; ; pos++ {
+			f.at = pos
+			if len(cur.pcs) == 0 {
+				break // an anchor failed at pos
+			}
+			if pos == len(n.sets) {
+				n.collect(&f, cur)
+				break
+			}
+			next.reset()
+			if !n.step(cur, next, pos) {
+				n.collect(&f, cur)
+				break
+			}
+			cur, next = next, cur
+		}
+		got, had := f.at-f.start, best.at-best.start
+		if best.at < 0 || got > had || got == had && f.at < len(n.sets) {
+			best = f
+		}
+	}
+	return best
+}
+
+func (n *nfa) collect(f *failure, t *threads) {
+	for _, pc := range t.pcs {
+		f.wanted = f.wanted.or(n.accept[pc])
+		f.nonASCII = f.nonASCII || n.nonASCII[pc]
+	}
+}
+
+// explain turns a failure into an InfeasibleError with a rewrite hint.
+func (n *nfa) explain(f failure, kt KeyType, in Input, l Layout) *InfeasibleError {
+	e := &InfeasibleError{Input: in, Offset: f.at}
+	textLen := len(n.sets)
+
+	wanted := f.wanted.String()
+	if f.wanted.len() == 0 && f.nonASCII {
+		wanted = "a non-ASCII character"
+	}
+	switch {
+	case f.wanted.len() == 0 && !f.nonASCII:
+		e.Reason = fmt.Sprintf("its anchors cannot hold at offset %d of the %d-character text", f.at, textLen)
+	case f.at == textLen:
+		e.Reason = fmt.Sprintf("it needs %s after the last of the %d characters", wanted, textLen)
+	case n.sets[f.at].len() == 1:
+		e.Reason = fmt.Sprintf("offset %d is always %s, but the pattern needs %s", f.at, n.sets[f.at], wanted)
+	default:
+		e.Reason = fmt.Sprintf("offset %d is one of %s, but the pattern needs %s", f.at, n.sets[f.at], wanted)
+	}
+
+	var all charSet
+	for _, s := range n.sets {
+		all = all.or(s)
+	}
+	prefix := n.constPrefix()
+	tail := n.tailStart()
+	switch {
+	case f.wanted.len() > 0 && f.wanted.and(all).len() == 0 || f.wanted.len() == 0 && f.nonASCII:
+		e.Hint = fmt.Sprintf("%ss only contain %s; remove or escape the other characters", in, all)
+	case f.at < len(prefix):
+		e.Hint = fmt.Sprintf("every %s %s starts with %q; continue the pattern from there or drop the ^ anchor", kt, in, prefix)
+	case f.at == textLen:
+		e.Hint = fmt.Sprintf("%s %ss have only %d characters; shorten the pattern", kt, in, textLen)
+	case f.at >= tail:
+		e.Hint = fmt.Sprintf("every %s %s ends in %s because of %s; end the pattern before offset %d or drop the $ anchor",
+			kt, in, n.describe(tail, textLen), l.tailReason(), tail)
+	default:
+		e.Hint = fmt.Sprintf("allow %s at offset %d", n.sets[f.at], f.at)
+	}
+	return e
+}
+
+// constPrefix returns the characters every text starts with.
+func (n *nfa) constPrefix() string {
+	var b strings.Builder
+	for _, s := range n.sets {
+		if s.len() != 1 {
+			break
+		}
+		for c := range byte(0x80) {
+			if s.has(c) {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// tailStart returns the offset from which no position holds the full
+// alphabet of the encoding.
+func (n *nfa) tailStart() int {
+	var full int
+	for _, s := range n.sets {
+		full = max(full, s.len())
+	}
+	i := len(n.sets)
+	for i > 0 && n.sets[i-1].len() < full {
+		i--
+	}
+	return i
+}
+
+// describe renders positions [from, to) as a regexp.
+func (n *nfa) describe(from, to int) string {
+	var b strings.Builder
+	for _, s := range n.sets[from:to] {
+		if s.len() == 1 {
+			str, _ := strconv.Unquote(s.String())
+			b.WriteString(regexp.QuoteMeta(str))
+			continue
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
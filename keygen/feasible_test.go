@@ -0,0 +1,197 @@
+package keygen
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCheckFeasible(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		pattern    string
+		opts       Options
+		in         Input
+		wantOffset int // -1 when feasible
+		wantSub    string
+	}{
+		{"any key", `.`, Options{}, InputPublicKey, -1, ""},
+		{"full ed25519 prefix", `^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI`, Options{}, InputPublicKey, -1, ""},
+		{"partly fixed position", `^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI[A-P]`, Options{}, InputPublicKey, -1, ""},
+		{"case-folded prefix", `(?i)^SSH-ED25519 aaaa`, Options{}, InputPublicKey, -1, ""},
+		{"fingerprint padding", `Q=$`, Options{}, InputFingerprint, -1, ""},
+		{"word boundary", `\bAAAA\b`, Options{}, InputFingerprint, -1, ""},
+		{"full SSHFP digest", `^[0-9a-f]{64}$`, Options{Type: KeyTypeRSA}, InputSSHFP, -1, ""},
+		{"onion address end", `^[a-z2-7]{54}[aiqy]d$`, Options{Type: KeyTypeOnion}, InputPublicKey, -1, ""},
+
+		{"ed25519 prefix mismatch", `^ssh-ed25519 AAAB`, Options{}, InputPublicKey, 15,
+			`offset 15 is always "A", but the pattern needs "B"; every ed25519 public key starts with "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI"`},
+		{"header bits", `^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIZ`, Options{}, InputPublicKey, 37,
+			`offset 37 is one of [A-P], but the pattern needs "Z"`},
+		{"wrong key type", `^ssh-rsa`, Options{}, InputPublicKey, 4, `offset 4 is always "e"`},
+		{"outside alphabet", `ab!`, Options{}, InputPublicKey, 79, `public keys only contain [ +\-/-9A-Za-z]`},
+		{"non-ASCII", `é`, Options{}, InputFingerprint, 43, "needs a non-ASCII character"},
+		{"fingerprint ends in padding", `Q$`, Options{}, InputFingerprint, 43,
+			"every ed25519 fingerprint ends in [048AEIMQUYcgkosw]= because of base64 padding"},
+		{"fingerprint padding bits", `R=$`, Options{}, InputFingerprint, 42, `needs "="`},
+		{"ecdsa-p384 double padding", `Q$`, Options{Type: KeyTypeECDSAP384}, InputPublicKey, 202, "ends in [AQgw]=="},
+		{"rsa 2048 odd modulus", `a$`, Options{Type: KeyTypeRSA, Bits: 2048}, InputPublicKey, 379,
+			"every rsa public key ends in [/13579BDFHJLNPRTVXZbdfhjlnprtvxz] because of the bits every key ends with"},
+		{"rsa 3072 odd modulus", `Q=$`, Options{Type: KeyTypeRSA, Bits: 3072}, InputPublicKey, 550, "ends in [08EMUcks]="},
+		{"rsa 4096 odd modulus", `A==$`, Options{Type: KeyTypeRSA, Bits: 4096}, InputPublicKey, 721, "ends in [Qw]=="},
+		{"rsa 4096 modulus end", `[Qw]==$`, Options{Type: KeyTypeRSA, Bits: 4096}, InputPublicKey, -1, ""},
+		{"rsa modulus top bit", `^.{38}[AB]`, Options{Type: KeyTypeRSA}, InputPublicKey, 38, "offset 38 is one of [CD]"},
+		{"ecdsa-p521 coordinate top bits", `^.{74}[I-Z]`, Options{Type: KeyTypeECDSAP521}, InputPublicKey, 74, "offset 74 is one of [A-H]"},
+		{"anchors", `^ssh-rsa$`, Options{Type: KeyTypeRSA}, InputPublicKey, 7, "its anchors cannot hold at offset 7"},
+		{"SSHFP digest is hex", `^00G`, Options{}, InputSSHFP, 2, "SSHFP digests only contain [0-9a-f]"},
+		{"SSHFP digest is lowercase", `^A`, Options{}, InputSSHFP, 0, "SSHFP digests only contain [0-9a-f]"},
+		{"SSHFP digest length", `^.{65}`, Options{}, InputSSHFP, 64, "ed25519 SSHFP digests have only 64 characters"},
+		{"wireguard key ends in padding", `[AEIMQUYcgkosw048]=$`, Options{Type: KeyTypeWireGuard}, InputPublicKey, -1, ""},
+		{"wireguard padding", `B=$`, Options{Type: KeyTypeWireGuard}, InputPublicKey, 42,
+			"every wireguard public key ends in [048AEIMQUYcgkosw]= because of base64 padding"},
+		{"age recipient", `^age1[qs]`, Options{Type: KeyTypeAge}, InputPublicKey, -1, ""},
+		{"age checksum", `^.{56}[02-9ac-hj-np-z]{6}$`, Options{Type: KeyTypeAge}, InputPublicKey, -1, ""},
+		{"age bech32 charset", `^age1b`, Options{Type: KeyTypeAge}, InputPublicKey, 4, "public keys only contain [0-9ac-hj-np-z]"},
+		{"age last data character", `^.{55}z`, Options{Type: KeyTypeAge}, InputPublicKey, 55, `offset 55 is one of [qs]`},
+		{"openpgp key ID", `(?i)^[0-9a-f]{32}deadbeef$`, Options{Type: KeyTypeOpenPGP}, InputFingerprint, -1, ""},
+		{"openpgp lowercase", `cafe$`, Options{Type: KeyTypeOpenPGP}, InputPublicKey, 39, "public keys only contain [0-9A-F]"},
+		{"minisign public key", `^RW[Q-T]`, Options{Type: KeyTypeMinisign}, InputPublicKey, -1, ""},
+		{"minisign algorithm", `^RWA`, Options{Type: KeyTypeMinisign}, InputPublicKey, 2, "offset 2 is one of [Q-T]"},
+		{"signify key ID", `^[0-9A-F]{16}$`, Options{Type: KeyTypeSignify}, InputFingerprint, -1, ""},
+		{"libp2p peer ID", `^12D3KooW[9A-HJ-NP-T]`, Options{Type: KeyTypeLibP2P}, InputPublicKey, -1, ""},
+		{"libp2p peer ID prefix", `^12D3KooWa`, Options{Type: KeyTypeLibP2P}, InputPublicKey, 8, "offset 8 is one of [9A-HJ-NP-T]"},
+		{"libp2p base58 charset", `0`, Options{Type: KeyTypeLibP2P}, InputPublicKey, 51, "public keys only contain [1-9A-HJ-NP-Za-km-z]"},
+		{"libp2p CID", `^bafzaajaiaejc[a-d]`, Options{Type: KeyTypeLibP2P}, InputFingerprint, -1, ""},
+		{"onion version byte", `^.{55}e`, Options{Type: KeyTypeOnion}, InputPublicKey, 55,
+			"every onion public key ends in [aiqy]d because of the constant bytes it ends with"},
+		{"onion address is lowercase", `^A`, Options{Type: KeyTypeOnion}, InputPublicKey, 0, "public keys only contain [2-7a-z]"},
+		{"too long", `^.{81}`, Options{}, InputPublicKey, 80, "ed25519 public keys have only 80 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := CheckFeasible(regexp.MustCompile(tt.pattern), tt.opts, tt.in)
+			if tt.wantOffset < 0 {
+				if err != nil {
+					t.Fatalf("CheckFeasible(%q) = %v, want nil", tt.pattern, err)
+				}
+				return
+			}
+			var ie *InfeasibleError
+			if !errors.As(err, &ie) {
+				t.Fatalf("CheckFeasible(%q) = %v, want *InfeasibleError", tt.pattern, err)
+			}
+			if ie.Offset != tt.wantOffset || ie.Input != tt.in {
+				t.Errorf("offset, input = %d, %v; want %d, %v", ie.Offset, ie.Input, tt.wantOffset, tt.in)
+			}
+			if !strings.Contains(err.Error(), tt.wantSub) {
+				t.Errorf("error = %q, want substring %q", err, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestCheckFeasible_Errors(t *testing.T) {
+	t.Parallel()
+
+	if err := CheckFeasible(nil, Options{}, InputPublicKey); !errors.Is(err, ErrNilRegex) {
+		t.Errorf("nil regex: err = %v, want ErrNilRegex", err)
+	}
+	if err := CheckFeasible(regexp.MustCompile("."), Options{Type: "dsa"}, InputPublicKey); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("unknown type: err = %v, want ErrUnsupportedType", err)
+	}
+	if err := CheckFeasible(regexp.MustCompile("."), Options{Type: KeyTypeOnion}, InputFingerprint); err == nil || !strings.Contains(err.Error(), "onion keys have no fingerprint") {
+		t.Errorf("onion fingerprint: err = %v, want unsupported input", err)
+	}
+	if err := CheckFeasible(regexp.MustCompile("."), Options{Type: KeyTypeOnion, Bits: 2048}, InputPublicKey); err == nil {
+		t.Error("onion with bits: err = nil")
+	}
+}
+
+// TestLayoutAlphabets_AdmitRealKeys checks the analysis never rejects what a
+// real key produces: every character of real representations lies in its
+// position's alphabet, and any slice of them is a feasible pattern.
+func TestLayoutAlphabets_AdmitRealKeys(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range newWireCases(t) {
+		t.Run(string(tc.opts.Type), func(t *testing.T) {
+			sshPub, err := ssh.NewPublicKey(tc.pub)
+			if err != nil {
+				t.Fatalf("NewPublicKey: %v", err)
+			}
+			h := sha256.Sum256(sshPub.Marshal())
+			texts := map[Input]string{
+				InputPublicKey:   getAuthorizedKey(sshPub),
+				InputFingerprint: base64.StdEncoding.EncodeToString(h[:]),
+				InputSSHFP:       hex.EncodeToString(h[:]),
+			}
+			for in, text := range texts {
+				l, err := tc.opts.layout(in)
+				if err != nil {
+					t.Fatalf("layout: %v", err)
+				}
+				sets := l.alphabets()
+				if len(sets) != len(text) {
+					t.Fatalf("%v: %d alphabets for %d characters", in, len(sets), len(text))
+				}
+				for i := range len(text) {
+					if !sets[i].has(text[i]) {
+						t.Errorf("%v offset %d: %q not in %s", in, i, text[i], sets[i])
+					}
+				}
+				for _, pattern := range []string{
+					"^" + regexp.QuoteMeta(text) + "$",
+					regexp.QuoteMeta(text[len(text)/2:]) + "$",
+					regexp.QuoteMeta(text[len(text)/3 : len(text)/2]),
+				} {
+					if err := CheckFeasible(regexp.MustCompile(pattern), tc.opts, in); err != nil {
+						t.Errorf("%v: CheckFeasible(real slice) = %v", in, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCharSetString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		chars string
+		want  string
+	}{
+		{"A", `"A"`},
+		{"AB", "[AB]"},
+		{"ABC", "[A-C]"},
+		{"-^]\\", `[\-\\-\^]`},
+		{"\x00\x01\x02", `[\x00-\x02]`},
+	}
+	for _, tt := range tests {
+		var s charSet
+		for i := range len(tt.chars) {
+			s.add(tt.chars[i])
+		}
+		if got := s.String(); got != tt.want {
+			t.Errorf("charSet(%q).String() = %s, want %s", tt.chars, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkCheckFeasible_RSA16384(b *testing.B) {
+	re := regexp.MustCompile(`.*!`)
+	opts := Options{Type: KeyTypeRSA, Bits: 16384}
+	for b.Loop() {
+		if err := CheckFeasible(re, opts, InputPublicKey); err == nil {
+			b.Fatal("expected infeasible")
+		}
+	}
+}
@@ -2,62 +2,127 @@ package keygen
 
 import (
 	"context"
+	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/pem"
-	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
-// ED25519 SSH wire format: uint32(11) + "ssh-ed25519" + uint32(32) + pubkey(32) = 51 bytes
-const wireKeyLen = 51
-const pubKeyOffset = 19
-
-var globalCounter atomic.Int64
-var matchCounter atomic.Int64
+// counters tracks keys generated and matches found by a set of workers.
+type counters struct {
+	keys    atomic.Int64
+	matches atomic.Int64
+}
 
-// ErrNilRegex is returned when FindKeys is called with a nil regex.
-var ErrNilRegex = errors.New("regex must not be nil")
+// defaultCounters backs the package-level FindKeys, KeyCount and MatchCount.
+var defaultCounters counters
 
 // Result holds a matched key pair and its metadata.
 type Result struct {
+	// PrivateKey is the matched key (ed25519.PrivateKey, *rsa.PrivateKey,
+	// *ecdsa.PrivateKey, or *ecdh.PrivateKey for WireGuard and age), for
+	// callers that encode it themselves.
+	PrivateKey    crypto.PrivateKey
 	PrivateKeyPEM []byte
+	// AuthorizedKey is the public text a Matcher with InputPublicKey was
+	// tested against, and Fingerprint the one for InputFingerprint:
+	//
+	//   - SSH keys: the authorized_keys line without a comment, and the
+	//     base64 SHA256 fingerprint
+	//   - onion: the address without ".onion", and none
+	//   - wireguard: the base64 public key, and none
+	//   - age: the age1 recipient, and none
+	//   - openpgp: the hex fingerprint, in both
+	//   - minisign, signify: the base64 public key, and the key ID
+	//   - libp2p: the peer ID, and its CIDv1
+	//
+	// PrivateKeyPEM is empty for key types that are not SSH keys (see
+	// KeyType.SSH).
 	AuthorizedKey string
 	Fingerprint   string
+	// Created is the creation time of OpenPGP keys, which their
+	// fingerprint covers.
+	Created time.Time
+	// Comment is stored in PrivateKeyPEM and follows the key in
+	// AuthorizedKeyLine; see WithComment.
+	Comment string
+	// Keys is the number of keys the search had generated, across all
+	// workers, when this one matched, and Elapsed how long it had run.
+	Keys    int64
+	Elapsed time.Duration
+	// Worker identifies the Searcher worker that found the key, from 0.
+	// FindKeys callers always see 0.
+	Worker int
+}
+
+// WithComment returns a copy of r with comment stored in its private key.
+func (r Result) WithComment(comment string) (Result, error) {
+	if strings.ContainsAny(comment, "\r\n") {
+		return Result{}, fmt.Errorf("comment must be a single line")
+	}
+	pemKey, err := ssh.MarshalPrivateKey(r.PrivateKey, comment)
+	if err != nil {
+		return Result{}, fmt.Errorf("marshal private key: %w", err)
+	}
+	r.PrivateKeyPEM = pem.EncodeToMemory(pemKey)
+	r.Comment = comment
+	return r, nil
+}
+
+// AuthorizedKeyLine returns the authorized_keys line for the key: the
+// AuthorizedKey followed by the Comment, if any.
+func (r Result) AuthorizedKeyLine() string {
+	if r.Comment == "" {
+		return r.AuthorizedKey
+	}
+	return r.AuthorizedKey + " " + r.Comment
+}
+
+// Text returns the representation of the key that a Matcher with the given
+// Input was tested against.
+func (r Result) Text(in Input) string {
+	switch in {
+	case InputFingerprint:
+		return r.Fingerprint
+	case InputSSHFP:
+		sum, _ := base64.StdEncoding.DecodeString(r.Fingerprint)
+		return hex.EncodeToString(sum)
+	}
+	return r.AuthorizedKey
 }
 
 // Options configures key generation behavior.
 type Options struct {
-	Regex       *regexp.Regexp
-	Fingerprint bool
+	// Matcher decides which keys are hits and which representation of
+	// each key it inspects.
+	Matcher Matcher
+	// Type selects the key algorithm; the zero value means ed25519.
+	Type KeyType
+	// Bits is the RSA modulus size; zero means DefaultRSABits. It must be
+	// zero for other key types.
+	Bits int
 }
 
-// KeyCount returns the total number of keys generated.
-func KeyCount() int64 { return globalCounter.Load() }
+// KeyCount returns the total number of keys generated by FindKeys.
+func KeyCount() int64 { return defaultCounters.keys.Load() }
 
-// MatchCount returns the total number of matches found.
-func MatchCount() int64 { return matchCounter.Load() }
+// MatchCount returns the total number of matches found by FindKeys.
+func MatchCount() int64 { return defaultCounters.matches.Load() }
 
-// ResetCounters zeroes the global and match counters (for test isolation).
+// ResetCounters zeroes the counters shared by FindKeys callers (for test
+// isolation). Searchers have their own counters and are unaffected.
 func ResetCounters() {
-	globalCounter.Store(0)
-	matchCounter.Store(0)
-}
-
-// newWireKeyBuf returns a pre-initialized ED25519 SSH wire format buffer.
-func newWireKeyBuf() []byte {
-	buf := make([]byte, wireKeyLen)
-	buf[3] = 11 // big-endian uint32(11) — length of "ssh-ed25519"
-	copy(buf[4:15], "ssh-ed25519")
-	buf[18] = 32 // big-endian uint32(32) — length of public key
-	return buf
+	defaultCounters.keys.Store(0)
+	defaultCounters.matches.Store(0)
 }
 
 // getFingerprint returns the SHA256 fingerprint of an ssh.PublicKey.
@@ -71,80 +136,124 @@ func getAuthorizedKey(key ssh.PublicKey) string {
 	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
 }
 
-// FindKeys generates ED25519 keys in a tight loop, matching against the regex.
-// Matched keys are sent on the results channel. Returns nil on context
-// cancellation, or an error if key generation fails.
+// FindKeys generates keys of the configured type (ED25519 by default) in a
+// tight loop, testing each against the Matcher. Matched keys are sent on
+// the results channel. Returns nil on context cancellation, or an error if
+// key generation fails.
+//
+// FindKeys counts into package-level counters shared by every caller; use a
+// Searcher to run independent searches in one process.
 func FindKeys(ctx context.Context, opts Options, results chan<- Result) error {
-	if opts.Regex == nil {
-		return ErrNilRegex
+	return findKeys(opts, &worker{ctx: ctx, results: results, c: &defaultCounters, start: time.Now()})
+}
+
+// findKeys is the worker loop behind FindKeys and Searcher.
+func findKeys(opts Options, w *worker) error {
+	if opts.Matcher == nil {
+		return ErrNilMatcher
 	}
-	wireKey := newWireKeyBuf()
-
-	authKeyPrefix := []byte("ssh-ed25519 ")
-	b64Len := base64.StdEncoding.EncodedLen(wireKeyLen)
-	authKeyBuf := make([]byte, len(authKeyPrefix)+b64Len)
-	copy(authKeyBuf, authKeyPrefix)
-
-	fpBuf := make([]byte, base64.StdEncoding.EncodedLen(sha256.Size))
-
-	var localCount int64
-	const flushInterval = 1024
-
-	for {
-		localCount++
-		if localCount >= flushInterval {
-			globalCounter.Add(localCount)
-			localCount = 0
-			if ctx.Err() != nil {
-				return nil
-			}
-		}
+	layout, err := opts.layout(opts.Matcher.Input())
+	if err != nil {
+		return err
+	}
+	p := newProbe(opts.Matcher, layout)
+	if st, ok := sourceTypes[opts.keyType()]; ok {
+		return searchSource(w, p, layout, st.newSource(opts.Matcher.Input()))
+	}
+	wire, err := opts.wire()
+	if err != nil {
+		return err
+	}
+	// Both digest inputs match on the SHA256 of the wire key; the layout
+	// decides how it is encoded.
+	fingerprint := opts.Matcher.Input() != InputPublicKey
 
-		pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
-		if err != nil {
-			return fmt.Errorf("generate ed25519 key: %w", err)
+	switch kt := opts.keyType(); kt {
+	case KeyTypeRSA:
+		return searchRSA(w, p, fingerprint, wire, opts.rsaBits())
+	case KeyTypeED25519:
+		return searchSSH(w, p, fingerprint, wire, &ed25519Source{})
+	default:
+		return searchSSH(w, p, fingerprint, wire, &ecdsaSource{curve: ecdsaCurves[kt].curve})
+	}
+}
+
+// sshKeySource generates key pairs for searchSSH. next writes the public key
+// material of a fresh pair into dst; last returns that pair and is only
+// called on a match, so the hot loop never boxes keys into interfaces.
+type sshKeySource interface {
+	next(dst []byte) error
+	last() (crypto.PrivateKey, crypto.PublicKey)
+}
+
+// ed25519Source generates ED25519 key pairs.
+type ed25519Source struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func (s *ed25519Source) next(dst []byte) error {
+	var err error
+	s.pub, s.priv, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate ed25519 key: %w", err)
+	}
+	copy(dst, s.pub)
+	return nil
+}
+
+func (s *ed25519Source) last() (crypto.PrivateKey, crypto.PublicKey) { return s.priv, s.pub }
+
+// searchSSH generates keys from src until cancelled, copying each public key
+// into a pre-initialized wire buffer so matching never re-encodes the
+// constant header.
+func searchSSH(w *worker, p *probe, fingerprint bool, wire wireFormat, src sshKeySource) error {
+	wireKey := wire.newBuf()
+	keyMaterial := wireKey[wire.keyOffset():]
+	for w.next() {
+		if err := src.next(keyMaterial); err != nil {
+			return err
 		}
-		copy(wireKey[pubKeyOffset:], pubKey)
 
 		var matched bool
-		if opts.Fingerprint {
+		if fingerprint {
 			sum := sha256.Sum256(wireKey)
-			base64.StdEncoding.Encode(fpBuf, sum[:])
-			matched = opts.Regex.Match(fpBuf)
+			matched = p.match(sum[:])
 		} else {
-			base64.StdEncoding.Encode(authKeyBuf[len(authKeyPrefix):], wireKey)
-			matched = opts.Regex.Match(authKeyBuf)
+			matched = p.match(wireKey)
 		}
-
 		if !matched {
 			continue
 		}
 
-		// Match found — slow path: flush counter, build result
-		globalCounter.Add(localCount)
-		localCount = 0
-		matchCounter.Add(1)
-
-		publicKey, err := ssh.NewPublicKey(pubKey)
+		// Match found — slow path: build result
+		result, err := newSSHResult(src.last())
 		if err != nil {
-			return fmt.Errorf("convert public key: %w", err)
+			return err
 		}
-
-		pemKey, err := ssh.MarshalPrivateKey(privKey, "")
-		if err != nil {
-			return fmt.Errorf("marshal private key: %w", err)
+		if !w.emit(result) {
+			return nil
 		}
+	}
+	return nil
+}
 
-		result := Result{
-			PrivateKeyPEM: pem.EncodeToMemory(pemKey),
-			AuthorizedKey: getAuthorizedKey(publicKey),
-			Fingerprint:   getFingerprint(publicKey),
-		}
+// newSSHResult builds the Result for an SSH key pair.
+func newSSHResult(priv crypto.PrivateKey, pub crypto.PublicKey) (Result, error) {
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return Result{}, fmt.Errorf("convert public key: %w", err)
+	}
 
-		select {
-		case results <- result:
-		case <-ctx.Done():
-			return nil
-		}
+	pemKey, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return Result{}, fmt.Errorf("marshal private key: %w", err)
 	}
+
+	return Result{
+		PrivateKey:    priv,
+		PrivateKeyPEM: pem.EncodeToMemory(pemKey),
+		AuthorizedKey: getAuthorizedKey(publicKey),
+		Fingerprint:   getFingerprint(publicKey),
+	}, nil
 }
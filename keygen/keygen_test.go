@@ -4,11 +4,14 @@
 package keygen
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"regexp"
 	"strings"
@@ -34,15 +37,36 @@ func assertResultFields(t *testing.T, r Result) {
 	if r.Fingerprint == "" {
 		t.Error("Fingerprint is empty")
 	}
+	priv, ok := r.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		t.Fatalf("PrivateKey is %T, want ed25519.PrivateKey", r.PrivateKey)
+	}
+	pub, err := ssh.NewPublicKey(priv.Public())
+	if err != nil {
+		t.Fatalf("NewPublicKey: %v", err)
+	}
+	if got := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))); got != r.AuthorizedKey {
+		t.Errorf("PrivateKey belongs to %q, want %q", got, r.AuthorizedKey)
+	}
+}
+
+// regexMatcher wraps re in a RegexMatcher, failing the test on error.
+func regexMatcher(t *testing.T, re *regexp.Regexp, in Input) Matcher {
+	t.Helper()
+	m, err := NewRegexMatcher(re, in)
+	if err != nil {
+		t.Fatalf("NewRegexMatcher: %v", err)
+	}
+	return m
 }
 
 func TestNewWireKeyBuf(t *testing.T) {
 	t.Parallel()
 
-	buf := newWireKeyBuf()
+	buf := ed25519Wire.newBuf()
 
-	if len(buf) != wireKeyLen {
-		t.Errorf("length = %d, want %d", len(buf), wireKeyLen)
+	if len(buf) != 51 {
+		t.Errorf("length = %d, want %d", len(buf), 51)
 	}
 	if buf[0] != 0 || buf[1] != 0 || buf[2] != 0 || buf[3] != 11 {
 		t.Errorf("algo name length = [%d %d %d %d], want [0 0 0 11]",
@@ -55,7 +79,10 @@ func TestNewWireKeyBuf(t *testing.T) {
 		t.Errorf("key length = [%d %d %d %d], want [0 0 0 32]",
 			buf[15], buf[16], buf[17], buf[18])
 	}
-	for i := pubKeyOffset; i < wireKeyLen; i++ {
+	if off := ed25519Wire.keyOffset(); off != 19 {
+		t.Errorf("keyOffset() = %d, want 19", off)
+	}
+	for i := ed25519Wire.keyOffset(); i < len(buf); i++ {
 		if buf[i] != 0 {
 			t.Errorf("buf[%d] = %d, want 0 (public key slot should be zeroed)", i, buf[i])
 			break
@@ -66,8 +93,8 @@ func TestNewWireKeyBuf(t *testing.T) {
 func TestNewWireKeyBuf_IndependentBuffers(t *testing.T) {
 	t.Parallel()
 
-	buf1 := newWireKeyBuf()
-	buf2 := newWireKeyBuf()
+	buf1 := ed25519Wire.newBuf()
+	buf2 := ed25519Wire.newBuf()
 
 	for i := range buf1 {
 		buf1[i] = 0xFF
@@ -90,12 +117,12 @@ func TestHotPathSlowPathEquivalence(t *testing.T) {
 			t.Fatalf("NewPublicKey: %v", err)
 		}
 
-		wireKey := newWireKeyBuf()
-		copy(wireKey[pubKeyOffset:], pub)
+		wireKey := ed25519Wire.newBuf()
+		copy(wireKey[ed25519Wire.keyOffset():], pub)
 
 		// Public key: hot path vs getAuthorizedKey.
 		authKeyPrefix := []byte("ssh-ed25519 ")
-		b64Len := base64.StdEncoding.EncodedLen(wireKeyLen)
+		b64Len := base64.StdEncoding.EncodedLen(ed25519Wire.len())
 		authKeyBuf := make([]byte, len(authKeyPrefix)+b64Len)
 		copy(authKeyBuf, authKeyPrefix)
 		base64.StdEncoding.Encode(authKeyBuf[len(authKeyPrefix):], wireKey)
@@ -116,8 +143,8 @@ func TestHotPathSlowPathEquivalence(t *testing.T) {
 func TestResetCounters(t *testing.T) {
 	// Not parallel: modifies global counter state.
 
-	globalCounter.Add(100)
-	matchCounter.Add(10)
+	defaultCounters.keys.Add(100)
+	defaultCounters.matches.Add(10)
 	t.Cleanup(func() { ResetCounters() })
 
 	ResetCounters()
@@ -130,14 +157,14 @@ func TestResetCounters(t *testing.T) {
 	}
 }
 
-func TestFindKeys_NilRegex(t *testing.T) {
+func TestFindKeys_NilMatcher(t *testing.T) {
 	results := make(chan Result, 1)
-	err := FindKeys(context.Background(), Options{Regex: nil}, results)
+	err := FindKeys(context.Background(), Options{Matcher: nil}, results)
 	if err == nil {
 		t.Fatal("want error, got nil")
 	}
-	if !errors.Is(err, ErrNilRegex) {
-		t.Errorf("error = %v, want %v", err, ErrNilRegex)
+	if !errors.Is(err, ErrNilMatcher) {
+		t.Errorf("error = %v, want %v", err, ErrNilMatcher)
 	}
 	select {
 	case r := <-results:
@@ -154,8 +181,9 @@ func TestFindKeys_Cancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	errCh := make(chan error, 1)
+	opts := Options{Matcher: regexMatcher(t, re, InputPublicKey)}
 	go func() {
-		errCh <- FindKeys(ctx, Options{Regex: re}, results)
+		errCh <- FindKeys(ctx, opts, results)
 	}()
 
 	// Wait for at least one result to prove the worker was running.
@@ -186,8 +214,9 @@ func TestFindKeys_AlreadyCancelledContext(t *testing.T) {
 	cancel()
 
 	errCh := make(chan error, 1)
+	opts := Options{Matcher: regexMatcher(t, re, InputPublicKey)}
 	go func() {
-		errCh <- FindKeys(ctx, Options{Regex: re}, results)
+		errCh <- FindKeys(ctx, opts, results)
 	}()
 
 	select {
@@ -209,8 +238,9 @@ func TestFindKeys_MultipleMatches(t *testing.T) {
 
 	results := make(chan Result, 3)
 	errCh := make(chan error, 1)
+	opts := Options{Matcher: regexMatcher(t, re, InputPublicKey)}
 	go func() {
-		errCh <- FindKeys(ctx, Options{Regex: re}, results)
+		errCh <- FindKeys(ctx, opts, results)
 	}()
 
 	const want = 3
@@ -250,8 +280,9 @@ func TestFindKeys_BlockedSendCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	errCh := make(chan error, 1)
+	opts := Options{Matcher: regexMatcher(t, re, InputPublicKey)}
 	go func() {
-		errCh <- FindKeys(ctx, Options{Regex: re}, results)
+		errCh <- FindKeys(ctx, opts, results)
 	}()
 
 	select {
@@ -278,19 +309,19 @@ func TestFindKeys_TrulySelectiveRegex(t *testing.T) {
 	// Not parallel: depends on and resets global counter state.
 
 	tests := []struct {
-		name        string
-		fingerprint bool
-		checkField  func(Result) string
+		name       string
+		input      Input
+		checkField func(Result) string
 	}{
 		{
-			name:        "public key mode",
-			fingerprint: false,
-			checkField:  func(r Result) string { return r.AuthorizedKey },
+			name:       "public key mode",
+			input:      InputPublicKey,
+			checkField: func(r Result) string { return r.AuthorizedKey },
 		},
 		{
-			name:        "fingerprint mode",
-			fingerprint: true,
-			checkField:  func(r Result) string { return r.Fingerprint },
+			name:       "fingerprint mode",
+			input:      InputFingerprint,
+			checkField: func(r Result) string { return r.Fingerprint },
 		},
 	}
 
@@ -305,8 +336,9 @@ func TestFindKeys_TrulySelectiveRegex(t *testing.T) {
 
 			results := make(chan Result, 1)
 			errCh := make(chan error, 1)
+			opts := Options{Matcher: regexMatcher(t, re, tt.input)}
 			go func() {
-				errCh <- FindKeys(ctx, Options{Regex: re, Fingerprint: tt.fingerprint}, results)
+				errCh <- FindKeys(ctx, opts, results)
 			}()
 
 			select {
@@ -345,8 +377,9 @@ func TestFindKeys_FingerprintModeRejectsPubKeyPattern(t *testing.T) {
 
 	results := make(chan Result, 1)
 	errCh := make(chan error, 1)
+	opts := Options{Matcher: regexMatcher(t, re, InputFingerprint)}
 	go func() {
-		errCh <- FindKeys(ctx, Options{Regex: re, Fingerprint: true}, results)
+		errCh <- FindKeys(ctx, opts, results)
 	}()
 
 	deadline := time.After(10 * time.Second)
@@ -379,9 +412,10 @@ func TestFindKeys_ConcurrentWorkers(t *testing.T) {
 
 	results := make(chan Result, matchesWanted)
 	errCh := make(chan error, numWorkers)
+	opts := Options{Matcher: regexMatcher(t, re, InputPublicKey)}
 	for range numWorkers {
 		go func() {
-			errCh <- FindKeys(ctx, Options{Regex: re}, results)
+			errCh <- FindKeys(ctx, opts, results)
 		}()
 	}
 
@@ -407,3 +441,70 @@ func TestFindKeys_ConcurrentWorkers(t *testing.T) {
 		t.Errorf("got %d distinct keys, want %d", len(seen), matchesWanted)
 	}
 }
+
+func TestResult_WithComment(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newSSHResult(priv, pub)
+	if err != nil {
+		t.Fatalf("newSSHResult: %v", err)
+	}
+	if got := r.AuthorizedKeyLine(); got != r.AuthorizedKey {
+		t.Errorf("AuthorizedKeyLine without comment = %q, want %q", got, r.AuthorizedKey)
+	}
+
+	c, err := r.WithComment("alice@laptop")
+	if err != nil {
+		t.Fatalf("WithComment: %v", err)
+	}
+	if c.AuthorizedKey != r.AuthorizedKey {
+		t.Errorf("AuthorizedKey changed to %q", c.AuthorizedKey)
+	}
+	if want := r.AuthorizedKey + " alice@laptop"; c.AuthorizedKeyLine() != want {
+		t.Errorf("AuthorizedKeyLine = %q, want %q", c.AuthorizedKeyLine(), want)
+	}
+	signer, err := ssh.ParsePrivateKey(c.PrivateKeyPEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey: %v", err)
+	}
+	if !strings.Contains(string(ssh.MarshalAuthorizedKey(signer.PublicKey())), strings.Fields(r.AuthorizedKey)[1]) {
+		t.Error("commented private key does not match the public key")
+	}
+	// The comment sits in the unencrypted private section, after the key.
+	block, _ := pem.Decode(c.PrivateKeyPEM)
+	if block == nil || !bytes.Contains(block.Bytes, []byte("alice@laptop")) {
+		t.Error("PrivateKeyPEM does not contain the comment")
+	}
+
+	if _, err := r.WithComment("two\nlines"); err == nil {
+		t.Error("WithComment accepted a multi-line comment")
+	}
+}
+
+func TestFindKeys_SSHFP(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{`^0`, `(?i)f$`} {
+		re := regexp.MustCompile(pattern)
+		m, err := NewMatcher(re, InputSSHFP)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		go func() { _ = FindKeys(ctx, Options{Matcher: m}, results) }()
+		r := <-results
+		cancel()
+
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.AuthorizedKey))
+		if err != nil {
+			t.Fatalf("ParseAuthorizedKey: %v", err)
+		}
+		sum := sha256.Sum256(pub.Marshal())
+		if text := r.Text(InputSSHFP); text != hex.EncodeToString(sum[:]) || !re.MatchString(text) {
+			t.Errorf("%q: Text(InputSSHFP) = %q for digest %x", pattern, text, sum)
+		}
+	}
+}
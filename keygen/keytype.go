@@ -0,0 +1,104 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedType is returned for an unknown KeyType.
+var ErrUnsupportedType = errors.New("unsupported key type")
+
+// KeyType selects the algorithm of generated keys.
+type KeyType string
+
+const (
+	// KeyTypeED25519 generates ssh-ed25519 keys. It is the default.
+	KeyTypeED25519 KeyType = "ed25519"
+	// KeyTypeRSA generates ssh-rsa keys of Options.Bits bits.
+	KeyTypeRSA KeyType = "rsa"
+)
+
+// DefaultRSABits is the RSA modulus size used when Options.Bits is zero,
+// matching ssh-keygen.
+const DefaultRSABits = 3072
+
+// KeyTypes lists the supported key types.
+var KeyTypes = []KeyType{
+	KeyTypeED25519, KeyTypeRSA,
+	KeyTypeECDSAP256, KeyTypeECDSAP384, KeyTypeECDSAP521,
+	KeyTypeOnion, KeyTypeWireGuard, KeyTypeAge, KeyTypeOpenPGP,
+	KeyTypeMinisign, KeyTypeSignify, KeyTypeLibP2P,
+}
+
+// ParseKeyType returns the KeyType named s.
+func ParseKeyType(s string) (KeyType, error) {
+	for _, kt := range KeyTypes {
+		if string(kt) == s {
+			return kt, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedType, s)
+}
+
+// keyType returns the configured key type, defaulting to ed25519.
+func (o Options) keyType() KeyType {
+	if o.Type == "" {
+		return KeyTypeED25519
+	}
+	return o.Type
+}
+
+// rsaBits returns the configured RSA modulus size, applying the default.
+func (o Options) rsaBits() int {
+	if o.Bits == 0 {
+		return DefaultRSABits
+	}
+	return o.Bits
+}
+
+// wire returns the SSH wire format of the configured key type, validating
+// type-specific options on the way.
+func (o Options) wire() (wireFormat, error) {
+	kt := o.keyType()
+	if o.Bits != 0 && kt != KeyTypeRSA {
+		return wireFormat{}, fmt.Errorf("bits only applies to key type %q", KeyTypeRSA)
+	}
+	switch kt {
+	case KeyTypeED25519:
+		return ed25519Wire, nil
+	case KeyTypeRSA:
+		bits := o.rsaBits()
+		if err := checkRSABits(bits); err != nil {
+			return wireFormat{}, err
+		}
+		return rsaWire(bits), nil
+	}
+	if c, ok := ecdsaCurves[kt]; ok {
+		return c.wire, nil
+	}
+	return wireFormat{}, fmt.Errorf("%w: %q", ErrUnsupportedType, kt)
+}
+
+// layout returns the Layout of the representation in for the configured key
+// type.
+func (o Options) layout(in Input) (Layout, error) {
+	if st, ok := sourceTypes[o.keyType()]; ok {
+		if o.Bits != 0 {
+			return Layout{}, fmt.Errorf("bits only applies to key type %q", KeyTypeRSA)
+		}
+		return st.layout(o.keyType(), in)
+	}
+	wire, err := o.wire()
+	if err != nil {
+		return Layout{}, err
+	}
+	switch in {
+	case InputPublicKey:
+		return wire.layout(), nil
+	case InputFingerprint:
+		return fingerprintLayout, nil
+	case InputSSHFP:
+		return sshfpLayout, nil
+	}
+	return Layout{}, fmt.Errorf("unsupported matcher input %d", in)
+}
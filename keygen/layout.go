@@ -0,0 +1,220 @@
+package keygen
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Encoding is a radix-2^Bits encoding of raw bytes, most significant bits
+// first, drawing digits from Alphabet. With Pad, the text is padded with
+// '=' to whole groups of bytes, as in standard base64. Zero Bits means the
+// raw bytes are one big number written in radix len(Alphabet), so no
+// character is a function of a few raw bits.
+type Encoding struct {
+	Alphabet string
+	Bits     int
+	Pad      bool
+}
+
+// The encodings of the supported representations.
+var (
+	// Base64Encoding is padded standard base64, the zero Encoding.
+	Base64Encoding = Encoding{Alphabet: base64Alphabet, Bits: 6, Pad: true}
+	// HexEncoding is lowercase hex.
+	HexEncoding = Encoding{Alphabet: hexAlphabet, Bits: 4}
+	// UpperHexEncoding is uppercase hex, as gpg prints fingerprints.
+	UpperHexEncoding = Encoding{Alphabet: upperHexAlphabet, Bits: 4}
+	// Base32Encoding is lowercase, unpadded RFC 4648 base32.
+	Base32Encoding = Encoding{Alphabet: base32Alphabet, Bits: 5}
+	// Bech32Encoding is the data part of a bech32 string (BIP 173),
+	// without its checksum.
+	Bech32Encoding = Encoding{Alphabet: bech32Alphabet, Bits: 5}
+	// Base58Encoding is base58btc, as Bitcoin addresses and libp2p peer
+	// IDs use it. Layouts using it have no data characters: their text
+	// after the Prefix is all Tail.
+	Base58Encoding = Encoding{Alphabet: base58Alphabet}
+)
+
+// Layout describes how the text of a representation is built from raw
+// bytes: a constant Prefix followed by the Encoding of RawLen bytes, the
+// first of which are always Head and the last always Foot, with the Fixed
+// bits in between. RawMatchers use it to test raw bytes in the hot loop
+// without encoding them, and the feasibility analysis to derive which
+// characters can appear at each position.
+type Layout struct {
+	Prefix string
+	Head   []byte
+	Foot   []byte
+	RawLen int
+	// Encoding is the encoding of the raw bytes; the zero value means
+	// Base64Encoding.
+	Encoding Encoding
+	// Tail is the number of characters after the data and padding that
+	// depend on every raw bit, such as a checksum, which Checksum writes
+	// to dst for raw. They are only ever tested on the encoded text.
+	Tail     int
+	Checksum func(raw, dst []byte)
+	// Fixed lists the bits between Head and Foot that every key shares,
+	// such as the top and low bits of an RSA modulus.
+	Fixed []FixedBits
+}
+
+// FixedBits are the bits under Mask of raw byte Index, which are always
+// those of Value.
+type FixedBits struct {
+	Index       int
+	Mask, Value byte
+}
+
+// fixedBit returns the value of bit (0 is the top bit of raw byte 0) when
+// every key has the same one there.
+func (l Layout) fixedBit(bit int) (v byte, ok bool) {
+	i, shift := bit/8, 7-bit%8
+	switch {
+	case i < len(l.Head):
+		return l.Head[i] >> shift & 1, true
+	case i >= l.RawLen:
+		return 0, true
+	case i >= l.footStart():
+		return l.Foot[i-l.footStart()] >> shift & 1, true
+	}
+	for _, f := range l.Fixed {
+		if f.Index == i && f.Mask>>shift&1 == 1 {
+			return f.Value >> shift & 1, true
+		}
+	}
+	return 0, false
+}
+
+// The SHA256 fingerprint is base64(SHA256(wire key)) for every SSH key type
+// and the SSHFP digest hex(SHA256(wire key)); the ed25519 authorized_keys
+// line is "ssh-ed25519 " + base64(wire key).
+var (
+	fingerprintLayout = Layout{RawLen: sha256.Size}
+	sshfpLayout       = Layout{RawLen: sha256.Size, Encoding: HexEncoding}
+	ed25519KeyLayout  = ed25519Wire.layout()
+)
+
+// encoding returns the Encoding of l, applying the default.
+func (l Layout) encoding() Encoding {
+	if l.Encoding == (Encoding{}) {
+		return Base64Encoding
+	}
+	return l.Encoding
+}
+
+// TextLen returns the length of the full text, including padding and
+// checksum.
+func (l Layout) TextLen() int {
+	return len(l.Prefix) + l.dataLen() + l.padLen() + l.Tail
+}
+
+// dataLen returns the number of characters that carry raw bits, excluding
+// '=' padding.
+func (l Layout) dataLen() int {
+	bits := l.encoding().Bits
+	if bits == 0 {
+		return 0
+	}
+	return (l.RawLen*8 + bits - 1) / bits
+}
+
+// padLen returns the number of '=' characters after the data.
+func (l Layout) padLen() int {
+	e := l.encoding()
+	if !e.Pad {
+		return 0
+	}
+	// A group is the fewest characters that hold whole bytes: 4 for base64.
+	group := 8
+	for group%e.Bits != 0 {
+		group += 8
+	}
+	group /= e.Bits
+	return (group - l.dataLen()%group) % group
+}
+
+// footStart returns the index of the first Foot byte in the raw bytes.
+func (l Layout) footStart() int { return l.RawLen - len(l.Foot) }
+
+// alphabet returns the characters data positions are drawn from, indexed
+// by digit value.
+func (l Layout) alphabet() string { return l.encoding().Alphabet }
+
+// digit returns the function giving the digit value at data position d of
+// raw.
+func (l Layout) digit() func(raw []byte, d int) byte {
+	switch bits := l.encoding().Bits; bits {
+	case 6:
+		return sextet
+	case 4:
+		return nibble
+	default:
+		return func(raw []byte, d int) byte { return digitAt(raw, d, bits) }
+	}
+}
+
+// encoder returns the function writing the text of raw after the prefix
+// into dst, using the standard library where it implements the encoding.
+func (l Layout) encoder() func(dst, src []byte) {
+	encode := l.dataEncoder()
+	if l.Checksum == nil {
+		return encode
+	}
+	tail := l.dataLen() + l.padLen()
+	return func(dst, src []byte) {
+		encode(dst[:tail], src)
+		l.Checksum(src, dst[tail:])
+	}
+}
+
+// dataEncoder returns the function writing the data and padding of raw
+// into dst.
+func (l Layout) dataEncoder() func(dst, src []byte) {
+	switch e := l.encoding(); e {
+	case Base64Encoding:
+		return base64.StdEncoding.Encode
+	case HexEncoding:
+		return func(dst, src []byte) { hex.Encode(dst, src) }
+	default:
+		digit, n := l.digit(), l.dataLen()
+		return func(dst, src []byte) {
+			for d := range n {
+				dst[d] = e.Alphabet[digit(src, d)]
+			}
+			for i := n; i < len(dst); i++ {
+				dst[i] = '='
+			}
+		}
+	}
+}
+
+// nibble returns the 4-bit hex value at data position d of raw.
+func nibble(raw []byte, d int) byte {
+	return raw[d/2] >> (4 - d%2*4) & 0xf
+}
+
+// sextet returns the 6-bit base64 value at data position d of raw. Bits
+// beyond the end of raw read as zero, as they do in the encoder.
+func sextet(raw []byte, d int) byte {
+	bit := d * 6
+	i := bit / 8
+	v := uint16(raw[i]) << 8
+	if i+1 < len(raw) {
+		v |= uint16(raw[i+1])
+	}
+	return byte(v>>(10-bit%8)) & 0x3f
+}
+
+// digitAt returns the value of the bits-wide digit at data position d of
+// raw, for any width up to 8. Bits beyond the end of raw read as zero.
+func digitAt(raw []byte, d, bits int) byte {
+	bit := d * bits
+	i := bit / 8
+	v := uint16(raw[i]) << 8
+	if i+1 < len(raw) {
+		v |= uint16(raw[i+1])
+	}
+	return byte(v>>(16-bits-bit%8)) & (1<<bits - 1)
+}
@@ -0,0 +1,100 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestLayoutTextLen(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		layout Layout
+		want   int
+	}{
+		{"ed25519 key", ed25519KeyLayout, 80},
+		{"ed25519 fingerprint", fingerprintLayout, 44},
+		{"SSHFP digest", sshfpLayout, 64},
+		{"onion address", onionLayout, 56},
+		{"age recipient", ageLayout, 62},
+		{"openpgp fingerprint", openpgpLayout, 40},
+		{"minisign public key", signifyKeyLayout, 56},
+		{"minisign key ID", keyIDLayout, 16},
+		{"libp2p peer ID", libp2pPeerIDLayout, 52},
+		{"libp2p CID", libp2pCIDLayout, 65},
+		{"padded base32", Layout{RawLen: 1, Encoding: Encoding{Alphabet: base32Alphabet, Bits: 5, Pad: true}}, 8},
+	}
+	for _, tt := range tests {
+		if got := tt.layout.TextLen(); got != tt.want {
+			t.Errorf("%s: TextLen() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSextetMatchesEncoder(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{1, 2, 3, 32, 51} {
+		raw := make([]byte, n)
+		if _, err := rand.Read(raw); err != nil {
+			t.Fatalf("rand.Read: %v", err)
+		}
+		text := base64.RawStdEncoding.EncodeToString(raw)
+		for d := range len(text) {
+			got := base64Alphabet[sextet(raw, d)]
+			if got != text[d] {
+				t.Errorf("len %d pos %d: sextet = %q, encoder = %q", n, d, got, text[d])
+			}
+		}
+		if l := (Layout{RawLen: n}); l.dataLen() != len(text) {
+			t.Errorf("len %d: dataLen() = %d, want %d", n, l.dataLen(), len(text))
+		}
+		if !strings.HasPrefix(base64.StdEncoding.EncodeToString(raw), text) {
+			t.Fatalf("len %d: padded and raw encodings disagree", n)
+		}
+	}
+}
+
+func TestNibbleMatchesEncoder(t *testing.T) {
+	t.Parallel()
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	text := hex.EncodeToString(raw)
+	l := Layout{RawLen: len(raw), Encoding: HexEncoding}
+	if l.dataLen() != len(text) {
+		t.Errorf("dataLen() = %d, want %d", l.dataLen(), len(text))
+	}
+	for d := range len(text) {
+		if got := l.alphabet()[l.digit()(raw, d)]; got != text[d] {
+			t.Errorf("pos %d: nibble = %q, encoder = %q", d, got, text[d])
+		}
+	}
+}
+
+func TestEncoderMatchesBase32(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{1, 4, 5, 35} {
+		raw := make([]byte, n)
+		if _, err := rand.Read(raw); err != nil {
+			t.Fatalf("rand.Read: %v", err)
+		}
+		want := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		for _, pad := range []bool{false, true} {
+			l := Layout{RawLen: n, Encoding: Encoding{Alphabet: base32Alphabet, Bits: 5, Pad: pad}}
+			text := make([]byte, l.TextLen())
+			l.encoder()(text, raw)
+			if pad && string(text) != want || !pad && string(text) != strings.TrimRight(want, "=") {
+				t.Errorf("len %d pad %v: encoder = %q, base32 = %q", n, pad, text, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package keygen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// KeyTypeLibP2P generates ed25519 identities for libp2p nodes, matched on
+// their peer ID or its CIDv1.
+const KeyTypeLibP2P KeyType = "libp2p"
+
+// libp2pKeyHeader starts the protobuf PublicKey message of an ed25519 key:
+// field 1 (Type) is 1 (Ed25519) and field 2 (Data) is 32 bytes long.
+var libp2pKeyHeader = []byte{0x08, 0x01, 0x12, ed25519.PublicKeySize}
+
+// libp2pPeerIDHeader starts the peer ID of an ed25519 key: the public key
+// message is short enough to be inlined as an identity multihash (code
+// 0x00) of its 36 bytes.
+var libp2pPeerIDHeader = append([]byte{0x00, byte(len(libp2pKeyHeader) + ed25519.PublicKeySize)}, libp2pKeyHeader...)
+
+// libp2pCIDHeader starts the CIDv1 of a peer ID: version 1 and the
+// libp2p-key multicodec (0x72).
+var libp2pCIDHeader = append([]byte{0x01, 0x72}, libp2pPeerIDHeader...)
+
+const (
+	// libp2pPeerIDPrefix starts the base58btc text of every ed25519 peer
+	// ID, as its leading bytes are constant.
+	libp2pPeerIDPrefix = "12D3KooW"
+	libp2pPeerIDLen    = 52
+	// libp2pCIDPrefix is the multibase prefix of lowercase, unpadded
+	// base32.
+	libp2pCIDPrefix = "b"
+)
+
+// libp2pPeerIDLayout is the 52-character base58btc peer ID, "12D3KooW..."
+// as go-libp2p and kubo print it. base58 is one big number, so its text is
+// only tested encoded.
+var libp2pPeerIDLayout = Layout{
+	Prefix:   libp2pPeerIDPrefix,
+	Head:     libp2pPeerIDHeader,
+	RawLen:   len(libp2pPeerIDHeader) + ed25519.PublicKeySize,
+	Encoding: Base58Encoding,
+	Tail:     libp2pPeerIDLen - len(libp2pPeerIDPrefix),
+	Checksum: func(raw, dst []byte) {
+		var text [libp2pPeerIDLen]byte
+		base58Encode(text[:], raw)
+		copy(dst, text[len(libp2pPeerIDPrefix):])
+	},
+}
+
+// libp2pCIDLayout is the 65-character CIDv1 of the peer ID in base32,
+// "bafzaa...", as IPNS names and kubo's --ipns-base=base32 show it.
+var libp2pCIDLayout = Layout{
+	Prefix:   libp2pCIDPrefix,
+	Head:     libp2pCIDHeader,
+	RawLen:   len(libp2pCIDHeader) + ed25519.PublicKeySize,
+	Encoding: Base32Encoding,
+}
+
+func init() {
+	sourceTypes[KeyTypeLibP2P] = sourceType{
+		layouts: map[Input]Layout{
+			InputPublicKey:   libp2pPeerIDLayout,
+			InputFingerprint: libp2pCIDLayout,
+		},
+		newSource: func(in Input) keySource {
+			return &libp2pSource{cid: in == InputFingerprint}
+		},
+	}
+}
+
+// libp2pSource generates ed25519 identities, writing the raw peer ID, or
+// its CID, of each.
+type libp2pSource struct {
+	cid  bool
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func (s *libp2pSource) next(dst []byte) error {
+	var err error
+	if s.pub, s.priv, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		return fmt.Errorf("generate ed25519 key: %w", err)
+	}
+	n := 0
+	if s.cid {
+		n = copy(dst, libp2pCIDHeader)
+	} else {
+		n = copy(dst, libp2pPeerIDHeader)
+	}
+	copy(dst[n:], s.pub)
+	return nil
+}
+
+func (s *libp2pSource) result() (Result, error) {
+	return Result{PrivateKey: s.priv, AuthorizedKey: LibP2PPeerID(s.pub), Fingerprint: LibP2PCID(s.pub)}, nil
+}
+
+// LibP2PPeerID returns the base58btc peer ID ("12D3KooW...") of the
+// ed25519 key pub.
+func LibP2PPeerID(pub ed25519.PublicKey) string {
+	raw := append(append([]byte{}, libp2pPeerIDHeader...), pub...)
+	text := make([]byte, libp2pPeerIDLen)
+	base58Encode(text, raw)
+	return string(text)
+}
+
+// LibP2PCID returns the CIDv1 of the peer ID of the ed25519 key pub, in
+// multibase base32 ("bafzaa...").
+func LibP2PCID(pub ed25519.PublicKey) string {
+	raw := append(append([]byte{}, libp2pCIDHeader...), pub...)
+	return libp2pCIDPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))
+}
@@ -0,0 +1,109 @@
+package keygen
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base32"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// base58Bytes decodes the base58btc text s of n bytes.
+func base58Bytes(t *testing.T, s string, n int) []byte {
+	t.Helper()
+	v := new(big.Int)
+	for _, c := range []byte(s) {
+		d := strings.IndexByte(base58Alphabet, c)
+		if d < 0 {
+			t.Fatalf("%q is not base58", s)
+		}
+		v.Mul(v, big.NewInt(58)).Add(v, big.NewInt(int64(d)))
+	}
+	return v.FillBytes(make([]byte, n))
+}
+
+func TestLibP2PPeerIDAndCID(t *testing.T) {
+	t.Parallel()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The identity multihash of the protobuf PublicKey message.
+	multihash := append([]byte{0x00, 0x24, 0x08, 0x01, 0x12, 0x20}, pub...)
+
+	id := LibP2PPeerID(pub)
+	if len(id) != libp2pPeerIDLayout.TextLen() || !strings.HasPrefix(id, "12D3KooW") {
+		t.Errorf("peer ID %q: want %d characters starting 12D3KooW", id, libp2pPeerIDLayout.TextLen())
+	}
+	if got := base58Bytes(t, id, len(multihash)); !bytes.Equal(got, multihash) {
+		t.Errorf("peer ID holds %x, want %x", got, multihash)
+	}
+
+	cid := LibP2PCID(pub)
+	if len(cid) != libp2pCIDLayout.TextLen() || !strings.HasPrefix(cid, "bafzaajaiaejc") {
+		t.Errorf("CID %q: want %d characters starting bafzaajaiaejc", cid, libp2pCIDLayout.TextLen())
+	}
+	got, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(cid[1:]))
+	if err != nil || !bytes.Equal(got, append([]byte{0x01, 0x72}, multihash...)) {
+		t.Errorf("CID holds %x (%v), want the libp2p-key CID of %x", got, err, multihash)
+	}
+}
+
+func TestLibP2PLayoutEncoder(t *testing.T) {
+	t.Parallel()
+
+	for _, pub := range []ed25519.PublicKey{make([]byte, 32), bytes.Repeat([]byte{0xff}, 32)} {
+		raw := append(append([]byte{}, libp2pPeerIDHeader...), pub...)
+		text := make([]byte, libp2pPeerIDLayout.TextLen()-len(libp2pPeerIDLayout.Prefix))
+		libp2pPeerIDLayout.encoder()(text, raw)
+		if got, want := libp2pPeerIDLayout.Prefix+string(text), LibP2PPeerID(pub); got != want {
+			t.Errorf("encoder = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFindKeys_LibP2P(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		pattern string
+		in      Input
+	}{
+		{`^12D3KooWA`, InputPublicKey},
+		{`(?i)x$`, InputPublicKey},
+		{`^bafzaajaiaejca`, InputFingerprint},
+		{`q7$`, InputFingerprint},
+	} {
+		re := regexp.MustCompile(tt.pattern)
+		m, err := NewMatcher(re, tt.in)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		errc := make(chan error, 1)
+		go func() { errc <- FindKeys(ctx, Options{Matcher: m, Type: KeyTypeLibP2P}, results) }()
+		r := <-results
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatalf("FindKeys: %v", err)
+		}
+
+		priv, ok := r.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("PrivateKey is %T, want ed25519.PrivateKey", r.PrivateKey)
+		}
+		pub := priv.Public().(ed25519.PublicKey)
+		if r.AuthorizedKey != LibP2PPeerID(pub) || r.Fingerprint != LibP2PCID(pub) {
+			t.Errorf("peer ID %q and CID %q are not those of the key", r.AuthorizedKey, r.Fingerprint)
+		}
+		if !re.MatchString(r.Text(tt.in)) {
+			t.Errorf("%q does not match %s", tt.pattern, r.Text(tt.in))
+		}
+	}
+}
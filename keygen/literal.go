@@ -0,0 +1,289 @@
+package keygen
+
+import (
+	"bytes"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+const (
+	base64Alphabet   = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	hexAlphabet      = "0123456789abcdef"
+	upperHexAlphabet = "0123456789ABCDEF"
+	base32Alphabet   = "abcdefghijklmnopqrstuvwxyz234567"
+	bech32Alphabet   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	base58Alphabet   = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// Anchor describes where a LiteralMatcher's literal must occur.
+type Anchor int
+
+const (
+	// AnchorNone matches the literal anywhere in the candidate.
+	AnchorNone Anchor = iota
+	// AnchorStart matches the literal at the start of the candidate.
+	AnchorStart
+	// AnchorEnd matches the literal at the end of the candidate.
+	AnchorEnd
+	// AnchorBoth requires the candidate to equal the literal.
+	AnchorBoth
+)
+
+// RawMatcher is an optional interface for Matchers that can test the raw
+// bytes behind a representation instead of its encoded text. FindKeys calls
+// BindRaw once per worker with the Layout of the selected Input; if ok is
+// true, the returned function replaces Match in the hot loop.
+type RawMatcher interface {
+	Matcher
+	BindRaw(l Layout) (match func(raw []byte) bool, ok bool)
+}
+
+// LiteralMatcher matches a fixed string, optionally anchored and
+// ASCII case-folded, without going through regexp.
+type LiteralMatcher struct {
+	lit    []byte // lowercased when fold is set
+	fold   bool
+	anchor Anchor
+	input  Input
+}
+
+// NewMatcher returns the fastest Matcher for re: a LiteralMatcher when the
+// pattern reduces to a plain, optionally anchored or case-folded literal,
+// and a RegexMatcher otherwise.
+func NewMatcher(re *regexp.Regexp, in Input) (Matcher, error) {
+	if re == nil {
+		return nil, ErrNilRegex
+	}
+	if lit, fold, anchor, ok := analyzeLiteral(re.String()); ok {
+		return NewLiteralMatcher(lit, fold, anchor, in), nil
+	}
+	return NewRegexMatcher(re, in)
+}
+
+// NewLiteralMatcher returns a Matcher for lit. When fold is set, ASCII
+// letters match regardless of case.
+func NewLiteralMatcher(lit string, fold bool, anchor Anchor, in Input) *LiteralMatcher {
+	if fold {
+		lit = strings.ToLower(lit)
+	}
+	return &LiteralMatcher{lit: []byte(lit), fold: fold, anchor: anchor, input: in}
+}
+
+// analyzeLiteral reports whether pattern is a non-empty ASCII literal,
+// optionally wrapped in captures and anchored with ^ and/or $.
+func analyzeLiteral(pattern string) (lit string, fold bool, anchor Anchor, ok bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false, AnchorNone, false
+	}
+	re = unwrapCaptures(re.Simplify())
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	var start, end bool
+	if len(subs) > 0 && subs[0].Op == syntax.OpBeginText {
+		start = true
+		subs = subs[1:]
+	}
+	if len(subs) > 0 && subs[len(subs)-1].Op == syntax.OpEndText {
+		end = true
+		subs = subs[:len(subs)-1]
+	}
+	if len(subs) != 1 {
+		return "", false, AnchorNone, false
+	}
+	l := unwrapCaptures(subs[0])
+	if l.Op != syntax.OpLiteral || len(l.Rune) == 0 {
+		return "", false, AnchorNone, false
+	}
+	for _, r := range l.Rune {
+		if r > 0x7f {
+			return "", false, AnchorNone, false
+		}
+	}
+
+	switch {
+	case start && end:
+		anchor = AnchorBoth
+	case start:
+		anchor = AnchorStart
+	case end:
+		anchor = AnchorEnd
+	}
+	fold = l.Flags&syntax.FoldCase != 0
+	lit = string(l.Rune)
+	if fold {
+		lit = strings.ToLower(lit)
+	}
+	return lit, fold, anchor, true
+}
+
+// unwrapCaptures strips capture groups around a single subexpression.
+func unwrapCaptures(re *syntax.Regexp) *syntax.Regexp {
+	for re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+	return re
+}
+
+// Input reports which representation the matcher expects.
+func (m *LiteralMatcher) Input() Input { return m.input }
+
+// String returns the name of the matching engine.
+func (m *LiteralMatcher) String() string {
+	var s string
+	switch m.anchor {
+	case AnchorStart:
+		s = "literal prefix"
+	case AnchorEnd:
+		s = "literal suffix"
+	case AnchorBoth:
+		s = "literal exact"
+	default:
+		s = "literal substring"
+	}
+	if m.fold {
+		s += " (?i)"
+	}
+	return s
+}
+
+// Match reports whether candidate contains the literal at the anchored
+// position.
+func (m *LiteralMatcher) Match(candidate []byte) bool {
+	n := len(m.lit)
+	switch m.anchor {
+	case AnchorStart:
+		return len(candidate) >= n && m.equal(candidate[:n])
+	case AnchorEnd:
+		return len(candidate) >= n && m.equal(candidate[len(candidate)-n:])
+	case AnchorBoth:
+		return len(candidate) == n && m.equal(candidate)
+	}
+	if !m.fold {
+		return bytes.Contains(candidate, m.lit)
+	}
+	for i := 0; i+n <= len(candidate); i++ {
+		if m.equal(candidate[i : i+n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares b, which must have the literal's length, to the literal.
+func (m *LiteralMatcher) equal(b []byte) bool {
+	if !m.fold {
+		return bytes.Equal(b, m.lit)
+	}
+	for i, c := range b {
+		if lower(c) != m.lit[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// digitCheck tests one data position of the raw bytes against the one or
+// two digit values (upper and lower case) the literal allows there.
+type digitCheck struct {
+	pos  int
+	a, b byte
+}
+
+// BindRaw resolves an anchored literal against l. Positions inside the
+// constant prefix and padding are checked once here; the returned function
+// only compares the digits of raw that the literal covers, so the hot loop
+// never encodes the key. Unanchored literals and literals reaching into a
+// checksum Tail are not supported.
+func (m *LiteralMatcher) BindRaw(l Layout) (func(raw []byte) bool, bool) {
+	never := func([]byte) bool { return false }
+
+	textLen := l.TextLen()
+	var offset int
+	switch m.anchor {
+	case AnchorStart:
+	case AnchorEnd:
+		offset = textLen - len(m.lit)
+	case AnchorBoth:
+		if len(m.lit) != textLen {
+			return never, true
+		}
+	default:
+		return nil, false
+	}
+	if offset < 0 || offset+len(m.lit) > textLen {
+		return never, true
+	}
+
+	dataLen, padEnd := l.dataLen(), l.dataLen()+l.padLen()
+	alphabet, digit := l.alphabet(), l.digit()
+	var checks []digitCheck
+	for i, c := range m.lit {
+		t := offset + i
+		d := t - len(l.Prefix)
+		switch {
+		case d < 0:
+			if !m.equal1(l.Prefix[t], c) {
+				return never, true
+			}
+		case d >= padEnd:
+			// The checksum is not a function of a few raw bits.
+			return nil, false
+		case d >= dataLen:
+			if c != '=' {
+				return never, true
+			}
+		default:
+			a := strings.IndexByte(alphabet, c)
+			b := a
+			if m.fold {
+				b = strings.IndexByte(alphabet, upper(c))
+			}
+			switch {
+			case a < 0 && b < 0:
+				return never, true
+			case a < 0:
+				a = b
+			case b < 0:
+				b = a
+			}
+			checks = append(checks, digitCheck{pos: d, a: byte(a), b: byte(b)})
+		}
+	}
+
+	return func(raw []byte) bool {
+		for _, c := range checks {
+			v := digit(raw, c.pos)
+			if v != c.a && v != c.b {
+				return false
+			}
+		}
+		return true
+	}, true
+}
+
+// equal1 compares a single text byte to a literal byte.
+func (m *LiteralMatcher) equal1(text, lit byte) bool {
+	if m.fold {
+		return lower(text) == lit
+	}
+	return text == lit
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func upper(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
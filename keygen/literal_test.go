@@ -0,0 +1,244 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeLiteral(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern    string
+		wantOK     bool
+		wantLit    string
+		wantFold   bool
+		wantAnchor Anchor
+	}{
+		{`abc`, true, "abc", false, AnchorNone},
+		{`^abc`, true, "abc", false, AnchorStart},
+		{`xyz$`, true, "xyz", false, AnchorEnd},
+		{`^xyz$`, true, "xyz", false, AnchorBoth},
+		{`(?i)word$`, true, "word", true, AnchorEnd},
+		{`(abc)$`, true, "abc", false, AnchorEnd},
+		{`^a\+b`, true, "a+b", false, AnchorStart},
+		{`a.c`, false, "", false, AnchorNone},
+		{`ab|cd`, false, "", false, AnchorNone},
+		{`^`, false, "", false, AnchorNone},
+		{``, false, "", false, AnchorNone},
+		{`a+`, false, "", false, AnchorNone},
+		{`(?m)abc$`, false, "", false, AnchorNone},
+		{`é$`, false, "", false, AnchorNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			t.Parallel()
+			lit, fold, anchor, ok := analyzeLiteral(tt.pattern)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if lit != tt.wantLit || fold != tt.wantFold || anchor != tt.wantAnchor {
+				t.Errorf("got (%q, %v, %v), want (%q, %v, %v)",
+					lit, fold, anchor, tt.wantLit, tt.wantFold, tt.wantAnchor)
+			}
+		})
+	}
+}
+
+func TestNewMatcher_EngineSelection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{`^ssh`, "literal prefix"},
+		{`xyz$`, "literal suffix"},
+		{`(?i)word$`, "literal suffix (?i)"},
+		{`abc`, "literal substring"},
+		{`^abc$`, "literal exact"},
+		{`[ab]c$`, "regexp"},
+	}
+	for _, tt := range tests {
+		m, err := NewMatcher(regexp.MustCompile(tt.pattern), InputPublicKey)
+		if err != nil {
+			t.Fatalf("NewMatcher(%q): %v", tt.pattern, err)
+		}
+		s, ok := m.(interface{ String() string })
+		if !ok {
+			t.Fatalf("NewMatcher(%q) returned %T without String", tt.pattern, m)
+		}
+		if got := s.String(); got != tt.want {
+			t.Errorf("NewMatcher(%q) engine = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestNewMatcher_NilRegex(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewMatcher(nil, InputPublicKey); err == nil {
+		t.Error("want error for nil regex, got nil")
+	}
+}
+
+// literalPatterns covers every anchor, folding, and the prefix/padding
+// edge cases of the ed25519 layouts and the hex and base32 encodings.
+var literalPatterns = []string{
+	`^ssh-ed25519 AAAA`,
+	`^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI`,
+	`^ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA`,
+	`^SSH`,
+	`(?i)^SSH-ED25519 aaaac3`,
+	`^ssh-rsa`,
+	`A$`,
+	`(?i)a$`,
+	`=$`,
+	`A=$`,
+	`(?i)q=$`,
+	`^A`,
+	`(?i)^a`,
+	`(?i)^ab`,
+	`Zz`,
+	`(?i)zz`,
+	`^x$`,
+	`\+/$`,
+	`-$`,
+	`^0f`,
+	`(?i)^AB`,
+	`9e$`,
+	`^p:a7`,
+	`(?i)^P:Q`,
+	`ba$`,
+}
+
+func TestLiteralMatcher_MatchEquivalence(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range literalPatterns {
+		re := regexp.MustCompile(pattern)
+		m, err := NewMatcher(re, InputPublicKey)
+		if err != nil {
+			t.Fatalf("NewMatcher(%q): %v", pattern, err)
+		}
+		if _, ok := m.(*LiteralMatcher); !ok {
+			t.Fatalf("NewMatcher(%q) = %T, want *LiteralMatcher", pattern, m)
+		}
+		for _, text := range []string{
+			"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAbcZzA=",
+			"SSH-ed25519 aaaaC3xyzzq=",
+			"x", "", "A=", "ab+/", "-",
+		} {
+			if got, want := m.Match([]byte(text)), re.MatchString(text); got != want {
+				t.Errorf("%q.Match(%q) = %v, regexp = %v", pattern, text, got, want)
+			}
+		}
+	}
+}
+
+func TestLiteralMatcher_BindRawEquivalence(t *testing.T) {
+	t.Parallel()
+
+	layouts := []Layout{ed25519KeyLayout, fingerprintLayout, sshfpLayout, {Prefix: "p:", RawLen: 2}, {Prefix: "p:", RawLen: 2, Encoding: HexEncoding}, {Prefix: "p:", RawLen: 3, Encoding: Base32Encoding}}
+	for _, pattern := range literalPatterns {
+		re := regexp.MustCompile(pattern)
+		lit, fold, anchor, ok := analyzeLiteral(pattern)
+		if !ok {
+			t.Fatalf("analyzeLiteral(%q) not ok", pattern)
+		}
+		m := NewLiteralMatcher(lit, fold, anchor, InputPublicKey)
+		for _, l := range layouts {
+			match, ok := m.BindRaw(l)
+			if anchor == AnchorNone {
+				if ok {
+					t.Errorf("%q: BindRaw ok for unanchored literal", pattern)
+				}
+				continue
+			}
+			if !ok {
+				t.Fatalf("%q: BindRaw not ok", pattern)
+			}
+			raw := make([]byte, l.RawLen)
+			for range 2000 {
+				if _, err := rand.Read(raw); err != nil {
+					t.Fatalf("rand.Read: %v", err)
+				}
+				text := l.Prefix + base64.StdEncoding.EncodeToString(raw)
+				switch l.Encoding {
+				case HexEncoding:
+					text = l.Prefix + hex.EncodeToString(raw)
+				case Base32Encoding:
+					text = l.Prefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))
+				}
+				if got, want := match(raw), re.MatchString(text); got != want {
+					t.Fatalf("%q on %q: raw = %v, regexp = %v", pattern, text, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLiteralMatcher_BindRawTail(t *testing.T) {
+	t.Parallel()
+
+	// Literals reaching into a checksum, or into a base58 number, fall
+	// back to matching the text.
+	for _, tt := range []struct {
+		pattern string
+		layout  Layout
+		ok      bool
+	}{
+		{`^age1qq`, ageLayout, true},
+		{`qqqqqq$`, ageLayout, false},
+		{`^age1b`, ageLayout, true},
+		{`^12D3Koo`, libp2pPeerIDLayout, true},
+		{`^12D3KooWA`, libp2pPeerIDLayout, false},
+		{`^Qm`, libp2pPeerIDLayout, true},
+	} {
+		lit, fold, anchor, ok := analyzeLiteral(tt.pattern)
+		if !ok {
+			t.Fatalf("analyzeLiteral(%q) not ok", tt.pattern)
+		}
+		if _, ok := NewLiteralMatcher(lit, fold, anchor, InputPublicKey).BindRaw(tt.layout); ok != tt.ok {
+			t.Errorf("%q: BindRaw ok = %v, want %v", tt.pattern, ok, tt.ok)
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	wireKey := ed25519Wire.newBuf()
+	if _, err := rand.Read(wireKey[ed25519Wire.keyOffset():]); err != nil {
+		b.Fatalf("rand.Read: %v", err)
+	}
+	text := []byte(ed25519KeyLayout.Prefix + base64.StdEncoding.EncodeToString(wireKey))
+	re := regexp.MustCompile(`(?i)vanity$`)
+
+	b.Run("regexp", func(b *testing.B) {
+		m, err := NewRegexMatcher(re, InputPublicKey)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for b.Loop() {
+			base64.StdEncoding.Encode(text[len(ed25519KeyLayout.Prefix):], wireKey)
+			m.Match(text)
+		}
+	})
+	b.Run("literal", func(b *testing.B) {
+		m, err := NewMatcher(re, InputPublicKey)
+		if err != nil {
+			b.Fatal(err)
+		}
+		match, _ := m.(RawMatcher).BindRaw(ed25519KeyLayout)
+		for b.Loop() {
+			match(wireKey)
+		}
+	})
+}
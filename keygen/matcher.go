@@ -0,0 +1,76 @@
+package keygen
+
+import (
+	"errors"
+	"regexp"
+)
+
+// ErrNilMatcher is returned when FindKeys is called without a Matcher.
+var ErrNilMatcher = errors.New("matcher must not be nil")
+
+// ErrNilRegex is returned when a regex matcher is built from a nil regex.
+var ErrNilRegex = errors.New("regex must not be nil")
+
+// Input identifies which representation of a candidate key a Matcher
+// inspects.
+type Input int
+
+const (
+	// InputPublicKey is the public key text: for SSH key types, the
+	// authorized_keys line ("ssh-ed25519 AAAA...") without a comment.
+	InputPublicKey Input = iota
+	// InputFingerprint is the base64 SHA256 fingerprint, without the
+	// "SHA256:" prefix.
+	InputFingerprint
+	// InputSSHFP is the lowercase hex SHA256 digest of the key, as an
+	// SSHFP DNS record (fingerprint type 2) carries it.
+	InputSSHFP
+)
+
+// String returns a short human-readable name for the input.
+func (in Input) String() string {
+	switch in {
+	case InputPublicKey:
+		return "public key"
+	case InputFingerprint:
+		return "fingerprint"
+	case InputSSHFP:
+		return "SSHFP digest"
+	default:
+		return "unknown"
+	}
+}
+
+// Matcher decides whether a candidate key is a hit. Implementations are
+// shared by all workers and must be safe for concurrent use.
+type Matcher interface {
+	// Match reports whether the candidate representation is a hit. The
+	// slice is only valid for the duration of the call.
+	Match(candidate []byte) bool
+	// Input reports which representation Match expects.
+	Input() Input
+}
+
+// RegexMatcher matches a representation against a regular expression.
+type RegexMatcher struct {
+	re    *regexp.Regexp
+	input Input
+}
+
+// NewRegexMatcher returns a Matcher that tests the given representation
+// against re.
+func NewRegexMatcher(re *regexp.Regexp, in Input) (*RegexMatcher, error) {
+	if re == nil {
+		return nil, ErrNilRegex
+	}
+	return &RegexMatcher{re: re, input: in}, nil
+}
+
+// Match reports whether candidate contains a match of the regex.
+func (m *RegexMatcher) Match(candidate []byte) bool { return m.re.Match(candidate) }
+
+// Input reports which representation the matcher expects.
+func (m *RegexMatcher) Input() Input { return m.input }
+
+// String returns the name of the matching engine.
+func (m *RegexMatcher) String() string { return "regexp" }
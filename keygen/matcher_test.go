@@ -0,0 +1,109 @@
+package keygen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixMatcher is a minimal custom Matcher used to verify that FindKeys
+// accepts implementations other than RegexMatcher.
+type prefixMatcher struct {
+	prefix []byte
+	input  Input
+}
+
+func (m prefixMatcher) Match(candidate []byte) bool { return bytes.HasPrefix(candidate, m.prefix) }
+func (m prefixMatcher) Input() Input                { return m.input }
+
+func TestNewRegexMatcher_NilRegex(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewRegexMatcher(nil, InputPublicKey)
+	if !errors.Is(err, ErrNilRegex) {
+		t.Errorf("error = %v, want %v", err, ErrNilRegex)
+	}
+	if m != nil {
+		t.Errorf("matcher = %v, want nil", m)
+	}
+}
+
+func TestRegexMatcher(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewRegexMatcher(regexp.MustCompile(`(?i)abc$`), InputFingerprint)
+	if err != nil {
+		t.Fatalf("NewRegexMatcher: %v", err)
+	}
+	if got := m.Input(); got != InputFingerprint {
+		t.Errorf("Input() = %v, want %v", got, InputFingerprint)
+	}
+	if !m.Match([]byte("xyzABC")) {
+		t.Error("Match(xyzABC) = false, want true")
+	}
+	if m.Match([]byte("abcxyz")) {
+		t.Error("Match(abcxyz) = true, want false")
+	}
+	if got := m.String(); got != "regexp" {
+		t.Errorf("String() = %q, want %q", got, "regexp")
+	}
+}
+
+func TestInputString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   Input
+		want string
+	}{
+		{InputPublicKey, "public key"},
+		{InputFingerprint, "fingerprint"},
+		{InputSSHFP, "SSHFP digest"},
+		{Input(99), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Input(%d).String() = %q, want %q", int(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestFindKeys_UnsupportedInput(t *testing.T) {
+	t.Parallel()
+
+	results := make(chan Result, 1)
+	m := prefixMatcher{prefix: []byte("ssh"), input: Input(99)}
+	err := FindKeys(context.Background(), Options{Matcher: m}, results)
+	if err == nil || !strings.Contains(err.Error(), "unsupported matcher input") {
+		t.Errorf("error = %v, want unsupported matcher input", err)
+	}
+}
+
+func TestFindKeys_CustomMatcher(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m := prefixMatcher{prefix: []byte("ssh-ed25519 AAAA"), input: InputPublicKey}
+	results := make(chan Result, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- FindKeys(ctx, Options{Matcher: m}, results)
+	}()
+
+	select {
+	case r := <-results:
+		assertResultFields(t, r)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for custom matcher result")
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("FindKeys error: %v", err)
+	}
+}
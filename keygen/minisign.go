@@ -0,0 +1,120 @@
+package keygen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeyTypeMinisign and KeyTypeSignify generate ed25519 signing keys for
+// minisign and OpenBSD's signify, matched on their base64 public key or
+// their key ID.
+const (
+	KeyTypeMinisign KeyType = "minisign"
+	KeyTypeSignify  KeyType = "signify"
+)
+
+// SignifyKeyNumSize is the size of the random key number that minisign and
+// signify keys, and the signatures they make, carry to tell keys apart.
+const SignifyKeyNumSize = 8
+
+// signifyAlgorithm starts every minisign and signify public key, secret key
+// and signature.
+const signifyAlgorithm = "Ed"
+
+// signifyKeyLayout is the 56-character base64 public key both tools write:
+// "Ed", the key number and the ed25519 public key. Its first two
+// characters are always "RW".
+var signifyKeyLayout = Layout{
+	Head:   []byte(signifyAlgorithm),
+	RawLen: len(signifyAlgorithm) + SignifyKeyNumSize + ed25519.PublicKeySize,
+}
+
+// keyIDLayout is the 16-character key ID: the key number in uppercase hex,
+// read as a little-endian integer by minisign and in order by signify.
+var keyIDLayout = Layout{RawLen: SignifyKeyNumSize, Encoding: UpperHexEncoding}
+
+func init() {
+	for _, kt := range []KeyType{KeyTypeMinisign, KeyTypeSignify} {
+		minisign := kt == KeyTypeMinisign
+		sourceTypes[kt] = sourceType{
+			layouts: map[Input]Layout{
+				InputPublicKey:   signifyKeyLayout,
+				InputFingerprint: keyIDLayout,
+			},
+			newSource: func(in Input) keySource {
+				return &signifySource{minisign: minisign, keyID: in == InputFingerprint}
+			},
+		}
+	}
+}
+
+// signifySource generates minisign or signify keys. Matching the key ID, it
+// keeps a key pair and draws key numbers for it until one matches, as the
+// key number is independent of the key; matching the public key, every
+// candidate is a new key pair.
+type signifySource struct {
+	minisign bool
+	keyID    bool
+	priv     ed25519.PrivateKey
+	keyNum   [SignifyKeyNumSize]byte
+}
+
+func (s *signifySource) next(dst []byte) error {
+	if s.priv == nil || !s.keyID {
+		var err error
+		if _, s.priv, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return fmt.Errorf("generate ed25519 key: %w", err)
+		}
+	}
+	if _, err := rand.Read(s.keyNum[:]); err != nil {
+		return fmt.Errorf("generate key number: %w", err)
+	}
+	if !s.keyID {
+		signifyPublicKey(dst, s.priv.Public().(ed25519.PublicKey), s.keyNum)
+		return nil
+	}
+	copy(dst, s.keyNum[:])
+	if s.minisign {
+		binary.BigEndian.PutUint64(dst, binary.LittleEndian.Uint64(s.keyNum[:]))
+	}
+	return nil
+}
+
+func (s *signifySource) result() (Result, error) {
+	pub := s.priv.Public().(ed25519.PublicKey)
+	raw := make([]byte, signifyKeyLayout.RawLen)
+	signifyPublicKey(raw, pub, s.keyNum)
+	keyID := SignifyKeyID(s.keyNum)
+	if s.minisign {
+		keyID = MinisignKeyID(s.keyNum)
+	}
+	r := Result{PrivateKey: s.priv, AuthorizedKey: base64.StdEncoding.EncodeToString(raw), Fingerprint: keyID}
+	// Every match gets a key pair of its own, not the same one under
+	// another key number.
+	s.priv = nil
+	return r, nil
+}
+
+// signifyPublicKey writes the raw public key of pub under keyNum into dst.
+func signifyPublicKey(dst []byte, pub ed25519.PublicKey, keyNum [SignifyKeyNumSize]byte) {
+	n := copy(dst, signifyAlgorithm)
+	n += copy(dst[n:], keyNum[:])
+	copy(dst[n:], pub)
+}
+
+// MinisignKeyID returns the key ID minisign shows for keyNum: the key
+// number as a little-endian integer in uppercase hex.
+func MinisignKeyID(keyNum [SignifyKeyNumSize]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyNum[:]))
+}
+
+// SignifyKeyID returns the key number of a signify key in uppercase hex,
+// which signify itself never shows.
+func SignifyKeyID(keyNum [SignifyKeyNumSize]byte) string {
+	return strings.ToUpper(hex.EncodeToString(keyNum[:]))
+}
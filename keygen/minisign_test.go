@@ -0,0 +1,99 @@
+package keygen
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestSignifyKeyIDs(t *testing.T) {
+	t.Parallel()
+
+	keyNum := [SignifyKeyNumSize]byte{0x4d, 0x3c, 0x2b, 0x1a, 0x7f, 0x4e, 0x8a, 0x05}
+	if got, want := MinisignKeyID(keyNum), "058A4E7F1A2B3C4D"; got != want {
+		t.Errorf("MinisignKeyID = %s, want %s", got, want)
+	}
+	if got, want := SignifyKeyID(keyNum), "4D3C2B1A7F4E8A05"; got != want {
+		t.Errorf("SignifyKeyID = %s, want %s", got, want)
+	}
+}
+
+func TestSignifySource_KeyID(t *testing.T) {
+	t.Parallel()
+
+	s := &signifySource{minisign: true, keyID: true}
+	raw := make([]byte, keyIDLayout.RawLen)
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	first, firstNum := s.priv, s.keyNum
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	if !first.Equal(s.priv) || s.keyNum == firstNum {
+		t.Error("key ID candidates should share the key pair and differ in key number")
+	}
+	r, err := s.result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%X", raw); got != r.Fingerprint {
+		t.Errorf("matched %s, want the key ID %s", got, r.Fingerprint)
+	}
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	if first.Equal(s.priv) {
+		t.Error("source kept its key pair after a match")
+	}
+}
+
+func TestFindKeys_Signify(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		kt      KeyType
+		pattern string
+		in      Input
+	}{
+		{KeyTypeMinisign, `^RWQ`, InputPublicKey},
+		{KeyTypeMinisign, `(?i)^cafe`, InputFingerprint},
+		{KeyTypeSignify, `x$`, InputPublicKey},
+		{KeyTypeSignify, `^0[A-F]`, InputFingerprint},
+	} {
+		re := regexp.MustCompile(tt.pattern)
+		m, err := NewMatcher(re, tt.in)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		errc := make(chan error, 1)
+		go func() { errc <- FindKeys(ctx, Options{Matcher: m, Type: tt.kt}, results) }()
+		r := <-results
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatalf("FindKeys: %v", err)
+		}
+
+		priv, ok := r.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("PrivateKey is %T, want ed25519.PrivateKey", r.PrivateKey)
+		}
+		blob, err := base64.StdEncoding.DecodeString(r.AuthorizedKey)
+		if err != nil || len(r.AuthorizedKey) != signifyKeyLayout.TextLen() || string(blob[:2]) != "Ed" || !bytes.Equal(blob[10:], priv.Public().(ed25519.PublicKey)) {
+			t.Fatalf("%s %q: public key %q does not hold the key", tt.kt, tt.pattern, r.AuthorizedKey)
+		}
+		keyID := SignifyKeyID([SignifyKeyNumSize]byte(blob[2:10]))
+		if tt.kt == KeyTypeMinisign {
+			keyID = MinisignKeyID([SignifyKeyNumSize]byte(blob[2:10]))
+		}
+		if r.Fingerprint != keyID || !re.MatchString(r.Text(tt.in)) {
+			t.Errorf("%s %q: key ID %q, want %q; matched text %q", tt.kt, tt.pattern, r.Fingerprint, keyID, r.Text(tt.in))
+		}
+	}
+}
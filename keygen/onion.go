@@ -0,0 +1,78 @@
+package keygen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha3"
+	"fmt"
+)
+
+// KeyTypeOnion generates ed25519 keys for Tor v3 onion services, matched on
+// their onion address.
+const KeyTypeOnion KeyType = "onion"
+
+const (
+	onionChecksumPrefix = ".onion checksum"
+	onionVersion        = 3
+)
+
+// onionMessage is the checksummed message: the prefix, public key and
+// version.
+type onionMessage [len(onionChecksumPrefix) + ed25519.PublicKeySize + 1]byte
+
+// onionLayout is the 56-character address without ".onion": the base32 of
+// pubkey || checksum[:2] || version (rend-spec-v3). The version byte makes
+// every address end in [aiqy]d.
+var onionLayout = Layout{RawLen: ed25519.PublicKeySize + 3, Foot: []byte{onionVersion}, Encoding: Base32Encoding}
+
+func init() {
+	sourceTypes[KeyTypeOnion] = sourceType{
+		layouts:   map[Input]Layout{InputPublicKey: onionLayout},
+		newSource: func(Input) keySource { return &onionSource{} },
+	}
+}
+
+// onionSource generates onion service keys, writing pubkey || checksum ||
+// version for each.
+type onionSource struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+	msg  onionMessage // reused so the hot loop never allocates
+}
+
+func (s *onionSource) next(dst []byte) error {
+	var err error
+	s.pub, s.priv, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate ed25519 key: %w", err)
+	}
+	onionRaw(dst, s.pub, &s.msg)
+	return nil
+}
+
+func (s *onionSource) result() (Result, error) {
+	return Result{PrivateKey: s.priv, AuthorizedKey: OnionAddress(s.pub)}, nil
+}
+
+// onionRaw writes the raw bytes of pub's onion address into dst, building
+// the checksummed message in msg.
+func onionRaw(dst []byte, pub ed25519.PublicKey, msg *onionMessage) {
+	n := copy(msg[:], onionChecksumPrefix)
+	n += copy(msg[n:], pub)
+	msg[n] = onionVersion
+	checksum := sha3.Sum256(msg[:])
+	copy(dst, pub)
+	dst[ed25519.PublicKeySize], dst[ed25519.PublicKeySize+1] = checksum[0], checksum[1]
+	dst[ed25519.PublicKeySize+2] = onionVersion
+}
+
+// OnionAddress returns the Tor v3 onion address of pub, without the
+// ".onion" suffix.
+func OnionAddress(pub ed25519.PublicKey) string {
+	var msg onionMessage
+	raw := make([]byte, onionLayout.RawLen)
+	onionRaw(raw, pub, &msg)
+	text := make([]byte, onionLayout.dataLen())
+	onionLayout.encoder()(text, raw)
+	return string(text)
+}
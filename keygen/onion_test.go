@@ -0,0 +1,70 @@
+package keygen
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha3"
+	"encoding/base32"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// referenceOnionAddress computes the address as rend-spec-v3 describes it,
+// with the standard library's base32 encoder.
+func referenceOnionAddress(pub ed25519.PublicKey) string {
+	checksum := sha3.Sum256(append(append([]byte(".onion checksum"), pub...), 3))
+	raw := append(append(append([]byte{}, pub...), checksum[:2]...), 3)
+	return strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+}
+
+func TestOnionAddress(t *testing.T) {
+	t.Parallel()
+
+	for range 50 {
+		pub, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, want := OnionAddress(pub), referenceOnionAddress(pub)
+		if got != want {
+			t.Fatalf("OnionAddress = %q, want %q", got, want)
+		}
+		if len(got) != onionLayout.TextLen() || !strings.HasSuffix(got, "d") {
+			t.Fatalf("address %q: want %d characters ending in d", got, onionLayout.TextLen())
+		}
+	}
+}
+
+func TestFindKeys_Onion(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{`^ab`, `(?i)^Q`, `[a-f]`, `yd$`} {
+		re := regexp.MustCompile(pattern)
+		m, err := NewMatcher(re, InputPublicKey)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		errc := make(chan error, 1)
+		go func() { errc <- FindKeys(ctx, Options{Matcher: m, Type: KeyTypeOnion}, results) }()
+		r := <-results
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatalf("FindKeys: %v", err)
+		}
+
+		priv, ok := r.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("PrivateKey is %T, want ed25519.PrivateKey", r.PrivateKey)
+		}
+		want := referenceOnionAddress(priv.Public().(ed25519.PublicKey))
+		if r.AuthorizedKey != want || r.Text(InputPublicKey) != want || !re.MatchString(want) {
+			t.Errorf("%q: AuthorizedKey = %q, want matching %q", pattern, r.AuthorizedKey, want)
+		}
+		if r.Fingerprint != "" || r.PrivateKeyPEM != nil {
+			t.Errorf("%q: onion result has SSH fields set", pattern)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package keygen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielewood/vanityssh-go/keyfile"
+)
+
+// KeyTypeOpenPGP generates ed25519 OpenPGP v4 keys, matched on their
+// fingerprint.
+const KeyTypeOpenPGP KeyType = "openpgp"
+
+// openpgpWindow is how far back creation times are searched. A v4
+// fingerprint covers the creation time, so every second of the window gives
+// another fingerprint for the same key pair.
+const openpgpWindow = 365 * 24 * time.Hour
+
+// openpgpKeyBody is the length of the body of an ed25519 public key packet,
+// as keyfile.OpenPGPPublicKeyBody writes it: version, creation time,
+// algorithm, the 9-byte curve OID and the point as an MPI.
+const openpgpKeyBody = 1 + 4 + 1 + 1 + 9 + 2 + 1 + ed25519.PublicKeySize
+
+// openpgpHashed is what a v4 fingerprint hashes: 0x99, the two-byte body
+// length and the public key packet body.
+type openpgpHashed [3 + openpgpKeyBody]byte
+
+// openpgpCreatedOffset is the index of the creation time in openpgpHashed.
+const openpgpCreatedOffset = 4
+
+// openpgpLayout is the 40-character fingerprint in uppercase hex, as gpg
+// prints it; long and short key IDs are its last 16 and 8 characters.
+var openpgpLayout = Layout{RawLen: sha1.Size, Encoding: UpperHexEncoding}
+
+func init() {
+	sourceTypes[KeyTypeOpenPGP] = sourceType{
+		layouts: map[Input]Layout{
+			InputPublicKey:   openpgpLayout,
+			InputFingerprint: openpgpLayout,
+		},
+		newSource: func(Input) keySource { return &openpgpSource{} },
+	}
+}
+
+// openpgpSource searches (key pair, creation time) pairs: each key pair is
+// tried at every second of the window back from when it was generated, so
+// most candidates cost one SHA-1 instead of a key generation.
+type openpgpSource struct {
+	priv    ed25519.PrivateKey
+	hashed  openpgpHashed
+	created uint32
+	oldest  uint32
+}
+
+func (s *openpgpSource) next(dst []byte) error {
+	if s.priv == nil || s.created == s.oldest {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("generate ed25519 key: %w", err)
+		}
+		s.priv = priv
+		openpgpKeyHashed(&s.hashed, pub)
+		now := uint32(time.Now().Unix())
+		s.created, s.oldest = now+1, now-uint32(openpgpWindow/time.Second)
+	}
+	s.created--
+	binary.BigEndian.PutUint32(s.hashed[openpgpCreatedOffset:], s.created)
+	sum := sha1.Sum(s.hashed[:])
+	copy(dst, sum[:])
+	return nil
+}
+
+func (s *openpgpSource) result() (Result, error) {
+	created := time.Unix(int64(s.created), 0)
+	fp := OpenPGPFingerprint(s.priv.Public().(ed25519.PublicKey), created)
+	r := Result{PrivateKey: s.priv, AuthorizedKey: fp, Fingerprint: fp, Created: created}
+	// Every match gets a key pair of its own, not the same one created at
+	// another time.
+	s.priv = nil
+	return r, nil
+}
+
+// openpgpKeyHashed writes the fingerprinted form of pub into h, with a zero
+// creation time. The packet body is the one keyfile writes, so the
+// fingerprints searched are those of the keys written.
+func openpgpKeyHashed(h *openpgpHashed, pub ed25519.PublicKey) {
+	h[0] = 0x99
+	binary.BigEndian.PutUint16(h[1:], openpgpKeyBody)
+	copy(h[3:], keyfile.OpenPGPPublicKeyBody(pub, time.Unix(0, 0)))
+}
+
+// OpenPGPFingerprint returns the v4 fingerprint, in uppercase hex, of the
+// ed25519 OpenPGP key pub created at created.
+func OpenPGPFingerprint(pub ed25519.PublicKey, created time.Time) string {
+	var h openpgpHashed
+	openpgpKeyHashed(&h, pub)
+	binary.BigEndian.PutUint32(h[openpgpCreatedOffset:], uint32(created.Unix()))
+	sum := sha1.Sum(h[:])
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
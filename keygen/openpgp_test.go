@@ -0,0 +1,104 @@
+package keygen
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestOpenPGPFingerprint(t *testing.T) {
+	t.Parallel()
+
+	// The sample v4 Ed25519Legacy key of RFC 9580, appendix A.3.
+	pub, _ := hex.DecodeString("3f098994bdd916ed4053197934e4a87c80733a1280d62f8010992e43ee3b2406")
+	got := OpenPGPFingerprint(pub, time.Unix(0x53f35f0b, 0))
+	if want := "C959BDBAFA32A2F89A153B678CFDE12197965A9A"; got != want {
+		t.Errorf("OpenPGPFingerprint = %s, want %s", got, want)
+	}
+}
+
+func TestOpenPGPSource_Window(t *testing.T) {
+	t.Parallel()
+
+	s := &openpgpSource{}
+	raw := make([]byte, openpgpLayout.RawLen)
+	before := time.Now().Truncate(time.Second)
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	first, created := s.priv, s.created
+	if now := uint32(time.Now().Unix()); created < uint32(before.Unix()) || created > now {
+		t.Errorf("first candidate created at %d, want now (%d)", created, now)
+	}
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	if !first.Equal(s.priv) || s.created != created-1 {
+		t.Errorf("second candidate: same key %v, created %d after %d", first.Equal(s.priv), s.created, created)
+	}
+
+	// At the end of the window the source moves on to a new key pair.
+	s.created = s.oldest
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	if first.Equal(s.priv) {
+		t.Error("source kept its key pair past the window")
+	}
+
+	// So it does after a match.
+	second := s.priv
+	if _, err := s.result(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.next(raw); err != nil {
+		t.Fatal(err)
+	}
+	if second.Equal(s.priv) {
+		t.Error("source kept its key pair after a match")
+	}
+}
+
+func TestFindKeys_OpenPGP(t *testing.T) {
+	t.Parallel()
+
+	oldest := time.Now().Add(-openpgpWindow - time.Second)
+	for _, tt := range []struct {
+		pattern string
+		in      Input
+	}{
+		{`^AB`, InputPublicKey},
+		{`CAF$`, InputFingerprint},
+		{`(?i)^d[0-9]`, InputPublicKey},
+	} {
+		re := regexp.MustCompile(tt.pattern)
+		m, err := NewMatcher(re, tt.in)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		errc := make(chan error, 1)
+		go func() { errc <- FindKeys(ctx, Options{Matcher: m, Type: KeyTypeOpenPGP}, results) }()
+		r := <-results
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatalf("FindKeys: %v", err)
+		}
+
+		priv, ok := r.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("PrivateKey is %T, want ed25519.PrivateKey", r.PrivateKey)
+		}
+		want := OpenPGPFingerprint(priv.Public().(ed25519.PublicKey), r.Created)
+		if r.AuthorizedKey != want || r.Fingerprint != want || !re.MatchString(want) {
+			t.Errorf("%q: AuthorizedKey %q, Fingerprint %q, want matching %q", tt.pattern, r.AuthorizedKey, r.Fingerprint, want)
+		}
+		if r.Created.Before(oldest) || r.Created.After(time.Now()) {
+			t.Errorf("%q: created %v, outside the window", tt.pattern, r.Created)
+		}
+	}
+}
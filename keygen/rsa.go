@@ -0,0 +1,134 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// rsaExponent is the public exponent of every generated RSA key. Searching
+// over e would be cheaper still, but non-standard exponents are rejected by
+// some of the legacy implementations RSA keys exist for.
+const rsaExponent = 65537
+
+// maxPrimePool bounds how many primes a worker combines before starting
+// over with fresh ones.
+const maxPrimePool = 1024
+
+// checkRSABits validates an RSA modulus size.
+func checkRSABits(bits int) error {
+	if bits < 2048 || bits > 16384 || bits%16 != 0 {
+		return fmt.Errorf("invalid RSA key size %d: must be a multiple of 16 between 2048 and 16384", bits)
+	}
+	return nil
+}
+
+// rsaWire returns the SSH wire format of an RSA public key:
+// string("ssh-rsa") + mpint(e) + mpint(n). e = 65537 encodes in 3 bytes and
+// n, whose top bit is set, in bits/8 bytes after a leading zero, which is
+// part of the constant header. n is the product of two odd primes, so its
+// low bit is set too.
+func rsaWire(bits int) wireFormat {
+	w := newWireFormat("ssh-rsa", bits/8,
+		uint32Bytes(3), []byte{0x01, 0x00, 0x01},
+		uint32Bytes(uint32(bits/8+1)), []byte{0x00})
+	w.fixed = []FixedBits{{Index: 0, Mask: 0x80, Value: 0x80}, {Index: bits/8 - 1, Mask: 0x01, Value: 0x01}}
+	return w
+}
+
+// searchRSA generates RSA keys until cancelled. Prime generation dominates
+// the cost of an RSA key, so each worker keeps a pool of primes and tests
+// the modulus of every new prime paired with each prime already in the pool:
+// the k-th prime yields k-1 candidates for the price of one. This is safe
+// because no two published keys ever share a prime — candidates that do not
+// match never leave the process, and the pool is discarded after every
+// match.
+func searchRSA(w *worker, p *probe, fingerprint bool, format wireFormat, bits int) error {
+	wire := format.newBuf()
+	nOff := format.keyOffset()
+	eBig := big.NewInt(rsaExponent)
+	rem := new(big.Int)
+	n := new(big.Int)
+	pool := make([]*big.Int, 0, maxPrimePool)
+
+	for {
+		if w.cancelled() {
+			return nil
+		}
+		if len(pool) == maxPrimePool {
+			pool = pool[:0]
+		}
+		prime, err := rand.Prime(rand.Reader, bits/2)
+		if err != nil {
+			return fmt.Errorf("generate rsa prime: %w", err)
+		}
+		// e is prime, so gcd(e, prime-1) = 1 unless e divides prime-1.
+		if rem.Mod(prime, eBig).Int64() == 1 {
+			continue
+		}
+
+		matched := false
+		for _, q := range pool {
+			if !w.next() {
+				return nil
+			}
+			n.Mul(prime, q)
+			if n.BitLen() != bits {
+				continue
+			}
+			n.FillBytes(wire[nOff:])
+
+			if fingerprint {
+				sum := sha256.Sum256(wire)
+				matched = p.match(sum[:])
+			} else {
+				matched = p.match(wire)
+			}
+			if !matched {
+				continue
+			}
+
+			key, err := newRSAKey(prime, q)
+			if err != nil {
+				return err
+			}
+			result, err := newSSHResult(key, &key.PublicKey)
+			if err != nil {
+				return err
+			}
+			if !w.emit(result) {
+				return nil
+			}
+			break
+		}
+
+		if matched {
+			// The pool's primes are now part of a published key.
+			pool = pool[:0]
+			continue
+		}
+		pool = append(pool, prime)
+	}
+}
+
+// newRSAKey assembles and validates the private key for primes p and q.
+func newRSAKey(p, q *big.Int) (*rsa.PrivateKey, error) {
+	one := big.NewInt(1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	d := new(big.Int).ModInverse(big.NewInt(rsaExponent), phi)
+	if d == nil {
+		return nil, fmt.Errorf("rsa exponent not invertible")
+	}
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: rsaExponent},
+		D:         d,
+		Primes:    []*big.Int{new(big.Int).Set(p), new(big.Int).Set(q)},
+	}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("validate rsa key: %w", err)
+	}
+	key.Precompute()
+	return key, nil
+}
@@ -0,0 +1,149 @@
+package keygen
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCheckRSABits(t *testing.T) {
+	t.Parallel()
+
+	for _, bits := range []int{2048, 3072, 4096} {
+		if err := checkRSABits(bits); err != nil {
+			t.Errorf("checkRSABits(%d) = %v, want nil", bits, err)
+		}
+	}
+	for _, bits := range []int{0, 1024, 2047, 3000, 32768} {
+		if err := checkRSABits(bits); err == nil {
+			t.Errorf("checkRSABits(%d) = nil, want error", bits)
+		}
+	}
+}
+
+func TestNewRSAKey(t *testing.T) {
+	t.Parallel()
+
+	p, err := rand.Prime(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Prime: %v", err)
+	}
+	q, err := rand.Prime(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Prime: %v", err)
+	}
+	key, err := newRSAKey(p, q)
+	if err != nil {
+		t.Skipf("primes rejected (e divides p-1 or q-1): %v", err)
+	}
+	if key.E != 65537 || key.N.Cmp(new(big.Int).Mul(p, q)) != 0 {
+		t.Errorf("key = (E %d, N %v), want E 65537, N = p*q", key.E, key.N)
+	}
+}
+
+func TestFindKeys_RSA(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	m, err := NewMatcher(regexp.MustCompile(`[A-Z]`), InputFingerprint)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	opts := Options{Matcher: m, Type: KeyTypeRSA, Bits: 2048}
+	results := make(chan Result, 2)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- FindKeys(ctx, opts, results)
+	}()
+
+	var keys []*rsa.PrivateKey
+	for range 2 {
+		select {
+		case r := <-results:
+			if !strings.HasPrefix(r.AuthorizedKey, "ssh-rsa AAAAB3NzaC1yc2EAAAADAQAB") {
+				t.Errorf("AuthorizedKey = %q, want ssh-rsa with e=65537", r.AuthorizedKey)
+			}
+			raw, err := ssh.ParseRawPrivateKey(r.PrivateKeyPEM)
+			if err != nil {
+				t.Fatalf("ParseRawPrivateKey: %v", err)
+			}
+			key, ok := raw.(*rsa.PrivateKey)
+			if !ok {
+				t.Fatalf("private key type = %T, want *rsa.PrivateKey", raw)
+			}
+			if key.N.BitLen() != 2048 {
+				t.Errorf("modulus = %d bits, want 2048", key.N.BitLen())
+			}
+			pub, err := ssh.NewPublicKey(&key.PublicKey)
+			if err != nil {
+				t.Fatalf("NewPublicKey: %v", err)
+			}
+			if got := getAuthorizedKey(pub); got != r.AuthorizedKey {
+				t.Errorf("private key does not match AuthorizedKey")
+			}
+			if !m.Match([]byte(r.Fingerprint)) {
+				t.Errorf("fingerprint %q does not match", r.Fingerprint)
+			}
+			keys = append(keys, key)
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for RSA key")
+		}
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("FindKeys error: %v", err)
+	}
+
+	// Keys from the same worker must never share a prime.
+	for _, p := range keys[0].Primes {
+		for _, q := range keys[1].Primes {
+			if p.Cmp(q) == 0 {
+				t.Fatal("two published keys share a prime")
+			}
+		}
+	}
+}
+
+func TestFindKeys_InvalidTypeOptions(t *testing.T) {
+	t.Parallel()
+
+	m := prefixMatcher{input: InputPublicKey}
+	tests := []struct {
+		name    string
+		opts    Options
+		wantSub string
+	}{
+		{"unknown type", Options{Matcher: m, Type: "dsa"}, "unsupported key type"},
+		{"bad rsa bits", Options{Matcher: m, Type: KeyTypeRSA, Bits: 1024}, "invalid RSA key size"},
+		{"bits for ed25519", Options{Matcher: m, Bits: 4096}, "bits only applies"},
+	}
+	for _, tt := range tests {
+		err := FindKeys(context.Background(), tt.opts, make(chan Result))
+		if err == nil || !strings.Contains(err.Error(), tt.wantSub) {
+			t.Errorf("%s: error = %v, want substring %q", tt.name, err, tt.wantSub)
+		}
+	}
+}
+
+func TestParseKeyType(t *testing.T) {
+	t.Parallel()
+
+	for _, kt := range KeyTypes {
+		got, err := ParseKeyType(string(kt))
+		if err != nil || got != kt {
+			t.Errorf("ParseKeyType(%q) = %q, %v", kt, got, err)
+		}
+	}
+	if _, err := ParseKeyType("dsa"); err == nil {
+		t.Error("ParseKeyType(dsa) = nil error, want error")
+	}
+}
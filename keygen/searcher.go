@@ -0,0 +1,152 @@
+package keygen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrSearcherStarted is returned when Start is called more than once.
+var ErrSearcherStarted = errors.New("searcher already started")
+
+// Stats is a snapshot of a Searcher's progress.
+type Stats struct {
+	Keys    int64
+	Matches int64
+	Workers int
+	Elapsed time.Duration
+}
+
+// Searcher owns a pool of key generation workers together with their
+// counters and results channel, so independent searches can run in the same
+// process. A Searcher runs once: create it with NewSearcher, call Start, read
+// Results until the channel is closed, and call Stop to end it early.
+type Searcher struct {
+	opts    Options
+	workers int
+	results chan Result
+	c       counters
+
+	mu      sync.Mutex
+	started time.Time
+	stopped time.Time
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+// NewSearcher validates opts and returns a Searcher that will run the given
+// number of workers (0 means one per CPU).
+func NewSearcher(opts Options, workers int) (*Searcher, error) {
+	if opts.Matcher == nil {
+		return nil, ErrNilMatcher
+	}
+	if workers < 0 {
+		return nil, fmt.Errorf("workers must be non-negative, got %d", workers)
+	}
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	return &Searcher{
+		opts:    opts,
+		workers: workers,
+		results: make(chan Result, workers),
+	}, nil
+}
+
+// Start launches the workers. They run until ctx is cancelled, Stop is
+// called, or a worker fails; the Results channel is closed once all of them
+// have exited.
+func (s *Searcher) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return ErrSearcherStarted
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	g, gctx := errgroup.WithContext(ctx)
+	s.started = time.Now()
+	for id := range s.workers {
+		w := &worker{ctx: gctx, results: s.results, c: &s.c, id: id, start: s.started}
+		g.Go(func() error {
+			return findKeys(s.opts, w)
+		})
+	}
+	s.done = make(chan struct{})
+
+	go func() {
+		err := g.Wait()
+		s.mu.Lock()
+		s.err = err
+		s.stopped = time.Now()
+		s.mu.Unlock()
+		close(s.results)
+		close(s.done)
+	}()
+	return nil
+}
+
+// Results returns the channel matched keys are delivered on. It is closed
+// when the search ends.
+func (s *Searcher) Results() <-chan Result { return s.results }
+
+// Wait blocks until every worker has exited and returns the first worker
+// error, if any. It returns nil immediately if the Searcher was never
+// started.
+func (s *Searcher) Wait() error {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Stop cancels the workers and waits for them to exit, returning the first
+// worker error, if any. It is safe to call more than once.
+func (s *Searcher) Stop() error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return s.Wait()
+}
+
+// KeyCount returns the number of keys generated so far.
+func (s *Searcher) KeyCount() int64 { return s.c.keys.Load() }
+
+// MatchCount returns the number of matches found so far.
+func (s *Searcher) MatchCount() int64 { return s.c.matches.Load() }
+
+// Stats returns a snapshot of the search progress. Elapsed stops advancing
+// once the search has ended.
+func (s *Searcher) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Stats{
+		Keys:    s.c.keys.Load(),
+		Matches: s.c.matches.Load(),
+		Workers: s.workers,
+	}
+	switch {
+	case s.started.IsZero():
+	case s.stopped.IsZero():
+		st.Elapsed = time.Since(s.started)
+	default:
+		st.Elapsed = s.stopped.Sub(s.started)
+	}
+	return st
+}
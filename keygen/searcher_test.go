@@ -0,0 +1,206 @@
+package keygen
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSearcher(t *testing.T, pattern string, workers int) *Searcher {
+	t.Helper()
+	m, err := NewMatcher(regexp.MustCompile(pattern), InputPublicKey)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	s, err := NewSearcher(Options{Matcher: m}, workers)
+	if err != nil {
+		t.Fatalf("NewSearcher: %v", err)
+	}
+	return s
+}
+
+func TestNewSearcher_Validation(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewSearcher(Options{}, 1); !errors.Is(err, ErrNilMatcher) {
+		t.Errorf("nil matcher: error = %v, want %v", err, ErrNilMatcher)
+	}
+	m := prefixMatcher{input: InputPublicKey}
+	if _, err := NewSearcher(Options{Matcher: m}, -1); err == nil {
+		t.Error("negative workers: want error, got nil")
+	}
+	s, err := NewSearcher(Options{Matcher: m}, 0)
+	if err != nil {
+		t.Fatalf("NewSearcher: %v", err)
+	}
+	if s.Stats().Workers < 1 {
+		t.Errorf("Workers = %d, want >= 1 for default", s.Stats().Workers)
+	}
+}
+
+func TestSearcher_Lifecycle(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSearcher(t, `ssh-ed25519`, 2)
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop before Start: %v", err)
+	}
+	if st := s.Stats(); st.Elapsed != 0 || st.Keys != 0 {
+		t.Errorf("Stats before Start = %+v, want zero", st)
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := s.Start(context.Background()); !errors.Is(err, ErrSearcherStarted) {
+		t.Errorf("second Start: error = %v, want %v", err, ErrSearcherStarted)
+	}
+
+	for range 3 {
+		select {
+		case r := <-s.Results():
+			assertResultFields(t, r)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for result")
+		}
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+	for range s.Results() {
+		// Drain anything sent before the workers exited.
+	}
+
+	st := s.Stats()
+	if st.Matches < 3 || st.Keys < st.Matches {
+		t.Errorf("Stats = %+v, want >= 3 matches and keys >= matches", st)
+	}
+	if st.Keys != s.KeyCount() || st.Matches != s.MatchCount() {
+		t.Errorf("Stats %+v disagrees with KeyCount/MatchCount %d/%d", st, s.KeyCount(), s.MatchCount())
+	}
+	if later := s.Stats().Elapsed; later != st.Elapsed {
+		t.Errorf("Elapsed advanced after Stop: %v -> %v", st.Elapsed, later)
+	}
+}
+
+func TestSearcher_ResultProvenance(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSearcher(t, `ssh-ed25519`, 3)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop()
+
+	var lastKeys int64
+	for range 20 {
+		select {
+		case r := <-s.Results():
+			if r.Worker < 0 || r.Worker >= 3 {
+				t.Errorf("Worker = %d, want 0..2", r.Worker)
+			}
+			if r.Keys < 1 || r.Keys > s.KeyCount() {
+				t.Errorf("Keys = %d, want 1..%d", r.Keys, s.KeyCount())
+			}
+			if r.Elapsed <= 0 || r.Elapsed > s.Stats().Elapsed {
+				t.Errorf("Elapsed = %v, want within the search's %v", r.Elapsed, s.Stats().Elapsed)
+			}
+			lastKeys = max(lastKeys, r.Keys)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for result")
+		}
+	}
+	if lastKeys < 20 {
+		t.Errorf("largest Keys = %d after 20 matches, want >= 20", lastKeys)
+	}
+}
+
+func TestSearcher_ContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	s := newTestSearcher(t, `^IMPOSSIBLE$`, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-s.Results():
+		if ok {
+			t.Error("unexpected result for impossible pattern")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("results channel not closed after cancel")
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+}
+
+func TestSearcher_WorkerError(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewSearcher(Options{Matcher: prefixMatcher{input: Input(99)}}, 2)
+	if err != nil {
+		t.Fatalf("NewSearcher: %v", err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := s.Wait(); err == nil || !strings.Contains(err.Error(), "unsupported matcher input") {
+		t.Errorf("Wait error = %v, want unsupported matcher input", err)
+	}
+}
+
+func TestSearcher_IndependentCounters(t *testing.T) {
+	// Not parallel: asserts the package-level counters stay untouched.
+	ResetCounters()
+	t.Cleanup(func() { ResetCounters() })
+
+	a := newTestSearcher(t, `ssh-ed25519`, 1)
+	b := newTestSearcher(t, `^IMPOSSIBLE$`, 1)
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start a: %v", err)
+	}
+	if err := b.Start(context.Background()); err != nil {
+		t.Fatalf("Start b: %v", err)
+	}
+
+	select {
+	case <-a.Results():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for result from a")
+	}
+	deadline := time.After(10 * time.Second)
+	for b.KeyCount() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("b generated no keys")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err := a.Stop(); err != nil {
+		t.Errorf("Stop a: %v", err)
+	}
+	if err := b.Stop(); err != nil {
+		t.Errorf("Stop b: %v", err)
+	}
+
+	if a.MatchCount() < 1 {
+		t.Errorf("a.MatchCount() = %d, want >= 1", a.MatchCount())
+	}
+	if b.MatchCount() != 0 {
+		t.Errorf("b.MatchCount() = %d, want 0", b.MatchCount())
+	}
+	if KeyCount() != 0 || MatchCount() != 0 {
+		t.Errorf("package counters = %d/%d, want 0/0", KeyCount(), MatchCount())
+	}
+}
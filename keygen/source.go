@@ -0,0 +1,64 @@
+package keygen
+
+import "fmt"
+
+// keySource generates candidates for key types that are not SSH keys. next
+// writes the raw bytes of the matched representation of a fresh candidate
+// into dst; result builds the Result of that candidate and is only called on
+// a match.
+type keySource interface {
+	next(dst []byte) error
+	result() (Result, error)
+}
+
+// sourceType describes a key type searched through a keySource: the Layout
+// of each Input it supports and a constructor for its per-worker source,
+// which writes the raw bytes of that Input.
+type sourceType struct {
+	layouts   map[Input]Layout
+	newSource func(in Input) keySource
+}
+
+// sourceTypes holds the key types that are not SSH keys, registered by the
+// file implementing each.
+var sourceTypes = map[KeyType]sourceType{}
+
+// SSH reports whether keys of type kt are SSH keys, with an authorized_keys
+// line, fingerprint and OpenSSH private key. Other types fill in only
+// Result.PrivateKey and Result.AuthorizedKey.
+func (kt KeyType) SSH() bool {
+	_, ok := sourceTypes[kt]
+	return !ok
+}
+
+// layout returns the Layout of the representation in for the source
+// type st.
+func (st sourceType) layout(kt KeyType, in Input) (Layout, error) {
+	l, ok := st.layouts[in]
+	if !ok {
+		return Layout{}, fmt.Errorf("%s keys have no %s to match", kt, in)
+	}
+	return l, nil
+}
+
+// searchSource generates candidates from src until cancelled, testing the
+// raw bytes of each.
+func searchSource(w *worker, p *probe, l Layout, src keySource) error {
+	raw := make([]byte, l.RawLen)
+	for w.next() {
+		if err := src.next(raw); err != nil {
+			return err
+		}
+		if !p.match(raw) {
+			continue
+		}
+		result, err := src.result()
+		if err != nil {
+			return err
+		}
+		if !w.emit(result) {
+			return nil
+		}
+	}
+	return nil
+}
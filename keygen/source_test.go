@@ -0,0 +1,17 @@
+package keygen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeyTypeSSH(t *testing.T) {
+	t.Parallel()
+
+	for _, kt := range KeyTypes {
+		want := !slices.Contains([]KeyType{KeyTypeOnion, KeyTypeWireGuard, KeyTypeAge, KeyTypeOpenPGP, KeyTypeMinisign, KeyTypeSignify, KeyTypeLibP2P}, kt)
+		if got := kt.SSH(); got != want {
+			t.Errorf("%s.SSH() = %v, want %v", kt, got, want)
+		}
+	}
+}
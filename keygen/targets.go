@@ -0,0 +1,175 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+// ErrNoTargets is returned by NewTargetSet when given no targets.
+var ErrNoTargets = errors.New("at least one target is required")
+
+// Target is one named pattern in a multi-target search.
+type Target struct {
+	Name  string
+	Regex *regexp.Regexp
+}
+
+// target is the per-target state of a TargetSet.
+type target struct {
+	Target
+	matcher Matcher
+	anchor  Anchor // literal targets only
+	litLen  int    // literal targets only
+	done    atomic.Bool
+}
+
+// TargetSet is a Matcher that tests each candidate against every outstanding
+// target at once. Literal targets share two Aho-Corasick automata (exact and
+// case-folded), so their cost does not grow with the number of targets;
+// other targets fall back to their regex. Targets are retired with Claim
+// once satisfied and are skipped from then on.
+type TargetSet struct {
+	input     Input
+	targets   []*target
+	remaining atomic.Int64
+
+	exact, folded       *ahoCorasick
+	exactIDs, foldedIDs []int // automaton literal id -> target index
+	regexIDs            []int // target indices without a literal form
+}
+
+// NewTargetSet builds a TargetSet over targets, testing the given
+// representation. Target names must be unique.
+func NewTargetSet(targets []Target, in Input) (*TargetSet, error) {
+	if len(targets) == 0 {
+		return nil, ErrNoTargets
+	}
+	s := &TargetSet{input: in}
+	var exactLits, foldedLits [][]byte
+	seen := make(map[string]bool, len(targets))
+	for i, tg := range targets {
+		if tg.Regex == nil {
+			return nil, fmt.Errorf("target %q: %w", tg.Name, ErrNilRegex)
+		}
+		if seen[tg.Name] {
+			return nil, fmt.Errorf("duplicate target name %q", tg.Name)
+		}
+		seen[tg.Name] = true
+
+		m, err := NewMatcher(tg.Regex, in)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", tg.Name, err)
+		}
+		t := &target{Target: tg, matcher: m}
+		s.targets = append(s.targets, t)
+
+		lm, ok := m.(*LiteralMatcher)
+		switch {
+		case !ok:
+			s.regexIDs = append(s.regexIDs, i)
+		case lm.fold:
+			t.anchor, t.litLen = lm.anchor, len(lm.lit)
+			foldedLits = append(foldedLits, lm.lit)
+			s.foldedIDs = append(s.foldedIDs, i)
+		default:
+			t.anchor, t.litLen = lm.anchor, len(lm.lit)
+			exactLits = append(exactLits, lm.lit)
+			s.exactIDs = append(s.exactIDs, i)
+		}
+	}
+	if len(exactLits) > 0 {
+		s.exact = newAhoCorasick(exactLits)
+	}
+	if len(foldedLits) > 0 {
+		s.folded = newAhoCorasick(foldedLits)
+	}
+	s.remaining.Store(int64(len(s.targets)))
+	return s, nil
+}
+
+// Input reports which representation the set expects.
+func (s *TargetSet) Input() Input { return s.input }
+
+// String returns the name of the matching engine.
+func (s *TargetSet) String() string {
+	return fmt.Sprintf("multi-target (%d literal, %d regexp)",
+		len(s.exactIDs)+len(s.foldedIDs), len(s.regexIDs))
+}
+
+// Len returns the total number of targets.
+func (s *TargetSet) Len() int { return len(s.targets) }
+
+// Remaining returns the number of targets not yet claimed.
+func (s *TargetSet) Remaining() int { return int(s.remaining.Load()) }
+
+// Pending returns the outstanding targets in their original order.
+func (s *TargetSet) Pending() []Target {
+	var pending []Target
+	for _, t := range s.targets {
+		if !t.done.Load() {
+			pending = append(pending, t.Target)
+		}
+	}
+	return pending
+}
+
+// Match reports whether candidate satisfies any outstanding target.
+func (s *TargetSet) Match(candidate []byte) bool {
+	if s.remaining.Load() == 0 {
+		return false
+	}
+	if s.exact != nil && s.exact.scan(candidate, false, s.hit(candidate, s.exactIDs)) {
+		return true
+	}
+	if s.folded != nil && s.folded.scan(candidate, true, s.hit(candidate, s.foldedIDs)) {
+		return true
+	}
+	for _, i := range s.regexIDs {
+		t := s.targets[i]
+		if !t.done.Load() && t.matcher.Match(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// hit returns an Aho-Corasick callback that accepts occurrences of
+// outstanding targets whose anchors are satisfied.
+func (s *TargetSet) hit(candidate []byte, ids []int) func(id, end int) bool {
+	return func(id, end int) bool {
+		t := s.targets[ids[id]]
+		if t.done.Load() {
+			return false
+		}
+		start := end - t.litLen
+		switch t.anchor {
+		case AnchorStart:
+			return start == 0
+		case AnchorEnd:
+			return end == len(candidate)
+		case AnchorBoth:
+			return start == 0 && end == len(candidate)
+		}
+		return true
+	}
+}
+
+// Claim retires the first outstanding target, in file order, that
+// candidate satisfies and returns its name; ok is false when there is none.
+// A key is only ever claimed for one target, so no two targets share a
+// private key, and each target is claimed at most once, even when several
+// workers find keys for it concurrently.
+func (s *TargetSet) Claim(candidate []byte) (name string, ok bool) {
+	for _, t := range s.targets {
+		if t.done.Load() || !t.matcher.Match(candidate) {
+			continue
+		}
+		if t.done.CompareAndSwap(false, true) {
+			s.remaining.Add(-1)
+			return t.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,154 @@
+package keygen
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func mustTargets(t *testing.T, in Input, specs ...string) *TargetSet {
+	t.Helper()
+	var targets []Target
+	for i := 0; i < len(specs); i += 2 {
+		targets = append(targets, Target{Name: specs[i], Regex: regexp.MustCompile(specs[i+1])})
+	}
+	s, err := NewTargetSet(targets, in)
+	if err != nil {
+		t.Fatalf("NewTargetSet: %v", err)
+	}
+	return s
+}
+
+func TestNewTargetSet_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewTargetSet(nil, InputPublicKey); !errors.Is(err, ErrNoTargets) {
+		t.Errorf("empty: error = %v, want %v", err, ErrNoTargets)
+	}
+	if _, err := NewTargetSet([]Target{{Name: "a"}}, InputPublicKey); !errors.Is(err, ErrNilRegex) {
+		t.Errorf("nil regex: error = %v, want %v", err, ErrNilRegex)
+	}
+	dup := []Target{
+		{Name: "a", Regex: regexp.MustCompile("x")},
+		{Name: "a", Regex: regexp.MustCompile("y")},
+	}
+	if _, err := NewTargetSet(dup, InputPublicKey); err == nil {
+		t.Error("duplicate names: want error, got nil")
+	}
+}
+
+func TestTargetSet_MatchEquivalence(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{`^ab`, `cd$`, `(?i)EF`, `^gh$`, `(?i)^IJ`, `k[lm]`, `zz`}
+	texts := []string{"abxx", "xxcd", "xefx", "xEFx", "gh", "ghx", "ijxx", "IJxx", "kl", "km", "kn", "", "cdx", "xab"}
+
+	for _, p := range patterns {
+		s := mustTargets(t, InputPublicKey, "t", p)
+		re := regexp.MustCompile(p)
+		for _, text := range texts {
+			if got, want := s.Match([]byte(text)), re.MatchString(text); got != want {
+				t.Errorf("%q.Match(%q) = %v, regexp = %v", p, text, got, want)
+			}
+		}
+	}
+}
+
+func TestTargetSet_ClaimRetiresTargets(t *testing.T) {
+	t.Parallel()
+
+	s := mustTargets(t, InputPublicKey, "alice", `(?i)alice$`, "bob", `^bob`, "carol", `c[a]rol`)
+	if s.Len() != 3 || s.Remaining() != 3 {
+		t.Fatalf("Len/Remaining = %d/%d, want 3/3", s.Len(), s.Remaining())
+	}
+
+	// One key satisfying two targets is only claimed for the first.
+	if got, ok := s.Claim([]byte("bob-ALICE")); !ok || got != "alice" {
+		t.Errorf("Claim = %q, %v, want alice", got, ok)
+	}
+	if s.Remaining() != 2 {
+		t.Errorf("Remaining = %d, want 2", s.Remaining())
+	}
+	if p := s.Pending(); len(p) != 2 || p[0].Name != "bob" || p[1].Name != "carol" {
+		t.Errorf("Pending = %v, want [bob carol]", p)
+	}
+	if s.Match([]byte("xalice")) {
+		t.Error("retired target still matches")
+	}
+	if got, ok := s.Claim([]byte("bob-alice")); !ok || got != "bob" {
+		t.Errorf("second Claim = %q, %v, want bob", got, ok)
+	}
+	if got, ok := s.Claim([]byte("bob-alice")); ok {
+		t.Errorf("third Claim = %q, want none", got)
+	}
+	if !s.Match([]byte("xcarolx")) {
+		t.Error("outstanding regex target does not match")
+	}
+	if got, ok := s.Claim([]byte("xcarolx")); !ok || got != "carol" {
+		t.Errorf("Claim = %q, %v, want carol", got, ok)
+	}
+	if s.Remaining() != 0 || s.Match([]byte("xcarolx")) {
+		t.Error("set with no remaining targets still matches")
+	}
+	if p := s.Pending(); len(p) != 0 {
+		t.Errorf("Pending = %v, want none", p)
+	}
+}
+
+func TestTargetSet_ConcurrentClaim(t *testing.T) {
+	t.Parallel()
+
+	s := mustTargets(t, InputPublicKey, "a", `x`)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var claimed int
+	for range 16 {
+		wg.Go(func() {
+			if _, ok := s.Claim([]byte("x")); ok {
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	if claimed != 1 {
+		t.Errorf("target claimed %d times, want 1", claimed)
+	}
+}
+
+func TestFindKeys_TargetSet(t *testing.T) {
+	t.Parallel()
+
+	s := mustTargets(t, InputFingerprint, "one", `A`, "two", `(?i)b`, "three", `[cC]`)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	results := make(chan Result, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- FindKeys(ctx, Options{Matcher: s}, results)
+	}()
+
+	found := make(map[string]bool)
+	for s.Remaining() > 0 {
+		select {
+		case r := <-results:
+			if name, ok := s.Claim([]byte(r.Text(InputFingerprint))); ok {
+				found[name] = true
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out with %d targets remaining", s.Remaining())
+		}
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("FindKeys error: %v", err)
+	}
+	if len(found) != 3 {
+		t.Errorf("found %v, want all three targets", found)
+	}
+}
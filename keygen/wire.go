@@ -0,0 +1,64 @@
+package keygen
+
+import "encoding/binary"
+
+// wireFormat describes the SSH wire encoding of a public key as a constant
+// header (length-prefixed algorithm name, curve name, exponent, length
+// fields) followed by keyLen bytes of per-key material. Each key type's hot
+// loop keeps one pre-initialized buffer and only copies the key material in
+// per candidate.
+type wireFormat struct {
+	name   string // algorithm name, also the authorized_keys prefix
+	header []byte
+	keyLen int
+	// fixed are the bits every key's material shares, indexed from the
+	// start of the key material.
+	fixed []FixedBits
+}
+
+// newWireFormat returns the wire format for algorithm name whose header
+// continues with the given pre-encoded fields.
+func newWireFormat(name string, keyLen int, fields ...[]byte) wireFormat {
+	header := appendString(nil, name)
+	for _, f := range fields {
+		header = append(header, f...)
+	}
+	return wireFormat{name: name, header: header, keyLen: keyLen}
+}
+
+// ED25519 SSH wire format: uint32(11) + "ssh-ed25519" + uint32(32) + pubkey(32) = 51 bytes
+var ed25519Wire = newWireFormat("ssh-ed25519", 32, uint32Bytes(32))
+
+// len returns the total wire length.
+func (w wireFormat) len() int { return len(w.header) + w.keyLen }
+
+// keyOffset returns where the key material starts.
+func (w wireFormat) keyOffset() int { return len(w.header) }
+
+// newBuf returns a buffer holding the header with the key material zeroed.
+func (w wireFormat) newBuf() []byte {
+	buf := make([]byte, w.len())
+	copy(buf, w.header)
+	return buf
+}
+
+// layout returns the Layout of the authorized_keys line for this format.
+func (w wireFormat) layout() Layout {
+	l := Layout{Prefix: w.name + " ", Head: w.header, RawLen: w.len()}
+	for _, f := range w.fixed {
+		f.Index += w.keyOffset()
+		l.Fixed = append(l.Fixed, f)
+	}
+	return l
+}
+
+// appendString appends an SSH string (uint32 length + bytes).
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// uint32Bytes returns v as a big-endian uint32 field.
+func uint32Bytes(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
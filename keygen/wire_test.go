@@ -0,0 +1,80 @@
+package keygen
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// wireCase is a generated public key together with the key material the hot
+// loop copies into the wire buffer for it.
+type wireCase struct {
+	opts     Options
+	pub      crypto.PublicKey
+	material []byte
+}
+
+func newWireCases(t *testing.T) []wireCase {
+	t.Helper()
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	cases := []wireCase{
+		{opts: Options{Type: KeyTypeED25519}, pub: edPub, material: edPub},
+		{opts: Options{Type: KeyTypeRSA, Bits: 2048}, pub: &rsaKey.PublicKey, material: rsaKey.N.FillBytes(make([]byte, 256))},
+	}
+	for _, kt := range []KeyType{KeyTypeECDSAP256, KeyTypeECDSAP384, KeyTypeECDSAP521} {
+		key, err := ecdsa.GenerateKey(ecdsaCurves[kt].curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("ecdsa.GenerateKey: %v", err)
+		}
+		point, err := key.PublicKey.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes: %v", err)
+		}
+		cases = append(cases, wireCase{opts: Options{Type: kt}, pub: &key.PublicKey, material: point[1:]})
+	}
+	return cases
+}
+
+// TestWireFormat_MatchesSSHMarshal checks that every key type's
+// pre-initialized buffer plus its key material reproduces ssh.Marshal, so
+// the hot-loop representation equals the slow-path one.
+func TestWireFormat_MatchesSSHMarshal(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range newWireCases(t) {
+		t.Run(string(tc.opts.Type), func(t *testing.T) {
+			wire, err := tc.opts.wire()
+			if err != nil {
+				t.Fatalf("wire: %v", err)
+			}
+			sshPub, err := ssh.NewPublicKey(tc.pub)
+			if err != nil {
+				t.Fatalf("NewPublicKey: %v", err)
+			}
+			buf := wire.newBuf()
+			if n := copy(buf[wire.keyOffset():], tc.material); n != wire.keyLen {
+				t.Fatalf("key material = %d bytes, want %d", n, wire.keyLen)
+			}
+			if !bytes.Equal(buf, sshPub.Marshal()) {
+				t.Errorf("wire buffer != ssh.Marshal\n got %x\nwant %x", buf, sshPub.Marshal())
+			}
+			if got, want := wire.layout().Prefix, sshPub.Type()+" "; got != want {
+				t.Errorf("layout prefix = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package keygen
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// KeyTypeWireGuard generates X25519 key pairs for WireGuard, matched on the
+// base64 public key.
+const KeyTypeWireGuard KeyType = "wireguard"
+
+// wireguardLayout is the 44-character base64 public key, as wg pubkey
+// prints it.
+var wireguardLayout = Layout{RawLen: 32}
+
+func init() {
+	sourceTypes[KeyTypeWireGuard] = sourceType{
+		layouts:   map[Input]Layout{InputPublicKey: wireguardLayout},
+		newSource: func(Input) keySource { return &wireguardSource{} },
+	}
+}
+
+// wireguardSource generates X25519 key pairs with clamped private keys, as
+// wg genkey does.
+type wireguardSource struct {
+	priv *ecdh.PrivateKey
+	seed [32]byte
+}
+
+func (s *wireguardSource) next(dst []byte) error {
+	if _, err := rand.Read(s.seed[:]); err != nil {
+		return fmt.Errorf("generate x25519 key: %w", err)
+	}
+	s.seed[0] &= 248
+	s.seed[31] = s.seed[31]&127 | 64
+	var err error
+	if s.priv, err = ecdh.X25519().NewPrivateKey(s.seed[:]); err != nil {
+		return fmt.Errorf("generate x25519 key: %w", err)
+	}
+	copy(dst, s.priv.PublicKey().Bytes())
+	return nil
+}
+
+func (s *wireguardSource) result() (Result, error) {
+	return Result{
+		PrivateKey:    s.priv,
+		AuthorizedKey: base64.StdEncoding.EncodeToString(s.priv.PublicKey().Bytes()),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package keygen
+
+import (
+	"context"
+	"crypto/ecdh"
+	"encoding/base64"
+	"regexp"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestFindKeys_WireGuard(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{`^wg`, `(?i)^ab`, `Q=$`, `[+/]`} {
+		re := regexp.MustCompile(pattern)
+		m, err := NewMatcher(re, InputPublicKey)
+		if err != nil {
+			t.Fatalf("NewMatcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		results := make(chan Result, 1)
+		errc := make(chan error, 1)
+		go func() { errc <- FindKeys(ctx, Options{Matcher: m, Type: KeyTypeWireGuard}, results) }()
+		r := <-results
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatalf("FindKeys: %v", err)
+		}
+
+		priv, ok := r.PrivateKey.(*ecdh.PrivateKey)
+		if !ok {
+			t.Fatalf("PrivateKey is %T, want *ecdh.PrivateKey", r.PrivateKey)
+		}
+		scalar := priv.Bytes()
+		if scalar[0]&7 != 0 || scalar[31]&0xc0 != 0x40 {
+			t.Errorf("private key is not clamped: %x", scalar)
+		}
+		pub, err := curve25519.X25519(scalar, curve25519.Basepoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := base64.StdEncoding.EncodeToString(pub)
+		if r.AuthorizedKey != want || len(want) != wireguardLayout.TextLen() || !re.MatchString(want) {
+			t.Errorf("%q: AuthorizedKey = %q, want matching %q", pattern, r.AuthorizedKey, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package keygen
+
+import (
+	"context"
+	"time"
+)
+
+// flushInterval is how many candidates a worker counts locally before
+// publishing them to the shared counter and checking for cancellation.
+const flushInterval = 1024
+
+// worker holds the per-goroutine state shared by every key type's search
+// loop: batched counting, cancellation and result delivery.
+type worker struct {
+	ctx     context.Context
+	results chan<- Result
+	c       *counters
+	id      int
+	start   time.Time
+	local   int64
+}
+
+// next counts one candidate. Every flushInterval candidates it publishes the
+// local count and reports false if the context has been cancelled.
+func (w *worker) next() bool {
+	w.local++
+	if w.local >= flushInterval {
+		w.flush()
+		return w.ctx.Err() == nil
+	}
+	return true
+}
+
+// flush publishes the local candidate count.
+func (w *worker) flush() {
+	w.c.keys.Add(w.local)
+	w.local = 0
+}
+
+// cancelled flushes and reports whether the context has been cancelled. Key
+// types with slow setup steps call it between them.
+func (w *worker) cancelled() bool {
+	if w.ctx.Err() != nil {
+		w.flush()
+		return true
+	}
+	return false
+}
+
+// emit is the slow path after a match: flush the counter, count the match
+// and deliver r, tagged with the worker, key count and elapsed time. It
+// reports false if the context was cancelled first.
+func (w *worker) emit(r Result) bool {
+	w.flush()
+	w.c.matches.Add(1)
+	r.Keys, r.Elapsed, r.Worker = w.c.keys.Load(), time.Since(w.start), w.id
+	select {
+	case w.results <- r:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+// probe tests one representation of each candidate against a Matcher. It
+// uses the matcher's raw fast path when available and otherwise encodes the
+// raw bytes into a pre-allocated text buffer.
+type probe struct {
+	m         Matcher
+	raw       func([]byte) bool
+	text      []byte
+	prefixLen int
+	encode    func(dst, src []byte)
+}
+
+// newProbe prepares a probe for representations laid out as l.
+func newProbe(m Matcher, l Layout) *probe {
+	p := &probe{m: m, prefixLen: len(l.Prefix)}
+	if rm, ok := m.(RawMatcher); ok {
+		if fn, ok := rm.BindRaw(l); ok {
+			p.raw = fn
+			return p
+		}
+	}
+	p.text = make([]byte, l.TextLen())
+	copy(p.text, l.Prefix)
+	p.encode = l.encoder()
+	return p
+}
+
+// match reports whether the representation of raw is a hit.
+func (p *probe) match(raw []byte) bool {
+	if p.raw != nil {
+		return p.raw(raw)
+	}
+	p.encode(p.text[p.prefixLen:], raw)
+	return p.m.Match(p.text)
+}